@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"os"
+	"strings"
+	"time"
+)
+
+// demoEvent -- is an event as it's written in events.json, the date is like "10 июня 20:00" without a year.
+type demoEvent struct {
+	Price        uint64   `json:"price"`
+	Restrictions uint64   `json:"restrictions"`
+	Date         string   `json:"date"`
+	Feature      []string `json:"feature"`
+	Address      string   `json:"address"`
+	Name         string   `json:"name"`
+	ImgPath      string   `json:"img_path"`
+}
+
+// demoMonths -- are Russian month names in the genitive case the way they are written in demo dates.
+var demoMonths = map[string]time.Month{
+	"января": time.January, "февраля": time.February, "марта": time.March, "апреля": time.April,
+	"мая": time.May, "июня": time.June, "июля": time.July, "августа": time.August,
+	"сентября": time.September, "октября": time.October, "ноября": time.November, "декабря": time.December,
+}
+
+// demoLocation -- is the time zone demo dates are given in.
+var demoLocation = time.FixedZone("MSK", 3*60*60)
+
+// openDemoStorage -- returns the in-memory storage with the events of the file at path published.
+func openDemoStorage(path string) (*memory.Storage, error) {
+	const op = "main.openDemoStorage"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var events []demoEvent
+	if err = json.Unmarshal(data, &events); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db := memory.New()
+	now := time.Now()
+	for _, event := range events {
+		date, err := parseDemoDate(event.Date, now)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q: %w", op, event.Name, err)
+		}
+
+		_, err = db.CreateEvent(context.Background(), &storage.Event{
+			Price:        event.Price,
+			Restrictions: event.Restrictions,
+			Date:         date,
+			Feature:      event.Feature,
+			Address:      event.Address,
+			Name:         strings.TrimSpace(event.Name),
+			ImgPath:      event.ImgPath,
+			Status:       storage.StatusPublished,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return db, nil
+}
+
+// parseDemoDate -- parses date like "10 июня 20:00" as its nearest occurrence after now, so demo events are never
+// archived as past ones.
+func parseDemoDate(value string, now time.Time) (time.Time, error) {
+	var day, hour, minute int
+	var month string
+	if _, err := fmt.Sscanf(value, "%d %s %d:%d", &day, &month, &hour, &minute); err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: %w", value, err)
+	}
+	m, ok := demoMonths[month]
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid month in date %q", value)
+	}
+
+	date := time.Date(now.Year(), m, day, hour, minute, 0, 0, demoLocation)
+	if date.Before(now) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, nil
+}
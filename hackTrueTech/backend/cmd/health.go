@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/wlcmtunknwndth/hackBPA/internal/cacher"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"log/slog"
+	"time"
+)
+
+var errCacheNotRestored = errors.New("cache isn't restored yet")
+
+// cacheRestored -- returns the readiness check of the cache, it fails until the cache is restored.
+func cacheRestored(cacheSrv *cacher.Cacher) func(context.Context) error {
+	return func(context.Context) error {
+		if !cacheSrv.Restored() {
+			return errCacheNotRestored
+		}
+		return nil
+	}
+}
+
+// restoreCache -- retries restoring the cache every interval until it's restored or ctx is done, so the server
+// becomes ready once the storage is back.
+func restoreCache(ctx context.Context, cacheSrv *cacher.Cacher, interval time.Duration) {
+	const op = "main.restoreCache"
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if err := cacheSrv.Restore(); err != nil {
+			slog.Error("couldn't restore cache", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			continue
+		}
+		slog.Info("cache restored", slogResponse.SlogOp(op))
+		return
+	}
+}
@@ -2,32 +2,79 @@ package main
 
 import (
 	"context"
+	"flag"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
 	"github.com/wlcmtunknwndth/hackBPA/internal/broker/nats"
 	"github.com/wlcmtunknwndth/hackBPA/internal/cacher"
 	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/booking"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/calendar"
 	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/event"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/health"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/history"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/moderation"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/review"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/stream"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/webhook"
 	"github.com/wlcmtunknwndth/hackBPA/internal/lib/corsSkip"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/readYourWrites"
 	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
-	"github.com/wlcmtunknwndth/hackBPA/internal/storage/postgres"
+	"github.com/wlcmtunknwndth/hackBPA/internal/operations"
+	"github.com/wlcmtunknwndth/hackBPA/internal/webhooks"
 	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 )
 
 const scope = "main"
 
 func main() {
+	demo := flag.Bool("demo", false, "run on in-memory storage seeded with demo events, no database is needed")
+	demoEvents := flag.String("demo-events", "../events.json", "path to events the demo storage is seeded with")
+	flag.Parse()
+
 	cfg := config.MustLoad()
+
+	mode := flag.Arg(0)
+	if mode == "migrate" {
+		if err := runMigrate(cfg, flag.Args()[1:]); err != nil {
+			slog.Error("couldn't migrate", slogResponse.SlogOp(scope), slogResponse.SlogErr(err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	switch {
+	case mode != modeAll && mode != modeAPI && mode != modeWorker:
+		slog.Error("unknown mode", slogResponse.SlogOp(scope), slog.String("mode", mode))
+		os.Exit(1)
+	case *demo && mode != modeAll:
+		slog.Error("demo storage is kept in memory of one process, api and worker can't run apart",
+			slogResponse.SlogOp(scope), slog.String("mode", mode))
+		os.Exit(1)
+	case cfg.Nats.Transport == config.TransportLocal && mode != modeAll:
+		slog.Error("local broker passes messages within one process, api and worker can't run apart",
+			slogResponse.SlogOp(scope), slog.String("mode", mode))
+		os.Exit(1)
+	}
+
 	slog.Info("Config: ", slog.Attr{Key: "Config", Value: slog.AnyValue(*cfg)})
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
 	//router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
 	router.Use(middleware.Logger)
+	router.Use(readYourWrites.Middleware(cfg.DB.ReadYourWritesWindow))
 
 	//m := &autocert.Manager{
 	//	Cache:      autocert.DirCache("golang-autocert"),
@@ -52,30 +99,102 @@ func main() {
 	//	}
 	//}()
 
-	db, err := postgres.New(&cfg.DB)
+	var db appStorage
+	var err error
+	if *demo {
+		db, err = openDemoStorage(*demoEvents)
+	} else {
+		db, err = openStorage(cfg)
+	}
 	if err != nil {
 		slog.Error("couldn't connect to storage", slogResponse.SlogOp(scope), slogResponse.SlogErr(err))
 		return
 	}
-	defer func(db *postgres.Storage) {
-		err := db.Close()
-		if err != nil {
-			slog.Error("couldn't close connection to storage", slogResponse.SlogOp(scope), slogResponse.SlogErr(err))
+	var stops shutdown
+	// The storage is closed last, once nothing asks it anymore.
+	stops.onShutdown("storage", func(ctx context.Context) error {
+		return closeWithin(ctx, db.Close)
+	})
+	slog.Info("successfully initialized storage", slog.String("driver", cfg.Storage.Driver), slog.Bool("demo", *demo))
+
+	if m, ok := db.(migrator); ok && cfg.DB.MigrateOnStart {
+		if err = m.MigrateUp(context.Background()); err != nil {
+			slog.Error("couldn't migrate storage", slogResponse.SlogOp(scope), slogResponse.SlogErr(err))
+			return
+		}
+		slog.Info("storage migrated")
+	}
+
+	ns, err := nats.New(&cfg.Nats, db)
+	if err != nil {
+		slog.Error("couldn't run nats:", slogResponse.SlogErr(err))
+		return
+	}
+	defer ns.Close()
+	stops.onShutdown("broker", ns.Drain)
+	slog.Info("nats created")
+
+	healthService := health.HealthHandler{Dependencies: []health.Dependency{
+		{Name: "storage", Check: db.Ping},
+		{Name: "broker", Check: ns.Ping},
+	}}
+
+	router.Get("/healthz", healthService.Live)
+	router.Get("/readyz", healthService.Ready)
+
+	if mode != modeAPI {
+		dispatcher := webhooks.New(db, &cfg.Webhooks)
+		if err = subscribe(ns, dispatcher); err != nil {
+			slog.Error("couldn't run subscribers", slogResponse.SlogErr(err))
 			return
 		}
-	}(db)
-	slog.Info("successfully initialized storage")
+
+		jobsCtx, stopJobs := context.WithCancel(context.Background())
+		defer stopJobs()
+		var jobs sync.WaitGroup
+		for _, job := range []func(context.Context){
+			func(ctx context.Context) { ns.RelayOutbox(ctx, cfg.Nats.OutboxInterval) },
+			dispatcher.Run,
+			func(ctx context.Context) { archive(ctx, db) },
+			func(ctx context.Context) { purgeOperations(ctx, db) },
+		} {
+			jobs.Add(1)
+			go func() {
+				defer jobs.Done()
+				job(jobsCtx)
+			}()
+		}
+		stops.onShutdown("background jobs", func(ctx context.Context) error {
+			stopJobs()
+			return wait(ctx, &jobs)
+		})
+	}
+
+	slog.Info("successfully initialized NATS", slog.String("mode", modeName(mode)))
+
+	if mode == modeWorker {
+		// Workers serve only /healthz and /readyz.
+		serve(ctx, srv, &healthService, &stops, cfg.Server.ShutdownTimeout, cfg.Server.PreStopDelay)
+		slog.Info("worker stopped")
+		return
+	}
 
 	cacheSrv := cacher.New(db, 2*time.Minute, 5*time.Minute)
 	if err = cacheSrv.Restore(); err != nil {
-		slog.Error("couldn't restore cache", slogResponse.SlogErr(err))
+		slog.Error("couldn't restore cache, retrying", slogResponse.SlogErr(err))
+		go restoreCache(ctx, cacheSrv, 5*time.Second)
 	} else {
 		slog.Info("cache restored")
 	}
+	healthService.Dependencies = append(healthService.Dependencies,
+		health.Dependency{Name: "cache", Check: cacheRestored(cacheSrv)})
 
-	ticker := time.NewTicker(5 * time.Minute)
-	quit := make(chan struct{})
+	backupDone := make(chan struct{})
+	stopBackup := make(chan struct{})
 	go func() {
+		defer close(backupDone)
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
@@ -83,57 +202,28 @@ func main() {
 					continue
 				}
 				slog.Info("made a cache backup")
-			case <-quit:
-				ticker.Stop()
+			case <-stopBackup:
 				return
 			}
 		}
 	}()
-	defer close(quit)
-
-	ns, err := nats.New(&cfg.Nats, db)
-	if err != nil {
-		slog.Error("couldn't run nats:", slogResponse.SlogErr(err))
-		return
-	}
-	defer ns.Close()
-	slog.Info("nats created")
-
-	del, err := ns.EventDeleter(context.Background())
-	if err != nil {
-		slog.Error("couldn't run deleter", slogResponse.SlogErr(err))
-		return
-	}
-	defer del.Unsubscribe()
-	save, err := ns.EventSaver(context.Background())
-	if err != nil {
-		slog.Error("couldn't run saver", slogResponse.SlogErr(err))
-		return
-	}
-	defer save.Unsubscribe()
-
-	send, err := ns.EventSender(context.Background())
-	if err != nil {
-		slog.Error("couldn't run sender", slogResponse.SlogErr(err))
-		return
-	}
-	defer send.Unsubscribe()
-
-	patch, err := ns.EventPatcher(context.Background())
-	if err != nil {
-		slog.Error("couldn't run patcher", slogResponse.SlogErr(err))
-		return
-	}
-	defer patch.Unsubscribe()
+	stops.onShutdown("cache", func(ctx context.Context) error {
+		close(stopBackup)
+		select {
+		case <-backupDone:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return cacheSrv.SaveCache()
+	})
 
-	featurer, err := ns.FilteredEventsSender(context.Background())
-	if err != nil {
-		slog.Error("couldn't run filtered events sender", slogResponse.SlogErr(err))
-		return
+	hub := stream.NewHub()
+	for _, handle := range []nats.DomainEventHandler{hub.Publish, cacheSrv.Invalidate} {
+		if _, err = ns.WatchDomainEvents(context.Background(), handle); err != nil {
+			slog.Error("couldn't watch domain events", slogResponse.SlogErr(err))
+			return
+		}
 	}
-	defer featurer.Unsubscribe()
-
-	slog.Info("successfully initialized NATS")
 
 	authService := auth.Auth{Db: db}
 
@@ -150,14 +240,22 @@ func main() {
 	router.Options("/delete_user", corsSkip.EnableCors)
 	router.Delete("/delete_user", authService.DeleteUser)
 
-	eventService := event.EventsHandler{Cache: cacheSrv, Broker: ns}
+	eventService := event.EventsHandler{Cache: cacheSrv, Broker: ns, Operations: operations.New(db)}
+	// Changes requested in async mode ask the broker, so they're waited for before it's drained.
+	stops.onShutdown("async operations", eventService.WaitAsync)
 
 	router.Options("/create_event", corsSkip.EnableCors)
 	router.Post("/create_event", eventService.CreateEvent)
 
+	router.Options("/create_series", corsSkip.EnableCors)
+	router.Post("/create_series", eventService.CreateSeries)
+
 	router.Options("/event", corsSkip.EnableCors)
 	router.Get("/event", eventService.GetEvent)
 
+	router.Options("/event_status", corsSkip.EnableCors)
+	router.Post("/event_status", eventService.SetStatus)
+
 	router.Options("/events", corsSkip.EnableCors)
 	router.Get("/events", eventService.GetEventsByFeature)
 
@@ -167,8 +265,93 @@ func main() {
 	router.Options("/patch_event", corsSkip.EnableCors)
 	router.Get("/patch_events", eventService.PatchEvent)
 
-	if err = srv.ListenAndServe(); err != nil {
-		slog.Error("failed to run server: ", slogResponse.SlogErr(err))
-	}
+	router.Options("/operations/{id}", corsSkip.EnableCors)
+	router.Get("/operations/{id}", eventService.GetOperation)
+
+	moderationService := moderation.ModerationHandler{Db: db, Cache: cacheSrv}
+
+	router.Options("/moderation_queue", corsSkip.EnableCors)
+	router.Get("/moderation_queue", moderationService.Queue)
+
+	router.Options("/approve_event", corsSkip.EnableCors)
+	router.Post("/approve_event", moderationService.Approve)
+
+	router.Options("/reject_event", corsSkip.EnableCors)
+	router.Post("/reject_event", moderationService.Reject)
+
+	router.Options("/submit_event", corsSkip.EnableCors)
+	router.Post("/submit_event", moderationService.Submit)
+
+	router.Options("/moderation_decisions", corsSkip.EnableCors)
+	router.Get("/moderation_decisions", moderationService.Decisions)
+
+	bookingService := booking.BookingHandler{Db: db}
+
+	router.Options("/book", corsSkip.EnableCors)
+	router.Post("/book", bookingService.Book)
+
+	router.Options("/check_in", corsSkip.EnableCors)
+	router.Post("/check_in", bookingService.CheckIn)
+
+	router.Options("/favorite", corsSkip.EnableCors)
+	router.Post("/favorite", bookingService.AddFavorite)
+	router.Delete("/favorite", bookingService.RemoveFavorite)
+
+	streamService := stream.StreamHandler{Hub: hub}
+
+	router.Options("/events/stream", corsSkip.EnableCors)
+	router.Get("/events/stream", streamService.Events)
+
+	calendarService := calendar.CalendarHandler{Db: db, Broker: ns}
+
+	router.Options("/events/{id}", corsSkip.EnableCors)
+	router.Get("/events/{id}", calendarService.ExportEvent)
+
+	historyService := history.HistoryHandler{Db: db, Cache: cacheSrv}
+
+	router.Options("/events/{id}/history", corsSkip.EnableCors)
+	router.Get("/events/{id}/history", historyService.History)
+
+	router.Options("/events/{id}/restore", corsSkip.EnableCors)
+	router.Post("/events/{id}/restore", historyService.Restore)
+
+	router.Get("/calendar/{token}", calendarService.Feed)
+
+	router.Options("/calendar_token", corsSkip.EnableCors)
+	router.Get("/calendar_token", calendarService.GetToken)
+	router.Post("/calendar_token", calendarService.RotateToken)
+
+	reviewService := review.ReviewHandler{Db: db}
+
+	router.Options("/create_review", corsSkip.EnableCors)
+	router.Post("/create_review", reviewService.CreateReview)
+
+	router.Options("/reviews", corsSkip.EnableCors)
+	router.Get("/reviews", reviewService.GetReviews)
+
+	router.Options("/hide_review", corsSkip.EnableCors)
+	router.Post("/hide_review", reviewService.HideReview)
+
+	router.Options("/restore_review", corsSkip.EnableCors)
+	router.Post("/restore_review", reviewService.RestoreReview)
+
+	router.Options("/venue_rating", corsSkip.EnableCors)
+	router.Get("/venue_rating", reviewService.GetVenueRating)
+
+	webhookService := webhook.WebhookHandler{Db: db}
+
+	router.Options("/webhooks", corsSkip.EnableCors)
+	router.Get("/webhooks", webhookService.List)
+	router.Post("/webhooks", webhookService.Create)
+
+	router.Options("/webhooks/{id}", corsSkip.EnableCors)
+	router.Delete("/webhooks/{id}", webhookService.Delete)
+
+	router.Options("/webhooks/{id}/deliveries", corsSkip.EnableCors)
+	router.Get("/webhooks/{id}/deliveries", webhookService.Deliveries)
+
+	// Streams don't end on their own, so they're ended for Shutdown not to wait for them.
+	srv.RegisterOnShutdown(hub.Close)
+	serve(ctx, srv, &healthService, &stops, cfg.Server.ShutdownTimeout, cfg.Server.PreStopDelay)
 	slog.Info("server closed")
 }
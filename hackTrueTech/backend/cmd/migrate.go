@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"log/slog"
+	"strconv"
+)
+
+const migrateUsage = "usage: migrate [up | down [steps] | version]"
+
+// runMigrate -- runs the migrate subcommand: "up" applies pending migrations (default), "down" reverts the given
+// number of the latest ones (1 by default) and "version" prints the current schema version.
+func runMigrate(cfg *config.Config, args []string) error {
+	const op = "main.runMigrate"
+
+	opened, err := openStorage(cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer opened.Close()
+
+	db, ok := opened.(migrator)
+	if !ok {
+		return fmt.Errorf("%s: storage driver %q has no migrations", op, cfg.Storage.Driver)
+	}
+
+	ctx := context.Background()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		err = db.MigrateUp(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("%s: invalid steps %q: %s", op, args[1], migrateUsage)
+			}
+		}
+		err = db.MigrateDown(ctx, steps)
+	case "version":
+		var version int64
+		if version, err = db.MigrationVersion(ctx); err == nil {
+			slog.Info("schema version", slog.Int64("version", version))
+		}
+	default:
+		return fmt.Errorf("%s: unknown command %q: %s", op, command, migrateUsage)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
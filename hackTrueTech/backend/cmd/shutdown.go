@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/health"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdown -- stops what the server runs in the reverse order it's started, like deferred calls, so the HTTP server
+// stops before the broker its handlers ask and the broker before the storage.
+type shutdown struct {
+	steps []shutdownStep
+}
+
+type shutdownStep struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// onShutdown -- adds stop to the steps run on shutdown. Stop should give up once ctx is done.
+func (s *shutdown) onShutdown(name string, stop func(ctx context.Context) error) {
+	s.steps = append(s.steps, shutdownStep{name: name, stop: stop})
+}
+
+// run -- runs every step within timeout. Steps failed or left once it's over don't keep the others from running.
+func (s *shutdown) run(timeout time.Duration) {
+	const op = "main.shutdown.run"
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for i := len(s.steps) - 1; i >= 0; i-- {
+		step := s.steps[i]
+		started := time.Now()
+		if err := step.stop(ctx); err != nil {
+			slog.Error("couldn't stop gracefully", slogResponse.SlogOp(op), slog.String("step", step.name),
+				slogResponse.SlogErr(err))
+			continue
+		}
+		slog.Info("stopped", slogResponse.SlogOp(op), slog.String("step", step.name),
+			slog.Duration("took", time.Since(started)))
+	}
+}
+
+// serve -- runs srv until ctx is done or it fails, then shuts everything down within timeout: first the server is
+// reported not ready by healthService and keeps serving for preStopDelay, then srv stops, then the rest of stops.
+func serve(ctx context.Context, srv *http.Server, healthService *health.HealthHandler, stops *shutdown,
+	timeout, preStopDelay time.Duration) {
+	stops.onShutdown("http server", func(ctx context.Context) error {
+		if err := srv.Shutdown(ctx); err != nil {
+			_ = srv.Close()
+			return err
+		}
+		return nil
+	})
+	stops.onShutdown("readiness", func(ctx context.Context) error {
+		healthService.Stop()
+		// Load balancers keep sending requests until they check the readiness again.
+		select {
+		case <-time.After(preStopDelay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.ListenAndServe()
+	}()
+	select {
+	case <-ctx.Done():
+	case err := <-served:
+		slog.Error("failed to run server: ", slogResponse.SlogErr(err))
+	}
+
+	// Another signal stops the process at once.
+	signal.Reset(os.Interrupt, syscall.SIGTERM)
+	slog.Info("stopping server", slog.Duration("timeout", timeout))
+	stops.run(timeout)
+}
+
+// wait -- waits for wg until ctx is done.
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeWithin -- runs close until ctx is done. The close keeps running after it, but the shutdown doesn't wait.
+func closeWithin(ctx context.Context, close func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/broker/nats"
+	"github.com/wlcmtunknwndth/hackBPA/internal/cacher"
+	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/booking"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/calendar"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/history"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/moderation"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/review"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/webhook"
+	"github.com/wlcmtunknwndth/hackBPA/internal/operations"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/postgres"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/sqlite"
+	"github.com/wlcmtunknwndth/hackBPA/internal/webhooks"
+	"time"
+)
+
+// appStorage -- is everything the server needs from a storage backend.
+type appStorage interface {
+	auth.Storage
+	nats.Storage
+	cacher.Storage
+	moderation.Storage
+	booking.Storage
+	calendar.Storage
+	review.Storage
+	history.Storage
+	webhook.Storage
+	webhooks.Storage
+	operations.Storage
+	ArchivePastEvents(ctx context.Context, before time.Time) (int64, error)
+	PurgeOperations(ctx context.Context, before time.Time) (int64, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// migrator -- is a backend with a versioned schema, every one but the in-memory storage.
+type migrator interface {
+	MigrateUp(ctx context.Context) error
+	MigrateDown(ctx context.Context, steps int) error
+	MigrationVersion(ctx context.Context) (int64, error)
+}
+
+// openStorage -- opens the backend selected by storage.driver.
+func openStorage(cfg *config.Config) (appStorage, error) {
+	const op = "main.openStorage"
+
+	switch cfg.Storage.Driver {
+	case config.DriverPostgres:
+		db, err := postgres.New(&cfg.DB)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return db, nil
+	case config.DriverSQLite:
+		db, err := sqlite.New(cfg.Storage.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return db, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown storage driver %q", op, cfg.Storage.Driver)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/broker/nats"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/operations"
+	"github.com/wlcmtunknwndth/hackBPA/internal/webhooks"
+	"log/slog"
+	"time"
+)
+
+// Modes the server runs in, selected by the first argument. The API handles HTTP requests and asks workers over
+// the broker to change events, workers run the broker subscribers and background jobs on the storage. By default
+// both run in one process.
+const (
+	modeAll    = ""
+	modeAPI    = "api"
+	modeWorker = "worker"
+)
+
+// modeName -- returns the name of mode to log.
+func modeName(mode string) string {
+	if mode == modeAll {
+		return "api and worker"
+	}
+	return mode
+}
+
+// subscribe -- runs every subscriber of the broker until it's drained or closed. Domain events are scheduled
+// for delivery to webhooks with dispatcher.
+func subscribe(ns *nats.Nats, dispatcher *webhooks.Dispatcher) error {
+	const op = "main.subscribe"
+
+	subscribers := []struct {
+		name string
+		run  func(context.Context) (nats.Subscription, error)
+	}{
+		{name: "deleter", run: ns.EventDeleter},
+		{name: "saver", run: ns.EventSaver},
+		{name: "sender", run: ns.EventSender},
+		{name: "patcher", run: ns.EventPatcher},
+		{name: "filtered events sender", run: ns.FilteredEventsSender},
+		{name: "series saver", run: ns.SeriesSaver},
+		{name: "series patcher", run: ns.SeriesPatcher},
+		{name: "status changer", run: ns.StatusChanger},
+		{name: "canceller", run: ns.Canceller},
+		{name: "webhooks", run: func(ctx context.Context) (nats.Subscription, error) {
+			return ns.DeliverDomainEvents(ctx, dispatcher.Enqueue)
+		}},
+	}
+
+	subs := make([]nats.Subscription, 0, len(subscribers))
+	unsubscribe := func() {
+		for _, sub := range subs {
+			if err := sub.Unsubscribe(); err != nil {
+				slog.Error("couldn't unsubscribe", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			}
+		}
+	}
+	for _, subscriber := range subscribers {
+		sub, err := subscriber.run(context.Background())
+		if err != nil {
+			unsubscribe()
+			return fmt.Errorf("%s: couldn't run %s: %w", op, subscriber.name, err)
+		}
+		subs = append(subs, sub)
+	}
+	return nil
+}
+
+// archive -- archives past events every hour until ctx is done.
+func archive(ctx context.Context, db appStorage) {
+	archiveTicker := time.NewTicker(time.Hour)
+	defer archiveTicker.Stop()
+
+	for {
+		archived, err := db.ArchivePastEvents(ctx, time.Now())
+		if err != nil {
+			slog.Error("couldn't archive past events", slogResponse.SlogErr(err))
+		} else if archived > 0 {
+			slog.Info("archived past events", slog.Int64("count", archived))
+		}
+
+		select {
+		case <-archiveTicker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// purgeOperations -- deletes expired operations every hour until ctx is done.
+func purgeOperations(ctx context.Context, db appStorage) {
+	purgeTicker := time.NewTicker(time.Hour)
+	defer purgeTicker.Stop()
+
+	for {
+		purged, err := db.PurgeOperations(ctx, time.Now().Add(-operations.Retention))
+		if err != nil {
+			slog.Error("couldn't purge expired operations", slogResponse.SlogErr(err))
+		} else if purged > 0 {
+			slog.Info("purged expired operations", slog.Int64("count", purged))
+		}
+
+		select {
+		case <-purgeTicker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -89,11 +89,15 @@ func (a *Auth) LogIn(w http.ResponseWriter, r *http.Request) {
 	}
 
 	pass, err := a.Db.GetPassword(ctx, usr.Username)
-	if err != nil || pass != usr.Password {
+	if err != nil {
 		slog.Error("couldn't get password from storage: ", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
 		httpResponse.Write(w, http.StatusUnauthorized, unauthorized)
 		return
 	}
+	if pass != usr.Password {
+		httpResponse.Write(w, http.StatusUnauthorized, unauthorized)
+		return
+	}
 
 	if usr.isAdmin, err = a.Db.IsAdmin(ctx, usr.Username); err != nil {
 		slog.Error("couldn't determine if user is admin: ", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
@@ -0,0 +1,177 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	admin         = "idkidkidk"
+	adminPassword = "idkidkidk"
+)
+
+func body(t *testing.T, v any) *bytes.Reader {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(data)
+}
+
+func accessCookie(res *http.Response) *http.Cookie {
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == "access" && cookie.Value != "" {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func login(t *testing.T, srv *auth.Auth, username, password string) *http.Cookie {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	srv.LogIn(w, httptest.NewRequest(http.MethodPost, "/login",
+		body(t, auth.User{Username: username, Password: password})))
+	cookie := accessCookie(w.Result())
+	if cookie == nil {
+		t.Fatalf("%s couldn't log in: %d", username, w.Code)
+	}
+	return cookie
+}
+
+func TestAuth_Register(t *testing.T) {
+	t.Setenv("auth_key", "test")
+
+	db := memory.New()
+	srv := auth.Auth{Db: db}
+
+	tests := []struct {
+		name       string
+		body       string
+		statusCode int
+		registered string
+	}{
+		{
+			name:       "new user",
+			body:       `{"username": "visitor", "password": "secret", "age": "25"}`,
+			statusCode: http.StatusOK,
+			registered: "visitor",
+		},
+		{
+			name:       "existing user",
+			body:       `{"username": "idkidk", "password": "other"}`,
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:       "malformed body",
+			body:       `{"username": `,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Register(w, httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(tt.body)))
+
+			res := w.Result()
+			if res.StatusCode != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", res.StatusCode, tt.statusCode)
+			}
+			if tt.registered == "" {
+				return
+			}
+			if accessCookie(res) == nil {
+				t.Error("registered user got no access cookie")
+			}
+			if _, err := db.GetPassword(context.Background(), tt.registered); err != nil {
+				t.Errorf("user isn't stored: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuth_LogIn(t *testing.T) {
+	t.Setenv("auth_key", "test")
+
+	srv := auth.Auth{Db: memory.New()}
+
+	tests := []struct {
+		name       string
+		usr        auth.User
+		statusCode int
+	}{
+		{name: "valid credentials", usr: auth.User{Username: admin, Password: adminPassword}, statusCode: http.StatusOK},
+		{name: "wrong password", usr: auth.User{Username: admin, Password: "wrong password"}, statusCode: http.StatusUnauthorized},
+		{name: "unknown user", usr: auth.User{Username: "nobody", Password: "password"}, statusCode: http.StatusUnauthorized},
+		{name: "empty username", usr: auth.User{Password: "password"}, statusCode: http.StatusUnauthorized},
+		{name: "short password", usr: auth.User{Username: admin, Password: "idk"}, statusCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.LogIn(w, httptest.NewRequest(http.MethodPost, "/login", body(t, tt.usr)))
+
+			res := w.Result()
+			if res.StatusCode != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", res.StatusCode, tt.statusCode)
+			}
+			if got := accessCookie(res) != nil; got != (tt.statusCode == http.StatusOK) {
+				t.Errorf("access cookie set = %v", got)
+			}
+		})
+	}
+}
+
+func TestAuth_DeleteUser(t *testing.T) {
+	t.Setenv("auth_key", "test")
+
+	db := memory.New()
+	srv := auth.Auth{Db: db}
+	if err := db.RegisterUser(context.Background(), &auth.User{Username: "visitor", Password: "visitor"}); err != nil {
+		t.Fatal(err)
+	}
+	adminCookie := login(t, &srv, admin, adminPassword)
+	visitorCookie := login(t, &srv, "visitor", "visitor")
+
+	tests := []struct {
+		name       string
+		cookie     *http.Cookie
+		statusCode int
+		deleted    bool
+	}{
+		{name: "anonymous", statusCode: http.StatusInternalServerError},
+		{name: "not admin", cookie: visitorCookie, statusCode: http.StatusForbidden},
+		{name: "admin", cookie: adminCookie, statusCode: http.StatusOK, deleted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, "/delete_user", strings.NewReader(`{"username": "visitor"}`))
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			w := httptest.NewRecorder()
+			srv.DeleteUser(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+			_, err := db.GetPassword(context.Background(), "visitor")
+			if deleted := err != nil; deleted != tt.deleted {
+				t.Errorf("user deleted = %v, want %v", deleted, tt.deleted)
+			}
+		})
+	}
+}
@@ -67,6 +67,16 @@ func IsAdmin(r *http.Request) (bool, error) {
 	return inf.IsAdmin, nil
 }
 
+func Username(r *http.Request) (string, error) {
+	const op = "auth.jwtAuth.Username"
+	inf, err := checkRequest(r)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return inf.Username, nil
+}
+
 func Access(r *http.Request) (bool, error) {
 	const op = "auth.jwtAuth.Access"
 	_, err := checkRequest(r)
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	mock "github.com/stretchr/testify/mock"
 	auth "github.com/wlcmtunknwndth/hackBPA/internal/auth"
 )
@@ -12,9 +14,27 @@ type Storage struct {
 	mock.Mock
 }
 
-// GetPassword provides a mock function with given fields: _a0
-func (_m *Storage) GetPassword(_a0 string) (string, error) {
-	ret := _m.Called(_a0)
+// DeleteUser provides a mock function with given fields: _a0, _a1
+func (_m *Storage) DeleteUser(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetPassword provides a mock function with given fields: _a0, _a1
+func (_m *Storage) GetPassword(_a0 context.Context, _a1 string) (string, error) {
+	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetPassword")
@@ -22,17 +42,17 @@ func (_m *Storage) GetPassword(_a0 string) (string, error) {
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -40,35 +60,45 @@ func (_m *Storage) GetPassword(_a0 string) (string, error) {
 	return r0, r1
 }
 
-// IsAdmin provides a mock function with given fields: _a0
-func (_m *Storage) IsAdmin(_a0 string) bool {
-	ret := _m.Called(_a0)
+// IsAdmin provides a mock function with given fields: _a0, _a1
+func (_m *Storage) IsAdmin(_a0 context.Context, _a1 string) (bool, error) {
+	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
 		panic("no return value specified for IsAdmin")
 	}
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(string) bool); ok {
-		r0 = rf(_a0)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// RegisterUser provides a mock function with given fields: _a0
-func (_m *Storage) RegisterUser(_a0 *auth.User) error {
-	ret := _m.Called(_a0)
+// RegisterUser provides a mock function with given fields: _a0, _a1
+func (_m *Storage) RegisterUser(_a0 context.Context, _a1 *auth.User) error {
+	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
 		panic("no return value specified for RegisterUser")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*auth.User) error); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *auth.User) error); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -3,6 +3,7 @@ package mocks
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/stretchr/testify/mock"
 	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
 	"net/http"
 	"net/http/httptest"
@@ -60,12 +61,12 @@ func TestAuth_LogIn(t *testing.T) {
 			return
 		}
 
-		req := httptest.NewRequest(http.MethodPost, "", bytes.NewReader(data))
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(data))
 
 		w := httptest.NewRecorder()
 
-		db.Mock.On("GetPassword", val.usr.Username).Return(val.usr.Password, nil)
-		db.Mock.On("IsAdmin", val.usr.Username).Return(val.isAdmin)
+		db.Mock.On("GetPassword", mock.Anything, val.usr.Username).Return(val.usr.Password, nil).Maybe()
+		db.Mock.On("IsAdmin", mock.Anything, val.usr.Username).Return(val.isAdmin, nil).Maybe()
 
 		authSrv.LogIn(w, req)
 
@@ -0,0 +1,126 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/middleware"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/wlcmtunknwndth/hackBPA/internal/contracts"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"time"
+)
+
+// Headers carrying the context of the request.
+const (
+	// HeaderRequestId -- is the id of the HTTP request the request is made within, see middleware.RequestID.
+	HeaderRequestId = "Request-Id"
+	// HeaderTimeout -- is the time left until the deadline of the request, e.g. "4.5s". It's sent instead of
+	// the deadline itself, so the clocks of the API and workers needn't be in sync.
+	HeaderTimeout = "Request-Timeout"
+	// HeaderReadYourWrites -- is set if the request is made with storage.WithReadYourWrites context, so the worker
+	// reads what the client has just written from the primary too.
+	HeaderReadYourWrites = "Read-Your-Writes"
+)
+
+// running -- is a request being handled by the worker.
+type running struct {
+	cancel context.CancelFunc
+}
+
+// askContext -- returns ctx of the request to be asked. It carries the request id, a new one if ctx has none,
+// and expires in requestTimeout unless ctx has a deadline already.
+func askContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if middleware.GetReqID(ctx) == "" {
+		ctx = context.WithValue(ctx, middleware.RequestIDKey, uuid.NewString())
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, requestTimeout)
+}
+
+// setTimeout -- sets HeaderTimeout of msg to the time left until the deadline of ctx, if any.
+func setTimeout(ctx context.Context, msg *nats.Msg) {
+	if deadline, ok := ctx.Deadline(); ok {
+		msg.Header.Set(HeaderTimeout, time.Until(deadline).String())
+	}
+}
+
+// withRequestId -- marks ctx with the request id from header, if any.
+func withRequestId(ctx context.Context, header nats.Header) context.Context {
+	if requestId := header.Get(HeaderRequestId); requestId != "" {
+		return context.WithValue(ctx, middleware.RequestIDKey, requestId)
+	}
+	return ctx
+}
+
+// withReadYourWrites -- marks ctx with storage.WithReadYourWrites if header has HeaderReadYourWrites.
+func withReadYourWrites(ctx context.Context, header nats.Header) context.Context {
+	if header.Get(HeaderReadYourWrites) != "" {
+		return storage.WithReadYourWrites(ctx)
+	}
+	return ctx
+}
+
+// msgContext -- returns the context the request msg is handled within. It carries the request id and the read your
+// writes mark, and is done once the time left for the request runs out or the asker cancels it, see Canceller.
+func (n *Nats) msgContext(ctx context.Context, msg *nats.Msg) (context.Context, context.CancelFunc) {
+	ctx = withReadYourWrites(withRequestId(ctx, msg.Header), msg.Header)
+
+	var cancel context.CancelFunc
+	if timeout, err := time.ParseDuration(msg.Header.Get(HeaderTimeout)); err == nil {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	requestId := middleware.GetReqID(ctx)
+	if requestId == "" {
+		return ctx, cancel
+	}
+	request := &running{cancel: cancel}
+	n.running.Store(requestId, request)
+	return ctx, func() {
+		n.running.CompareAndDelete(requestId, request)
+		cancel()
+	}
+}
+
+// Canceller -- cancels requests the worker is handling once their askers cancel them, so the storage doesn't work
+// on requests nobody waits for. It isn't subscribed in the queue group, every worker gets every cancellation.
+func (n *Nats) Canceller(ctx context.Context) (Subscription, error) {
+	const op = "broker.nats.context.Canceller"
+
+	sub, err := n.b.Subscribe(contracts.SubjectCancelRequest, func(msg *nats.Msg) {
+		var cancellation contracts.CancelRequest
+		if err := decode(msg, &cancellation); err != nil {
+			slog.Error("couldn't decode cancellation", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			return
+		}
+		if request, found := n.running.Load(cancellation.RequestId); found {
+			request.(*running).cancel()
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return sub, nil
+}
+
+// cancelRequest -- tells workers the request made within ctx is cancelled.
+func (n *Nats) cancelRequest(ctx context.Context) {
+	const op = "broker.nats.context.cancelRequest"
+
+	requestId := middleware.GetReqID(ctx)
+	msg, err := newMsg(ctx, contracts.SubjectCancelRequest, "", contracts.CancelRequest{RequestId: requestId})
+	if err != nil {
+		slog.Error("couldn't marshall cancellation", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		return
+	}
+	if err = n.b.PublishMsg(msg); err != nil {
+		slog.Error("couldn't send cancellation", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
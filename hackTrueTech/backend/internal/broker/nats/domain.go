@@ -0,0 +1,71 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/wlcmtunknwndth/hackBPA/internal/contracts"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+)
+
+// domainEvents -- are the subjects of domain events told to consumers outside the server. Cancellation notifications
+// aren't among them, they carry the bookers of the event.
+var domainEvents = []string{storage.SubjectEventCreated, storage.SubjectEventUpdated, storage.SubjectEventDeleted}
+
+// DomainEventHandler -- handles the domain event published from the outbox, key is its idempotency key.
+type DomainEventHandler func(ctx context.Context, subject, key string, payload []byte) error
+
+// DeliverDomainEvents -- runs handle on every domain event. Subscribers of every worker join the queue group, so
+// a domain event is handled once however many workers run, unless the outbox publishes it again.
+func (n *Nats) DeliverDomainEvents(ctx context.Context, handle DomainEventHandler) (Subscription, error) {
+	return n.subscribeDomainEvents(ctx, n.queue, handle)
+}
+
+// WatchDomainEvents -- runs handle on every domain event in every process it's called in, e.g. to stream them
+// to the clients of each API instance.
+func (n *Nats) WatchDomainEvents(ctx context.Context, handle DomainEventHandler) (Subscription, error) {
+	return n.subscribeDomainEvents(ctx, "", handle)
+}
+
+// subscribeDomainEvents -- runs handle on domain events of the current version of the contracts, subscribing
+// in the queue group unless it's empty.
+func (n *Nats) subscribeDomainEvents(ctx context.Context, queue string, handle DomainEventHandler) (Subscription,
+	error) {
+	const op = "broker.nats.domain.subscribeDomainEvents"
+
+	subs := make(subscriptions, 0, len(domainEvents))
+	for _, subject := range domainEvents {
+		sub, err := n.b.QueueSubscribe(subject, queue, func(msg *nats.Msg) {
+			if err := contracts.Check(msg.Header.Get(contracts.HeaderVersion)); err != nil {
+				slog.Error("couldn't decode domain event", slogResponse.SlogOp(op), slog.String("subject", msg.Subject),
+					slogResponse.SlogErr(err))
+				return
+			}
+			if err := handle(ctx, msg.Subject, msg.Header.Get(nats.MsgIdHdr), msg.Data); err != nil {
+				slog.Error("couldn't handle domain event", slogResponse.SlogOp(op), slog.String("subject", msg.Subject),
+					slog.String("key", msg.Header.Get(nats.MsgIdHdr)), slogResponse.SlogErr(err))
+			}
+		})
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: %w", op, err), subs.Unsubscribe())
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// subscriptions -- are subscriptions to several subjects run as one.
+type subscriptions []Subscription
+
+func (s subscriptions) Unsubscribe() error {
+	var errs []error
+	for _, sub := range s {
+		if err := sub.Unsubscribe(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,85 @@
+package nats
+
+import (
+	"context"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// domainEvent -- is a domain event a handler got.
+type domainEvent struct {
+	subject, key string
+}
+
+// collect -- returns a handler sending domain events it gets to the returned channel.
+func collect() (DomainEventHandler, chan domainEvent) {
+	got := make(chan domainEvent, 16)
+	return func(ctx context.Context, subject, key string, payload []byte) error {
+		got <- domainEvent{subject: subject, key: key}
+		return nil
+	}, got
+}
+
+func TestDomainEvents(t *testing.T) {
+	cfg := runServer(t)
+	cfg.JetStream.Enabled = false
+	cfg.QueueGroup = "event-workers"
+
+	db := memory.New()
+	delivered, deliveries := collect()
+	for range 2 {
+		sub, err := connect(t, cfg, db).DeliverDomainEvents(context.Background(), delivered)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = sub.Unsubscribe() })
+	}
+	watched, watches := collect()
+	n := connect(t, cfg, db)
+	sub, err := n.WatchDomainEvents(context.Background(), watched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sub.Unsubscribe() })
+
+	id, err := db.CreateEvent(context.Background(), &storage.Event{Name: "event", Date: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.DeleteEvent(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.PublishOutbox(context.Background(), 10, n.publishOutbox); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []domainEvent{
+		{subject: storage.SubjectEventCreated, key: "outbox-1"},
+		{subject: storage.SubjectEventDeleted, key: "outbox-2"},
+	}
+	// Domain events of different subjects and ones handled by different workers may come in any order.
+	for name, got := range map[string]chan domainEvent{"delivered": deliveries, "watched": watches} {
+		var actual []domainEvent
+		for range want {
+			select {
+			case event := <-got:
+				actual = append(actual, event)
+			case <-time.After(time.Second):
+				t.Fatalf("%s only %+v, want %+v", name, actual, want)
+			}
+		}
+		slices.SortFunc(actual, func(a, b domainEvent) int { return strings.Compare(a.key, b.key) })
+		if !slices.Equal(actual, want) {
+			t.Errorf("%s %+v, want %+v", name, actual, want)
+		}
+		select {
+		case extra := <-got:
+			t.Errorf("%s %+v more than once", name, extra)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
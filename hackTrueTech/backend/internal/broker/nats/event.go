@@ -1,12 +1,13 @@
 package nats
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/go-chi/chi/middleware"
 	"github.com/nats-io/nats.go"
+	"github.com/wlcmtunknwndth/hackBPA/internal/contracts"
 	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
 	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
 	"log/slog"
@@ -14,51 +15,75 @@ import (
 	"time"
 )
 
-const (
-	MustSaveEvent          = "save_event"
-	MustDeleteEvent        = "del.*"
-	AskDeleteEvent         = "del."
-	MustSendEvent          = "get.*"
-	AskGetEvent            = "get."
-	MustPatchEvent         = "patch.*"
-	AskPatchEvent          = "patch."
-	MustSendFilteredEvents = "filtered_events"
-	AskFilteredEvents      = "filtered_events"
-)
+// HeaderActor -- is the header of requests changing events holding the user the change is made by, it's recorded
+// in the event history.
+const HeaderActor = "Actor"
+
+// requestTimeout -- is how long Ask* methods wait for the reply if the context they're called with has no deadline.
+const requestTimeout = 5 * time.Second
 
-func convertUintToString(num uint64) string {
-	return strconv.FormatUint(num, 10)
+// Subscription -- is a running subscriber, either a core NATS subscription or a JetStream consumer.
+type Subscription interface {
+	Unsubscribe() error
 }
 
-func convertStrToUint(str string) (uint64, error) {
-	return strconv.ParseUint(str, 10, 64)
+// command -- handles the request msg and returns the payload of the reply or the error.
+type command func(ctx context.Context, msg *nats.Msg) (any, error)
+
+// newMsg -- returns the message v to subject sent with the version of the contracts on behalf of the actor, if any,
+// within the request ctx carries the id of. The message is marked with HeaderReadYourWrites if ctx is.
+func newMsg(ctx context.Context, subject, actor string, v any) (*nats.Msg, error) {
+	data, err := contracts.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Header.Set(contracts.HeaderVersion, strconv.Itoa(contracts.Version))
+	if actor != "" {
+		msg.Header.Set(HeaderActor, actor)
+	}
+	if requestId := middleware.GetReqID(ctx); requestId != "" {
+		msg.Header.Set(HeaderRequestId, requestId)
+	}
+	if storage.ReadsYourWrites(ctx) {
+		msg.Header.Set(HeaderReadYourWrites, "1")
+	}
+	msg.Data = data
+	return msg, nil
 }
 
-func (n *Nats) EventSender(ctx context.Context) (*nats.Subscription, error) {
-	const op = "broker.nats.event.PublishEvent"
-	sub, err := n.b.Subscribe(MustSendEvent, func(msg *nats.Msg) {
-		id, err := convertStrToUint(msg.Subject[4:])
-		if err != nil {
-			slog.Error("couldn't convert str to uint64", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
+// decode -- decodes the request msg into v, a request which can't be decoded is invalid.
+func decode(msg *nats.Msg, v any) error {
+	if err := contracts.Unmarshal(msg.Header.Get(contracts.HeaderVersion), msg.Data, v); err != nil {
+		return invalid(err)
+	}
+	return nil
+}
 
-		event, err := n.db.GetEvent(ctx, id)
-		if err != nil {
-			slog.Error("couldn't get event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
+// withActor -- marks ctx with the actor of the request msg.
+func withActor(ctx context.Context, msg *nats.Msg) context.Context {
+	return storage.WithActor(ctx, msg.Header.Get(HeaderActor))
+}
 
-		data, err := json.Marshal(event)
-		if err != nil {
-			slog.Error("couldn't marshall event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
+// subscribe -- runs handle on requests to subject within their contexts, see msgContext, and replies to them.
+// Subscribers of every worker join the queue group, so a request is handled once however many workers run. Commands
+// are consumed from JetStream if it's enabled, see consume, its durable consumers are shared by the workers the same
+// way.
+func (n *Nats) subscribe(ctx context.Context, subject string, handle command) (Subscription, error) {
+	const op = "broker.nats.event.subscribe"
 
-		if err = msg.Respond(data); err != nil {
-			slog.Error("couldn't send reply", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
+	if n.js != nil && isCommand(subject) {
+		return n.consume(ctx, subject, handle)
+	}
+
+	sub, err := n.b.QueueSubscribe(subject, n.queue, func(msg *nats.Msg) {
+		ctx, cancel := n.msgContext(ctx, msg)
+		defer cancel()
+
+		payload, err := handle(ctx, msg)
+		logFailure(ctx, op, msg.Subject, err)
+		n.respond(ctx, msg, payload, err)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
@@ -66,159 +91,397 @@ func (n *Nats) EventSender(ctx context.Context) (*nats.Subscription, error) {
 	return sub, nil
 }
 
-func (n *Nats) AskEvent(id uint64) ([]byte, error) {
-	const op = "broker.nats.event.GetEvent"
+// logFailure -- logs err the request to subject failed with, if the storage didn't just reject the request.
+func logFailure(ctx context.Context, op, subject string, err error) {
+	if err == nil {
+		return
+	}
+	if newReply(nil, err).Code == CodeInternal {
+		slog.Error("couldn't handle request", slogResponse.SlogOp(op), slog.String("subject", subject),
+			slog.String("request_id", middleware.GetReqID(ctx)), slogResponse.SlogErr(err))
+		return
+	}
+	slog.Info("request rejected", slogResponse.SlogOp(op), slog.String("subject", subject),
+		slog.String("request_id", middleware.GetReqID(ctx)), slogResponse.SlogErr(err))
+}
+
+func (n *Nats) EventSender(ctx context.Context) (Subscription, error) {
+	return n.subscribe(ctx, contracts.SubjectGetEvent, n.sendEvent)
+}
+
+func (n *Nats) sendEvent(ctx context.Context, msg *nats.Msg) (any, error) {
+	const op = "broker.nats.event.sendEvent"
 
-	msg, err := n.b.Request(AskGetEvent+convertUintToString(id), nil, 5*time.Second)
+	var request contracts.EventRequest
+	if err := decode(msg, &request); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	event, err := n.db.GetEvent(ctx, request.Id)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return msg.Data, nil
+	return event, nil
 }
 
-func (n *Nats) EventSaver(ctx context.Context) (*nats.Subscription, error) {
-	const op = "broker.nats.event.Saver"
-	sub, err := n.b.Subscribe(MustSaveEvent, func(msg *nats.Msg) {
-		var event storage.Event
-		if err := json.Unmarshal(msg.Data, &event); err != nil {
-			slog.Error("couldn't unmarshall event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
+// AskEvent -- returns the event as JSON, storage.ErrNotFound is returned if there is no such event.
+func (n *Nats) AskEvent(ctx context.Context, id uint64) ([]byte, error) {
+	const op = "broker.nats.event.GetEvent"
 
-		id, err := n.db.CreateEvent(ctx, &event)
-		if err != nil {
-			slog.Error("couldn't create event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
+	var event json.RawMessage
+	if err := n.ask(ctx, contracts.SubjectGetEvent, "", contracts.EventRequest{Id: id}, &event); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return event, nil
+}
 
-		if err = msg.Respond([]byte(convertUintToString(id))); err != nil {
-			slog.Error("couldn't publish id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
-	})
+func (n *Nats) EventSaver(ctx context.Context) (Subscription, error) {
+	return n.subscribe(ctx, contracts.SubjectSaveEvent, n.saveEvent)
+}
+
+func (n *Nats) saveEvent(ctx context.Context, msg *nats.Msg) (any, error) {
+	const op = "broker.nats.event.saveEvent"
+
+	var event contracts.SaveEventRequest
+	if err := decode(msg, &event); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := n.db.CreateEvent(storage.WithActor(ctx, event.Owner), &event)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return sub, nil
+	return contracts.SaveEventReply{Id: id}, nil
 }
 
-func (n *Nats) AskSave(event *storage.Event) (uint64, error) {
+func (n *Nats) AskSave(ctx context.Context, event *storage.Event) (uint64, error) {
 	const op = "broker.nats.Event.AskSave"
-	data, err := json.Marshal(*event)
-	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
-	}
 
-	msg, err := n.b.Request(MustSaveEvent, data, 5*time.Second)
-	if err != nil {
+	var saved contracts.SaveEventReply
+	if err := n.ask(ctx, contracts.SubjectSaveEvent, "", event, &saved); err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
+	return saved.Id, nil
+}
 
-	id, err := strconv.ParseUint(string(msg.Data), 10, 64)
-	if err != nil {
-		slog.Error("couldn't atoi", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-		return 0, fmt.Errorf("%s: %w", op, err)
+func (n *Nats) EventDeleter(ctx context.Context) (Subscription, error) {
+	return n.subscribe(ctx, contracts.SubjectDeleteEvent, n.deleteEvent)
+}
+
+func (n *Nats) deleteEvent(ctx context.Context, msg *nats.Msg) (any, error) {
+	const op = "broker.nats.event.deleteEvent"
+
+	var request contracts.EventRequest
+	if err := decode(msg, &request); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return id, nil
+	if err := n.db.DeleteEvent(withActor(ctx, msg), request.Id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return nil, nil
 }
 
-func (n *Nats) EventDeleter(ctx context.Context) (*nats.Subscription, error) {
-	const op = "broker.nats.event.Deleter"
-	sub, err := n.b.Subscribe(MustDeleteEvent, func(msg *nats.Msg) {
-		id, err := strconv.ParseUint(msg.Subject[4:], 10, 64)
-		if err != nil {
-			slog.Error("couldn't parse id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
-		if err = n.db.DeleteEvent(ctx, id); err != nil {
-			slog.Error("couldn't delete event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
-	})
-	if err != nil {
-		return nil, err
+// AskDelete -- deletes the event on behalf of the actor once the deletion is confirmed. storage.ErrNotFound is
+// returned if there is no such event.
+func (n *Nats) AskDelete(ctx context.Context, id uint64, actor string) error {
+	const op = "broker.nats.event.AskDelete"
+
+	if err := n.ask(ctx, contracts.SubjectDeleteEvent, actor, contracts.EventRequest{Id: id}, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
-	return sub, nil
+	return nil
 }
 
-func (n *Nats) AskDelete(id uint64) error {
-	return n.b.Publish(fmt.Sprintf("%s%d", AskDeleteEvent, id), nil)
+// EventPatcher -- applies patches and replies with the patched event or the error.
+func (n *Nats) EventPatcher(ctx context.Context) (Subscription, error) {
+	return n.subscribe(ctx, contracts.SubjectPatchEvent, n.patchEvent)
 }
 
-func (n *Nats) EventPatcher(ctx context.Context) (*nats.Subscription, error) {
-	const op = "broker.nats.event.EventPatcher"
-	sub, err := n.b.Subscribe(MustPatchEvent, func(msg *nats.Msg) {
-		var event storage.Event
-		if err := json.Unmarshal(msg.Data, &event); err != nil {
-			return
-		}
-		if err := n.db.PatchEvent(ctx, &event); err != nil {
-			slog.Error("couldn't patch event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
+func (n *Nats) patchEvent(ctx context.Context, msg *nats.Msg) (any, error) {
+	const op = "broker.nats.event.patchEvent"
+
+	var patch contracts.PatchEventRequest
+	if err := decode(msg, &patch); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	event, err := n.patchAt(ctx, &patch, func(patch *storage.EventPatch) (uint64, error) {
+		return n.db.PatchEvent(withActor(ctx, msg), patch)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return sub, nil
+	return event, nil
 }
 
-func (n *Nats) AskPatch(event *storage.Event) error {
+// AskPatch -- applies the patch on behalf of the actor and returns the patched event. storage.ErrNotFound
+// or storage.ErrVersionConflict is returned if the storage rejected the patch.
+func (n *Nats) AskPatch(ctx context.Context, patch *storage.EventPatch, actor string) (*storage.Event, error) {
 	const op = "broker.nats.event.AskPatch"
-	data, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+
+	var event storage.Event
+	if err := n.ask(ctx, contracts.SubjectPatchEvent, actor, patch, &event); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return n.b.Publish(fmt.Sprintf("%s%d", AskPatchEvent, event.Id), data)
+	return &event, nil
 }
 
-func (n *Nats) FilteredEventsSender(ctx context.Context) (*nats.Subscription, error) {
-	const op = "broker.nats.event.FilteredEventsSender"
+// maxPatchAttempts -- is how many times a patch without a version is tried if the event changes concurrently.
+const maxPatchAttempts = 3
 
-	sub, err := n.b.Subscribe(MustSendFilteredEvents, func(msg *nats.Msg) {
-		var features []string
-		buf := &bytes.Buffer{}
-		buf.Write(msg.Data)
-		err := gob.NewDecoder(buf).Decode(&features)
-		if err != nil {
-			slog.Error("couldn't decode features", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
-		}
+// patchAt -- reads the event, applies the patch with apply at the version read and returns the event as it is
+// after the patch. The event is built from the one read and the patch, so a committed patch is never replied with
+// an error of a read after it. A patch without a version is retried if the event changed since it was read.
+func (n *Nats) patchAt(ctx context.Context, patch *storage.EventPatch,
+	apply func(*storage.EventPatch) (uint64, error)) (*storage.Event, error) {
+	const op = "broker.nats.event.patchAt"
 
-		events, err := n.db.GetEventsByFeature(ctx, features)
+	for attempt := 1; ; attempt++ {
+		event, err := n.db.GetEvent(storage.WithReadYourWrites(ctx), patch.Id)
 		if err != nil {
-			slog.Error("couldn't get events by feature", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if patch.Version != 0 && patch.Version != event.Version {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
 		}
 
-		data, err := json.Marshal(events)
+		pinned := *patch
+		pinned.Version = event.Version
+		version, err := apply(&pinned)
+		if errors.Is(err, storage.ErrVersionConflict) && patch.Version == 0 && attempt < maxPatchAttempts {
+			continue
+		}
 		if err != nil {
-			slog.Error("couldn't marshal events", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
+			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		if err = msg.Respond(data); err != nil {
-			slog.Error("couldn't respond to sub", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			return
+		pinned.Apply(event)
+		event.Date = event.Date.UTC()
+		event.Version = version
+		return event, nil
+	}
+}
+
+// ask -- sends the request v to subject on behalf of the actor within ctx and decodes the payload of the reply into
+// res unless it's nil. *ReplyError is returned if the request failed. The request id and the deadline of ctx are
+// sent with the request, workers are told to stop handling it if ctx is cancelled.
+func (n *Nats) ask(ctx context.Context, subject, actor string, v any, res any) error {
+	ctx, cancel := askContext(ctx)
+	defer cancel()
+
+	request, err := newMsg(ctx, subject, actor, v)
+	if err != nil {
+		return err
+	}
+	setTimeout(ctx, request)
+
+	msg, err := n.request(ctx, request)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			n.cancelRequest(ctx)
 		}
-	})
+		return err
+	}
+
+	var reply Reply
+	if err = contracts.Unmarshal(msg.Header.Get(contracts.HeaderVersion), msg.Data, &reply); err != nil {
+		return err
+	}
+	if err = reply.err(); err != nil {
+		return err
+	}
+	if res == nil || reply.Payload == nil {
+		return nil
+	}
+	return json.Unmarshal(reply.Payload, res)
+}
+
+// request -- sends the request and waits for the reply until ctx is done. Commands are published to JetStream if
+// it's enabled, see publishCommand.
+func (n *Nats) request(ctx context.Context, request *nats.Msg) (*nats.Msg, error) {
+	if n.js != nil && isCommand(request.Subject) {
+		return n.publishCommand(ctx, request)
+	}
+	return n.b.RequestMsgWithContext(ctx, request)
+}
+
+// respond -- replies to msg with payload, or with err if it isn't nil. Nothing is sent if msg expects no reply.
+func (n *Nats) respond(ctx context.Context, msg *nats.Msg, payload any, err error) {
+	n.reply(ctx, msg.Reply, newReply(payload, err))
+}
+
+// reply -- sends res to the subject the reply is expected at, if any.
+func (n *Nats) reply(ctx context.Context, subject string, res Reply) {
+	const op = "broker.nats.event.reply"
+	if subject == "" {
+		return
+	}
+
+	msg, err := newMsg(ctx, subject, "", res)
+	if err != nil {
+		slog.Error("couldn't marshall reply", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		return
+	}
+	if err = n.b.PublishMsg(msg); err != nil {
+		slog.Error("couldn't send reply", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
+
+func (n *Nats) FilteredEventsSender(ctx context.Context) (Subscription, error) {
+	return n.subscribe(ctx, contracts.SubjectFilteredEvents, n.sendFilteredEvents)
+}
+
+func (n *Nats) sendFilteredEvents(ctx context.Context, msg *nats.Msg) (any, error) {
+	const op = "broker.nats.event.sendFilteredEvents"
+
+	var filter contracts.FilteredEventsRequest
+	if err := decode(msg, &filter); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events, err := n.db.GetEventsByFeature(ctx, &filter)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return sub, nil
+	return events, nil
 }
 
-func (n *Nats) AskFilteredEvents(features []string) ([]byte, error) {
+func (n *Nats) AskFilteredEvents(ctx context.Context, filter *storage.Filter) ([]byte, error) {
 	const op = "broker.nats.event.AskFilteredEvents"
-	buf := &bytes.Buffer{}
-	err := gob.NewEncoder(buf).Encode(&features)
-	if err != nil {
+
+	var events json.RawMessage
+	if err := n.ask(ctx, contracts.SubjectFilteredEvents, "", filter, &events); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+func (n *Nats) SeriesSaver(ctx context.Context) (Subscription, error) {
+	return n.subscribe(ctx, contracts.SubjectSaveSeries, n.saveSeries)
+}
+
+func (n *Nats) saveSeries(ctx context.Context, msg *nats.Msg) (any, error) {
+	const op = "broker.nats.event.saveSeries"
+
+	var series contracts.SaveSeriesRequest
+	if err := decode(msg, &series); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := n.db.CreateSeries(storage.WithActor(ctx, series.Event.Owner), &series); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return series, nil
+}
+
+// AskSaveSeries -- saves the series and returns it with ids of the series and its materialised occurrences set.
+func (n *Nats) AskSaveSeries(ctx context.Context, series *storage.Series) (*storage.Series, error) {
+	const op = "broker.nats.event.AskSaveSeries"
+
+	var saved storage.Series
+	if err := n.ask(ctx, contracts.SubjectSaveSeries, "", series, &saved); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &saved, nil
+}
+
+// SeriesPatcher -- applies patches to series and replies with the patched event the patch was made through
+// or the error.
+func (n *Nats) SeriesPatcher(ctx context.Context) (Subscription, error) {
+	return n.subscribe(ctx, contracts.SubjectPatchSeries, n.patchSeries)
+}
+
+func (n *Nats) patchSeries(ctx context.Context, msg *nats.Msg) (any, error) {
+	const op = "broker.nats.event.patchSeries"
+
+	var patch contracts.PatchEventRequest
+	if err := decode(msg, &patch); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	msg, err := n.b.Request(AskFilteredEvents, buf.Bytes(), 5*time.Second)
+	// The date isn't patched in series.
+	patch.Date = nil
+	event, err := n.patchAt(ctx, &patch, func(patch *storage.EventPatch) (uint64, error) {
+		return n.db.PatchSeries(withActor(ctx, msg), patch)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	return event, nil
+}
+
+// AskPatchSeries -- applies the patch on behalf of the actor to every occurrence of the series the event patch.Id
+// belongs to and returns that event patched. storage.ErrNotFound or storage.ErrVersionConflict is returned if
+// the storage rejected the patch.
+func (n *Nats) AskPatchSeries(ctx context.Context, patch *storage.EventPatch, actor string) (*storage.Event, error) {
+	const op = "broker.nats.event.AskPatchSeries"
+
+	var event storage.Event
+	if err := n.ask(ctx, contracts.SubjectPatchSeries, actor, patch, &event); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &event, nil
+}
+
+// StatusChanger -- moves events to the requested status. Bookers of a cancelled event are notified via
+// contracts.SubjectEventCancelled.
+func (n *Nats) StatusChanger(ctx context.Context) (Subscription, error) {
+	return n.subscribe(ctx, contracts.SubjectSetStatus, n.setStatus)
+}
+
+func (n *Nats) setStatus(ctx context.Context, msg *nats.Msg) (any, error) {
+	const op = "broker.nats.event.setStatus"
+
+	var request contracts.SetStatusRequest
+	if err := decode(msg, &request); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := n.db.SetEventStatus(withActor(ctx, msg), request.Id, request.Status); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if request.Status == storage.StatusCancelled {
+		if err := n.notifyCancelled(storage.WithReadYourWrites(ctx), request.Id); err != nil {
+			slog.Error("couldn't notify bookers", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		}
+	}
+	return nil, nil
+}
+
+func (n *Nats) notifyCancelled(ctx context.Context, id uint64) error {
+	const op = "broker.nats.event.notifyCancelled"
+
+	event, err := n.db.GetEvent(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	bookers, err := n.db.GetBookers(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
-	return msg.Data, nil
+	msg, err := newMsg(ctx, contracts.SubjectEventCancelled, "", contracts.EventCancelled{
+		EventId: id,
+		Name:    event.Name,
+		Date:    event.Date,
+		Bookers: bookers,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = n.b.PublishMsg(msg); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// AskSetStatus -- moves the event to the status on behalf of the actor once the worker confirms it.
+// storage.ErrInvalidTransition is returned if the storage rejected the change, the event is missing included.
+func (n *Nats) AskSetStatus(ctx context.Context, id uint64, status, actor string) error {
+	const op = "broker.nats.event.AskSetStatus"
+
+	request := contracts.SetStatusRequest{Id: id, Status: status}
+	if err := n.ask(ctx, contracts.SubjectSetStatus, actor, request, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
 }
@@ -0,0 +1,285 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/middleware"
+	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+func TestQueueGroup(t *testing.T) {
+	cfg := runServer(t)
+	cfg.JetStream.Enabled = false
+	cfg.QueueGroup = "event-workers"
+
+	db := memory.New()
+	var workers []*flakyStorage
+	for range 2 {
+		worker := &flakyStorage{Storage: db}
+		workers = append(workers, worker)
+		runWorker(t, connect(t, cfg, worker))
+	}
+	api := connect(t, cfg, db)
+
+	const asked = 10
+	for i := range asked {
+		date := time.Now().Add(time.Duration(i+1) * time.Hour)
+		if _, err := api.AskSave(context.Background(), &storage.Event{Name: "event", Date: date}); err != nil {
+			t.Fatalf("AskSave: %v", err)
+		}
+	}
+
+	var attempts int32
+	for _, worker := range workers {
+		attempts += worker.attempts.Load()
+	}
+	events, err := db.GetEventsByFeature(context.Background(), &storage.Filter{})
+	if attempts != asked || err != nil || len(events) != asked {
+		t.Errorf("%d attempts saved %d events, %v; want every request handled by one worker", attempts, len(events), err)
+	}
+}
+
+// blockingStorage -- gets events once the context of the request is done and reports the context.
+type blockingStorage struct {
+	*memory.Storage
+	done chan context.Context
+}
+
+func (s *blockingStorage) GetEvent(ctx context.Context, id uint64) (*storage.Event, error) {
+	<-ctx.Done()
+	s.done <- ctx
+	return nil, ctx.Err()
+}
+
+func TestContextPropagation(t *testing.T) {
+	cfg := runServer(t)
+	db := &blockingStorage{Storage: memory.New(), done: make(chan context.Context, 1)}
+	n := connect(t, cfg, db)
+	for _, run := range []func(context.Context) (Subscription, error){n.EventSender, n.Canceller} {
+		sub, err := run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = sub.Unsubscribe() })
+	}
+
+	tests := []struct {
+		name   string
+		ctx    func(context.Context) (context.Context, context.CancelFunc)
+		cancel time.Duration
+		want   error
+	}{
+		{name: "deadline", ctx: func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return context.WithTimeout(ctx, 50*time.Millisecond)
+		}, want: context.DeadlineExceeded},
+		{name: "cancelled", ctx: context.WithCancel, cancel: 50 * time.Millisecond, want: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx(context.WithValue(context.Background(), middleware.RequestIDKey, tt.name))
+			defer cancel()
+			if tt.cancel > 0 {
+				time.AfterFunc(tt.cancel, cancel)
+			}
+
+			if _, err := n.AskEvent(ctx, 1); !errors.Is(err, tt.want) {
+				t.Errorf("AskEvent = %v, want %v", err, tt.want)
+			}
+			select {
+			case handled := <-db.done:
+				if !errors.Is(handled.Err(), tt.want) || middleware.GetReqID(handled) != tt.name {
+					t.Errorf("request handled within %v of request %q, want %v of %q", handled.Err(),
+						middleware.GetReqID(handled), tt.want, tt.name)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("storage keeps handling the request")
+			}
+		})
+	}
+}
+
+// consistencyStorage -- reports whether reads are made with storage.WithReadYourWrites context.
+type consistencyStorage struct {
+	*memory.Storage
+	reads chan bool
+}
+
+func (s *consistencyStorage) GetEvent(ctx context.Context, id uint64) (*storage.Event, error) {
+	s.reads <- storage.ReadsYourWrites(ctx)
+	return s.Storage.GetEvent(ctx, id)
+}
+
+func (s *consistencyStorage) GetEventsByFeature(ctx context.Context, filter *storage.Filter) ([]storage.Event,
+	error) {
+	s.reads <- storage.ReadsYourWrites(ctx)
+	return s.Storage.GetEventsByFeature(ctx, filter)
+}
+
+func TestReadYourWritesPropagation(t *testing.T) {
+	db := &consistencyStorage{Storage: memory.New(), reads: make(chan bool, 1)}
+	n := connect(t, &config.Nats{Transport: config.TransportLocal}, db)
+	for _, run := range []func(context.Context) (Subscription, error){n.EventSender, n.FilteredEventsSender} {
+		if _, err := run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	asks := map[string]func(ctx context.Context) error{
+		"event": func(ctx context.Context) error {
+			_, err := n.AskEvent(ctx, 1)
+			return err
+		},
+		"events": func(ctx context.Context) error {
+			_, err := n.AskFilteredEvents(ctx, &storage.Filter{})
+			return err
+		},
+	}
+	for name, ask := range asks {
+		for _, marked := range []bool{false, true} {
+			ctx := context.Background()
+			if marked {
+				ctx = storage.WithReadYourWrites(ctx)
+			}
+			// The event doesn't exist, only the context it's looked for within matters.
+			_ = ask(ctx)
+			select {
+			case got := <-db.reads:
+				if got != marked {
+					t.Errorf("%s read your writes = %v, want %v", name, got, marked)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%s isn't read", name)
+			}
+		}
+	}
+}
+
+// racingStorage -- fails reads once an event is patched, and lets another change of the event land right before
+// the first races patches.
+type racingStorage struct {
+	*memory.Storage
+	races   int
+	patched bool
+}
+
+func (s *racingStorage) GetEvent(ctx context.Context, id uint64) (*storage.Event, error) {
+	if s.patched {
+		return nil, errors.New("replica is gone")
+	}
+	return s.Storage.GetEvent(ctx, id)
+}
+
+func (s *racingStorage) PatchEvent(ctx context.Context, patch *storage.EventPatch) (uint64, error) {
+	if err := s.race(ctx, patch.Id); err != nil {
+		return 0, err
+	}
+	version, err := s.Storage.PatchEvent(ctx, patch)
+	s.patched = err == nil
+	return version, err
+}
+
+func (s *racingStorage) PatchSeries(ctx context.Context, patch *storage.EventPatch) (uint64, error) {
+	if err := s.race(ctx, patch.Id); err != nil {
+		return 0, err
+	}
+	version, err := s.Storage.PatchSeries(ctx, patch)
+	s.patched = err == nil
+	return version, err
+}
+
+func (s *racingStorage) race(ctx context.Context, id uint64) error {
+	if s.races == 0 {
+		return nil
+	}
+	s.races--
+	address := "changed concurrently"
+	_, err := s.Storage.PatchEvent(ctx, &storage.EventPatch{Id: id, Address: &address})
+	return err
+}
+
+func TestPatchReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		series  bool
+		version uint64
+		races   int
+		// editedAlone -- edits the second occurrence of the series alone and patches it instead of the first one.
+		editedAlone bool
+		// want -- is the version of the event replied, zero if the patch fails with storage.ErrVersionConflict.
+		want uint64
+	}{
+		{name: "event", version: 1, want: 2},
+		{name: "series", series: true, version: 1, want: 2},
+		{name: "any version", want: 2},
+		{name: "any version changed concurrently", races: 2, want: 4},
+		{name: "series at any version changed concurrently", series: true, races: 1, want: 3},
+		{name: "series through an occurrence edited alone", series: true, editedAlone: true, want: 3},
+		{name: "series at the version of an occurrence edited alone", series: true, version: 2, editedAlone: true,
+			want: 3},
+		{name: "any version changed concurrently too often", races: maxPatchAttempts},
+		{name: "version changed concurrently", version: 1, races: 1},
+		{name: "stale version", version: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &racingStorage{Storage: memory.New()}
+			n := connect(t, &config.Nats{Transport: config.TransportLocal}, db)
+			for _, run := range []func(context.Context) (Subscription, error){n.EventPatcher, n.SeriesPatcher} {
+				if _, err := run(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			date := time.Date(2030, time.May, 1, 10, 0, 0, 0, time.UTC)
+			if _, err := db.CreateSeries(context.Background(), &storage.Series{Recurrence: "FREQ=DAILY;COUNT=2",
+				Start: date, Event: storage.Event{Name: "before", City: "Москва", Date: date}}); err != nil {
+				t.Fatal(err)
+			}
+			id, wantDate := uint64(1), date
+			if tt.editedAlone {
+				id, wantDate = 2, date.AddDate(0, 0, 1)
+				address := "edited alone"
+				edit := &storage.EventPatch{Id: id, Address: &address}
+				if _, err := db.Storage.PatchEvent(context.Background(), edit); err != nil {
+					t.Fatal(err)
+				}
+			}
+			db.races = tt.races
+
+			name := "after"
+			patch := &storage.EventPatch{Id: id, Version: tt.version, Name: &name}
+			ask := n.AskPatch
+			if tt.series {
+				ask = n.AskPatchSeries
+			}
+			event, err := ask(context.Background(), patch, "")
+			if tt.want == 0 {
+				if !errors.Is(err, storage.ErrVersionConflict) {
+					t.Fatalf("patch = %v, want %v", err, storage.ErrVersionConflict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("patch: %v", err)
+			}
+
+			db.patched = false
+			saved, err := db.GetEvent(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event.Version != tt.want || event.Name != name || !event.Date.Equal(wantDate) {
+				t.Errorf("replied %q at %v version %d, want %q at %v version %d", event.Name, event.Date,
+					event.Version, name, wantDate, tt.want)
+			}
+			if event.Version != saved.Version || event.Address != saved.Address || event.City != saved.City {
+				t.Errorf("replied %+v, saved %+v", event, saved)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/contracts"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Headers of commands kept in JetStream and of dead letters.
+const (
+	// HeaderReplyTo -- is the subject the reply to the command is sent to, JetStream takes the reply subject
+	// of the message itself for acks.
+	HeaderReplyTo = "Reply-To"
+	// HeaderDeadLetterSubject, HeaderDeadLetterError and HeaderDeliveries -- are the subject the dead letter
+	// was sent to, the error it failed with the last time and the number of times it was delivered.
+	HeaderDeadLetterSubject = "Dead-Letter-Subject"
+	HeaderDeadLetterError   = "Dead-Letter-Error"
+	HeaderDeliveries        = "Deliveries"
+)
+
+// ErrAccepted -- is returned by Ask* methods if the command is kept in JetStream, so it's going to be handled, but
+// its reply isn't received: the asker's context is done first, or the command was already published with the same
+// request id.
+var ErrAccepted = errors.New("command accepted")
+
+// commands -- are subjects of the commands kept in JetStream, each one is consumed by its own durable consumer.
+var commands = []string{
+	contracts.SubjectSaveEvent,
+	contracts.SubjectPatchEvent,
+	contracts.SubjectDeleteEvent,
+	contracts.SubjectSaveSeries,
+	contracts.SubjectPatchSeries,
+}
+
+// isCommand -- reports whether subject is one of commands.
+func isCommand(subject string) bool {
+	return slices.Contains(commands, subject)
+}
+
+// newJetStream -- creates the stream commands are kept in and the stream of dead letters if they don't exist yet.
+func newJetStream(conn *nats.Conn, cfg *config.JetStream) (jetstream.JetStream, error) {
+	const op = "broker.nats.jetstream.newJetStream"
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	if _, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      cfg.Stream,
+		Subjects:  commands,
+		Retention: jetstream.WorkQueuePolicy,
+	}); err != nil {
+		return nil, fmt.Errorf("%s: commands stream: %w", op, err)
+	}
+
+	if _, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.DeadLetterStream,
+		Subjects: []string{cfg.DeadLetterSubject},
+	}); err != nil {
+		return nil, fmt.Errorf("%s: dead letters stream: %w", op, err)
+	}
+	return js, nil
+}
+
+// publishCommand -- publishes the command to JetStream and waits for the reply sent to HeaderReplyTo until ctx
+// is done. The command is kept in the stream once it's published, so it's handled even if the reply isn't received
+// in time, ErrAccepted is returned then. The request id and the subject are the message id of the command, so
+// JetStream drops it if the request is repeated within the duplicates window of the stream.
+func (n *Nats) publishCommand(ctx context.Context, request *nats.Msg) (*nats.Msg, error) {
+	const op = "broker.nats.jetstream.publishCommand"
+
+	inbox := nats.NewInbox()
+	replies := make(chan *nats.Msg, 1)
+	sub, err := n.b.Subscribe(inbox, func(msg *nats.Msg) {
+		select {
+		case replies <- msg:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err := sub.Unsubscribe(); err != nil {
+			slog.Error("couldn't unsubscribe from inbox", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		}
+	}()
+
+	if request.Header == nil {
+		request.Header = nats.Header{}
+	}
+	request.Header.Set(HeaderReplyTo, inbox)
+	var opts []jetstream.PublishOpt
+	if requestId := request.Header.Get(HeaderRequestId); requestId != "" {
+		opts = append(opts, jetstream.WithMsgID(requestId+":"+request.Subject))
+	}
+	ack, err := n.js.PublishMsg(ctx, request, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// The reply to the command published first is sent to its own inbox.
+	if ack.Duplicate {
+		return nil, fmt.Errorf("%s: %w: duplicate of %d", op, ErrAccepted, ack.Sequence)
+	}
+
+	select {
+	case msg := <-replies:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrAccepted, ctx.Err())
+	}
+}
+
+// consumeContext -- stops consuming on Unsubscribe.
+type consumeContext struct {
+	jetstream.ConsumeContext
+}
+
+func (c consumeContext) Unsubscribe() error {
+	c.Stop()
+	return nil
+}
+
+// consume -- runs handle on commands to subject kept in JetStream by the durable consumer of the subject. A command
+// is acked once it's handled or the storage rejected it, and the reply is sent. A command failed for another reason
+// is redelivered after the next of config.JetStream Backoff intervals, on the last delivery it's moved to the dead
+// letters subject with the error replied. Commands which can't be decoded are moved there at once.
+func (n *Nats) consume(ctx context.Context, subject string, handle command) (Subscription, error) {
+	const op = "broker.nats.jetstream.consume"
+
+	consumer, err := n.js.CreateOrUpdateConsumer(ctx, n.cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       n.cfg.Durable + "-" + subject,
+		FilterSubject: subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       n.cfg.AckWait,
+		MaxDeliver:    n.cfg.MaxDeliver,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cc, err := consumer.Consume(func(msg jetstream.Msg) {
+		n.process(ctx, msg, handle)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return consumeContext{cc}, nil
+}
+
+// process -- handles the command msg and acks it, schedules its redelivery or moves it to the dead letters.
+func (n *Nats) process(ctx context.Context, msg jetstream.Msg, handle command) {
+	const op = "broker.nats.jetstream.process"
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		slog.Error("couldn't get command metadata", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		return
+	}
+
+	// The command is kept until it's handled however long it takes, so neither the asker's deadline nor its
+	// cancellation applies to it.
+	ctx = withReadYourWrites(withRequestId(ctx, msg.Headers()), msg.Headers())
+	stop := n.keepInProgress(msg)
+	payload, err := handle(ctx, &nats.Msg{Subject: msg.Subject(), Header: msg.Headers(), Data: msg.Data()})
+	stop()
+	logFailure(ctx, op, msg.Subject(), err)
+
+	res := newReply(payload, err)
+	switch {
+	case res.Code == CodeBadRequest || res.Code == CodeInternal && meta.NumDelivered >= uint64(n.cfg.MaxDeliver):
+		if err = n.deadLetter(ctx, msg, meta.NumDelivered, res.Message); err != nil {
+			slog.Error("couldn't move command to dead letters", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			if err = msg.NakWithDelay(n.backoff(meta.NumDelivered)); err != nil {
+				slog.Error("couldn't nak command", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			}
+			return
+		}
+		n.reply(ctx, msg.Headers().Get(HeaderReplyTo), res)
+		err = msg.Term()
+	case res.Code == CodeInternal:
+		err = msg.NakWithDelay(n.backoff(meta.NumDelivered))
+	default:
+		n.reply(ctx, msg.Headers().Get(HeaderReplyTo), res)
+		err = msg.Ack()
+	}
+	if err != nil {
+		slog.Error("couldn't ack command", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
+
+// keepInProgress -- tells JetStream the command is still being handled every half of AckWait until the returned
+// func is called, so it isn't redelivered to another worker while the handler runs longer than AckWait.
+func (n *Nats) keepInProgress(msg jetstream.Msg) func() {
+	const op = "broker.nats.jetstream.keepInProgress"
+	if n.cfg.AckWait <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(n.cfg.AckWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := msg.InProgress(); err != nil {
+					slog.Error("couldn't mark command in progress", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// backoff -- returns how long to wait before the command delivered the given number of times is redelivered.
+func (n *Nats) backoff(delivered uint64) time.Duration {
+	if len(n.cfg.Backoff) == 0 {
+		return 0
+	}
+	return n.cfg.Backoff[min(int(delivered), len(n.cfg.Backoff))-1]
+}
+
+// deadLetter -- publishes the command to the dead letters subject with the reason it failed.
+func (n *Nats) deadLetter(ctx context.Context, msg jetstream.Msg, delivered uint64, reason string) error {
+	const op = "broker.nats.jetstream.deadLetter"
+
+	letter := nats.NewMsg(n.cfg.DeadLetterSubject)
+	for key, values := range msg.Headers() {
+		letter.Header[key] = values
+	}
+	letter.Header.Set(HeaderDeadLetterSubject, msg.Subject())
+	letter.Header.Set(HeaderDeadLetterError, reason)
+	letter.Header.Set(HeaderDeliveries, strconv.FormatUint(delivered, 10))
+	letter.Data = msg.Data()
+
+	if _, err := n.js.PublishMsg(ctx, letter); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	slog.Warn("command moved to dead letters", slogResponse.SlogOp(op), slog.String("subject", msg.Subject()),
+		slog.String("reason", reason))
+	return nil
+}
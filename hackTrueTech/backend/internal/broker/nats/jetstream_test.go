@@ -0,0 +1,299 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/middleware"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/contracts"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyStorage -- fails to create events while failures is positive.
+type flakyStorage struct {
+	*memory.Storage
+	failures atomic.Int32
+	attempts atomic.Int32
+}
+
+func (s *flakyStorage) CreateEvent(ctx context.Context, event *storage.Event) (uint64, error) {
+	s.attempts.Add(1)
+	if s.failures.Add(-1) >= 0 {
+		return 0, errors.New("connection refused")
+	}
+	return s.Storage.CreateEvent(ctx, event)
+}
+
+// runServer -- starts an embedded nats-server with JetStream and returns the config to connect to it.
+func runServer(t *testing.T) *config.Nats {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server isn't ready")
+	}
+	t.Cleanup(srv.Shutdown)
+
+	return &config.Nats{
+		Address: srv.ClientURL(),
+		JetStream: config.JetStream{
+			Enabled:           true,
+			Stream:            "EVENT_COMMANDS",
+			Durable:           "event-worker",
+			AckWait:           time.Second,
+			MaxDeliver:        3,
+			Backoff:           []time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
+			DeadLetterSubject: "dead_letter.events",
+			DeadLetterStream:  "EVENT_DEAD_LETTERS",
+		},
+	}
+}
+
+func connect(t *testing.T, cfg *config.Nats, db Storage) *Nats {
+	t.Helper()
+
+	n, err := New(cfg, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(n.Close)
+	return n
+}
+
+// runWorker -- consumes commands saving, patching and deleting events until the test ends.
+func runWorker(t *testing.T, n *Nats) {
+	t.Helper()
+
+	for _, run := range []func(context.Context) (Subscription, error){n.EventSaver, n.EventPatcher, n.EventDeleter} {
+		sub, err := run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = sub.Unsubscribe() })
+	}
+}
+
+// deadLetters -- returns the messages moved to the dead letters.
+func deadLetters(t *testing.T, n *Nats) []*jetstream.RawStreamMsg {
+	t.Helper()
+
+	stream, err := n.js.Stream(context.Background(), n.cfg.DeadLetterStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := stream.Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var letters []*jetstream.RawStreamMsg
+	for seq := info.State.FirstSeq; seq <= info.State.LastSeq && info.State.Msgs > 0; seq++ {
+		msg, err := stream.GetMsg(context.Background(), seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		letters = append(letters, msg)
+	}
+	return letters
+}
+
+func TestJetStream_Commands(t *testing.T) {
+	cfg := runServer(t)
+	db := memory.New()
+	n := connect(t, cfg, db)
+	runWorker(t, n)
+
+	ctx := context.Background()
+	id, err := n.AskSave(ctx, &storage.Event{Name: "saved", Owner: "owner", Date: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("AskSave: %v", err)
+	}
+
+	name := "patched"
+	patched, err := n.AskPatch(ctx, &storage.EventPatch{Id: id, Version: 1, Name: &name}, "editor")
+	if err != nil || patched.Name != name || patched.Version != 2 {
+		t.Fatalf("AskPatch = %+v, %v; want patched event", patched, err)
+	}
+	_, err = n.AskPatch(ctx, &storage.EventPatch{Id: id, Version: 1, Name: &name}, "editor")
+	if !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("AskPatch of stale version = %v, want storage.ErrVersionConflict", err)
+	}
+
+	if err = n.AskDelete(ctx, id, "owner"); err != nil {
+		t.Fatalf("AskDelete: %v", err)
+	}
+	if err = n.AskDelete(ctx, id, "owner"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("AskDelete of deleted event = %v, want storage.ErrNotFound", err)
+	}
+
+	records, err := db.GetEventHistory(ctx, id)
+	if err != nil || len(records) != 3 || records[1].Actor != "editor" {
+		t.Errorf("history = %+v, %v; want creation, patch by editor and deletion", records, err)
+	}
+	if letters := deadLetters(t, n); len(letters) != 0 {
+		t.Errorf("%d dead letters, rejected commands mustn't be dead lettered", len(letters))
+	}
+}
+
+func TestJetStream_WorkerDown(t *testing.T) {
+	cfg := runServer(t)
+	db := memory.New()
+	n := connect(t, cfg, db)
+
+	// The command is kept in the stream even though nobody replies to it.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := n.AskSave(ctx, &storage.Event{Name: "queued", Date: time.Now().Add(time.Hour)}); !errors.Is(err,
+		ErrAccepted) {
+		t.Fatalf("AskSave with no worker running = %v, want ErrAccepted", err)
+	}
+
+	runWorker(t, connect(t, cfg, db))
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if event, err := db.GetEvent(context.Background(), 1); err == nil {
+			if event.Name != "queued" {
+				t.Errorf("saved %+v, want queued event", event)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("command sent while the worker was down isn't handled")
+		}
+	}
+}
+
+func TestJetStream_Duplicates(t *testing.T) {
+	cfg := runServer(t)
+	db := &flakyStorage{Storage: memory.New()}
+	n := connect(t, cfg, db)
+
+	// The request is repeated with the same id, e.g. retried by the client, while the worker is down.
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "retried")
+	for i := range 2 {
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		_, err := n.AskSave(ctx, &storage.Event{Name: "queued", Date: time.Now().Add(time.Hour)})
+		cancel()
+		if !errors.Is(err, ErrAccepted) {
+			t.Fatalf("AskSave #%d = %v, want ErrAccepted", i+1, err)
+		}
+	}
+
+	runWorker(t, connect(t, cfg, db))
+	// Another request with its own id is handled after the one kept in the stream.
+	if id, err := n.AskSave(context.Background(), &storage.Event{Name: "another",
+		Date: time.Now().Add(time.Hour)}); err != nil || id != 2 {
+		t.Fatalf("AskSave = %d, %v; want the second event", id, err)
+	}
+	if attempts := db.attempts.Load(); attempts != 2 {
+		t.Errorf("%d events saved, want the repeated request saved once", attempts)
+	}
+}
+
+// slowStorage -- takes delay to create an event.
+type slowStorage struct {
+	flakyStorage
+	delay time.Duration
+}
+
+func (s *slowStorage) CreateEvent(ctx context.Context, event *storage.Event) (uint64, error) {
+	time.Sleep(s.delay)
+	return s.flakyStorage.CreateEvent(ctx, event)
+}
+
+func TestJetStream_InProgress(t *testing.T) {
+	cfg := runServer(t)
+	cfg.JetStream.AckWait = 200 * time.Millisecond
+	db := &slowStorage{flakyStorage: flakyStorage{Storage: memory.New()}, delay: 3 * cfg.JetStream.AckWait}
+	n := connect(t, cfg, db)
+	// Two workers, so a redelivered command would be handled by the other one at once.
+	runWorker(t, n)
+	runWorker(t, connect(t, cfg, db))
+
+	id, err := n.AskSave(context.Background(), &storage.Event{Name: "slow", Date: time.Now().Add(time.Hour)})
+	if err != nil || id != 1 {
+		t.Fatalf("AskSave = %d, %v; want saved", id, err)
+	}
+	// Give a redelivery, if any, time to be handled.
+	time.Sleep(2 * cfg.JetStream.AckWait)
+	if attempts := db.attempts.Load(); attempts != 1 {
+		t.Errorf("%d attempts, want the command handled longer than AckWait delivered once", attempts)
+	}
+}
+
+func TestJetStream_Redelivery(t *testing.T) {
+	cfg := runServer(t)
+
+	t.Run("recovered", func(t *testing.T) {
+		db := &flakyStorage{Storage: memory.New()}
+		db.failures.Store(2)
+		n := connect(t, cfg, db)
+		runWorker(t, n)
+
+		id, err := n.AskSave(context.Background(), &storage.Event{Name: "retried", Date: time.Now().Add(time.Hour)})
+		if err != nil || id != 1 || db.attempts.Load() != 3 {
+			t.Errorf("AskSave = %d, %v after %d attempts; want saved on the 3rd", id, err, db.attempts.Load())
+		}
+	})
+
+	t.Run("dead lettered", func(t *testing.T) {
+		db := &flakyStorage{Storage: memory.New()}
+		db.failures.Store(100)
+		n := connect(t, cfg, db)
+		runWorker(t, n)
+
+		_, err := n.AskSave(context.Background(), &storage.Event{Name: "failing", Date: time.Now().Add(time.Hour)})
+		var replyErr *ReplyError
+		if !errors.As(err, &replyErr) || replyErr.Code != CodeInternal {
+			t.Fatalf("AskSave = %v, want internal error", err)
+		}
+
+		letters := deadLetters(t, n)
+		if len(letters) != 1 || letters[0].Header.Get(HeaderDeadLetterSubject) != contracts.SubjectSaveEvent ||
+			letters[0].Header.Get(HeaderDeliveries) != strconv.Itoa(cfg.JetStream.MaxDeliver) {
+			t.Fatalf("dead letters = %+v, want the command after %d deliveries", letters, cfg.JetStream.MaxDeliver)
+		}
+		if attempts := db.attempts.Load(); attempts != int32(cfg.JetStream.MaxDeliver) {
+			t.Errorf("%d attempts, want %d", attempts, cfg.JetStream.MaxDeliver)
+		}
+	})
+}
+
+func TestJetStream_Poison(t *testing.T) {
+	cfg := runServer(t)
+	db := &flakyStorage{Storage: memory.New()}
+	n := connect(t, cfg, db)
+	runWorker(t, n)
+
+	msg, err := n.request(context.Background(), &nats.Msg{Subject: contracts.SubjectSaveEvent, Data: []byte(`{"name": `)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Data) == "" || db.attempts.Load() != 0 {
+		t.Fatalf("reply = %s after %d attempts, want the error without saving", msg.Data, db.attempts.Load())
+	}
+
+	letters := deadLetters(t, n)
+	if len(letters) != 1 || string(letters[0].Data) != `{"name": ` ||
+		letters[0].Header.Get(HeaderDeliveries) != "1" {
+		t.Fatalf("dead letters = %+v, want the malformed command delivered once", letters)
+	}
+}
@@ -0,0 +1,290 @@
+package nats
+
+import (
+	"bytes"
+	"context"
+	"github.com/nats-io/nats.go"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// localPendingLimit -- is the number of messages a local subscriber may fall behind by, it misses the ones past it
+// as a slow consumer of the NATS server does.
+const localPendingLimit = nats.DefaultSubPendingMsgsLimit
+
+// local -- is the transport within the process, so the API and workers run in one process without the NATS server.
+// Messages are passed to subscribers to the same subject, wildcards aren't supported, and each subscriber handles
+// them one at a time in its own goroutine, as over the NATS server. Every subscriber gets a copy of the message.
+type local struct {
+	mu     sync.Mutex
+	subs   map[string][]*localSub
+	closed bool
+}
+
+func newLocal() *local {
+	return &local{subs: make(map[string][]*localSub)}
+}
+
+func (l *local) PublishMsg(msg *nats.Msg) error {
+	_, err := l.publish(msg)
+	return err
+}
+
+// RequestMsgWithContext -- publishes msg with an inbox to reply to and waits for the first reply until ctx is done.
+// Returns nats.ErrNoResponders if nobody is subscribed to the subject.
+func (l *local) RequestMsgWithContext(ctx context.Context, msg *nats.Msg) (*nats.Msg, error) {
+	inbox := nats.NewInbox()
+	replies := make(chan *nats.Msg, 1)
+	sub, err := l.Subscribe(inbox, func(reply *nats.Msg) {
+		select {
+		case replies <- reply:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	request := *msg
+	request.Reply = inbox
+	subscribers, err := l.publish(&request)
+	if err != nil {
+		return nil, err
+	}
+	if subscribers == 0 {
+		return nil, nats.ErrNoResponders
+	}
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *local) Subscribe(subject string, handle nats.MsgHandler) (Subscription, error) {
+	return l.QueueSubscribe(subject, "", handle)
+}
+
+func (l *local) QueueSubscribe(subject, queue string, handle nats.MsgHandler) (Subscription, error) {
+	if subject == "" {
+		return nil, nats.ErrBadSubject
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, nats.ErrConnectionClosed
+	}
+
+	sub := &localSub{local: l, subject: subject, queue: queue, handle: handle, done: make(chan struct{})}
+	sub.cond = sync.NewCond(&sub.mu)
+	l.subs[subject] = append(l.subs[subject], sub)
+	go sub.run()
+	return sub, nil
+}
+
+// FlushTimeout -- does nothing, messages are passed to subscribers once they're published.
+func (l *local) FlushTimeout(timeout time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nats.ErrConnectionClosed
+	}
+	return nil
+}
+
+func (l *local) Drain(ctx context.Context) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nats.ErrConnectionClosed
+	}
+	var subs []*localSub
+	for _, subscribers := range l.subs {
+		subs = append(subs, subscribers...)
+	}
+	l.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.drain()
+	}
+	defer l.Close()
+	for _, sub := range subs {
+		select {
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Ping -- fails once the transport is closed, it's never disconnected otherwise.
+func (l *local) Ping(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nats.ErrConnectionClosed
+	}
+	return nil
+}
+
+// Close -- stops every subscriber, messages they haven't handled yet are dropped.
+func (l *local) Close() {
+	l.mu.Lock()
+	subs := l.subs
+	l.subs, l.closed = nil, true
+	l.mu.Unlock()
+
+	for _, subscribers := range subs {
+		for _, sub := range subscribers {
+			sub.stop()
+		}
+	}
+}
+
+// publish -- passes msg to every subscriber to its subject outside queue groups and to a random member of each
+// queue group. Returns the number of subscribers msg is passed to.
+func (l *local) publish(msg *nats.Msg) (int, error) {
+	const op = "broker.nats.local.publish"
+
+	if msg.Subject == "" {
+		return 0, nats.ErrBadSubject
+	}
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return 0, nats.ErrConnectionClosed
+	}
+	var targets []*localSub
+	groups := make(map[string][]*localSub)
+	for _, sub := range l.subs[msg.Subject] {
+		if sub.queue == "" {
+			targets = append(targets, sub)
+			continue
+		}
+		groups[sub.queue] = append(groups[sub.queue], sub)
+	}
+	for _, members := range groups {
+		targets = append(targets, members[rand.IntN(len(members))])
+	}
+	l.mu.Unlock()
+
+	for _, sub := range targets {
+		if !sub.push(copyMsg(msg)) {
+			slog.Warn("local subscriber falls behind, message is dropped", slogResponse.SlogOp(op),
+				slog.String("subject", msg.Subject))
+		}
+	}
+	return len(targets), nil
+}
+
+// copyMsg -- returns a copy of msg sharing nothing with it, so subscribers can't change what the others get.
+func copyMsg(msg *nats.Msg) *nats.Msg {
+	cp := &nats.Msg{Subject: msg.Subject, Reply: msg.Reply, Data: bytes.Clone(msg.Data)}
+	if msg.Header != nil {
+		cp.Header = make(nats.Header, len(msg.Header))
+		for key, values := range msg.Header {
+			cp.Header[key] = slices.Clone(values)
+		}
+	}
+	return cp
+}
+
+// localSub -- is a subscriber of the local transport, it handles pending messages in the order they're published.
+type localSub struct {
+	local   *local
+	subject string
+	queue   string
+	handle  nats.MsgHandler
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []*nats.Msg
+	stopped bool
+	// draining -- is set once the subscriber takes no new messages, it stops after handling pending ones.
+	draining bool
+	// done -- is closed once the subscriber stops.
+	done chan struct{}
+}
+
+// Unsubscribe -- stops the subscriber, messages it hasn't handled yet are dropped.
+func (s *localSub) Unsubscribe() error {
+	s.local.mu.Lock()
+	subscribers := s.local.subs[s.subject]
+	i := slices.Index(subscribers, s)
+	if i < 0 {
+		s.local.mu.Unlock()
+		return nats.ErrBadSubscription
+	}
+	if subscribers = slices.Delete(subscribers, i, i+1); len(subscribers) == 0 {
+		delete(s.local.subs, s.subject)
+	} else {
+		s.local.subs[s.subject] = subscribers
+	}
+	s.local.mu.Unlock()
+
+	s.stop()
+	return nil
+}
+
+// push -- queues msg to be handled, reports whether the subscriber keeps up. Messages to a stopped or draining
+// subscriber are dropped, it has just unsubscribed.
+func (s *localSub) push(msg *nats.Msg) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped || s.draining {
+		return true
+	}
+	if len(s.pending) >= localPendingLimit {
+		return false
+	}
+	s.pending = append(s.pending, msg)
+	s.cond.Signal()
+	return true
+}
+
+func (s *localSub) stop() {
+	s.mu.Lock()
+	s.stopped, s.pending = true, nil
+	s.mu.Unlock()
+	s.cond.Signal()
+}
+
+func (s *localSub) drain() {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+	s.cond.Signal()
+}
+
+// run -- handles pending messages until the subscriber is stopped or it's drained.
+func (s *localSub) run() {
+	defer close(s.done)
+
+	for {
+		s.mu.Lock()
+		for len(s.pending) == 0 && !s.stopped && !s.draining {
+			s.cond.Wait()
+		}
+		if s.stopped || len(s.pending) == 0 {
+			s.mu.Unlock()
+			return
+		}
+		msg := s.pending[0]
+		s.pending[0] = nil
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+
+		s.handle(msg)
+	}
+}
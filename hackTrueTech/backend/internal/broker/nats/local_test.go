@@ -0,0 +1,116 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"github.com/nats-io/nats.go"
+	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLocal(t *testing.T) {
+	l := newLocal()
+	t.Cleanup(l.Close)
+
+	var queued atomic.Int32
+	const asked = 10
+	// watched -- receives every request the subscriber outside the queue group gets, it isn't waited for by them.
+	watched := make(chan struct{}, asked+1)
+	for range 2 {
+		if _, err := l.QueueSubscribe("echo", "workers", func(msg *nats.Msg) {
+			queued.Add(1)
+			msg.Data[0] = '!'
+			_ = l.PublishMsg(&nats.Msg{Subject: msg.Reply, Data: msg.Data})
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	watcher, err := l.Subscribe("echo", func(msg *nats.Msg) { watched <- struct{}{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = l.Subscribe("silent", func(msg *nats.Msg) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range asked {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		request := &nats.Msg{Subject: "echo", Data: []byte("ping")}
+		reply, err := l.RequestMsgWithContext(ctx, request)
+		cancel()
+		if err != nil || string(reply.Data) != "!ing" || string(request.Data) != "ping" {
+			t.Fatalf("reply = %v, %v; want a copy of the request changed by one worker", reply, err)
+		}
+	}
+	for i := range asked {
+		select {
+		case <-watched:
+		case <-time.After(time.Second):
+			t.Fatalf("subscriber outside the queue group got %d requests, want %d", i, asked)
+		}
+	}
+	if err = watcher.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if got := queued.Load(); got != asked {
+		t.Errorf("queue group handled %d requests, want %d", got, asked)
+	}
+	if extra := len(watched); extra != 0 {
+		t.Errorf("subscriber outside the queue group got %d requests more than %d", extra, asked)
+	}
+	if err = watcher.Unsubscribe(); !errors.Is(err, nats.ErrBadSubscription) {
+		t.Errorf("second Unsubscribe = %v, want %v", err, nats.ErrBadSubscription)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = l.RequestMsgWithContext(ctx, &nats.Msg{Subject: "silent"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unanswered request = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err = l.RequestMsgWithContext(context.Background(), &nats.Msg{Subject: "nobody"}); !errors.Is(err,
+		nats.ErrNoResponders) {
+		t.Errorf("request to nobody = %v, want %v", err, nats.ErrNoResponders)
+	}
+
+	l.Close()
+	if err = l.PublishMsg(&nats.Msg{Subject: "echo"}); !errors.Is(err, nats.ErrConnectionClosed) {
+		t.Errorf("publish after Close = %v, want %v", err, nats.ErrConnectionClosed)
+	}
+}
+
+func TestLocalBroker(t *testing.T) {
+	if _, err := New(&config.Nats{Transport: config.TransportLocal, JetStream: config.JetStream{Enabled: true}},
+		memory.New()); err == nil {
+		t.Error("local transport runs with JetStream")
+	}
+
+	db := memory.New()
+	n := connect(t, &config.Nats{Transport: config.TransportLocal, QueueGroup: "event-workers"}, db)
+	runWorker(t, n)
+	sub, err := n.EventSender(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sub.Unsubscribe() })
+
+	id, err := n.AskSave(context.Background(), &storage.Event{Name: "event", Date: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("AskSave: %v", err)
+	}
+	if _, err = n.AskEvent(context.Background(), id); err != nil {
+		t.Errorf("AskEvent: %v", err)
+	}
+	if err = n.AskDelete(context.Background(), id, ""); err != nil {
+		t.Errorf("AskDelete: %v", err)
+	}
+	if err = n.AskDelete(context.Background(), id, ""); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("AskDelete of deleted event = %v, want %v", err, storage.ErrNotFound)
+	}
+	if _, err = n.AskFilteredEvents(context.Background(), &storage.Filter{}); !errors.Is(err, nats.ErrNoResponders) {
+		t.Errorf("AskFilteredEvents with no sender = %v, want %v", err, nats.ErrNoResponders)
+	}
+}
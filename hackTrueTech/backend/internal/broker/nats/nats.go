@@ -4,8 +4,13 @@ import (
 	"context"
 	"fmt"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
 	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,22 +18,131 @@ type Storage interface {
 	GetEvent(context.Context, uint64) (*storage.Event, error)
 	DeleteEvent(context.Context, uint64) error
 	CreateEvent(context.Context, *storage.Event) (uint64, error)
-	PatchEvent(context.Context, *storage.Event) error
-	GetEventsByFeature(ctx context.Context, features []string) ([]storage.Event, error)
+	PatchEvent(context.Context, *storage.EventPatch) (uint64, error)
+	GetEventsByFeature(ctx context.Context, filter *storage.Filter) ([]storage.Event, error)
+	CreateSeries(context.Context, *storage.Series) (uint64, error)
+	PatchSeries(context.Context, *storage.EventPatch) (uint64, error)
+	SetEventStatus(ctx context.Context, id uint64, status string) error
+	GetBookers(ctx context.Context, id uint64) ([]string, error)
+	PublishOutbox(ctx context.Context, limit int, publish func([]storage.OutboxMessage) error) (int, error)
+	PurgeOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+// transport -- carries messages between the API and workers, either a connection to the NATS server or the local
+// transport within the process.
+type transport interface {
+	PublishMsg(msg *nats.Msg) error
+	RequestMsgWithContext(ctx context.Context, msg *nats.Msg) (*nats.Msg, error)
+	Subscribe(subject string, handle nats.MsgHandler) (Subscription, error)
+	// QueueSubscribe -- subscribes handle in the queue group, so each message is handled by one of its members.
+	// Handle gets every message if queue is empty.
+	QueueSubscribe(subject, queue string, handle nats.MsgHandler) (Subscription, error)
+	FlushTimeout(timeout time.Duration) error
+	// Drain -- stops subscribers taking new messages, waits until they handle the ones they've taken and closes
+	// the transport. It's closed at once if ctx is done first.
+	Drain(ctx context.Context) error
+	// Ping -- returns the error messages can't be carried with, nil if they can.
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// conn -- is the transport over the NATS server.
+type conn struct {
+	*nats.Conn
+	// closed -- is closed once the connection is.
+	closed <-chan struct{}
+	// connected -- is kept by the disconnect and reconnect handlers of the connection.
+	connected *atomic.Bool
+}
+
+func (c conn) Subscribe(subject string, handle nats.MsgHandler) (Subscription, error) {
+	sub, err := c.Conn.Subscribe(subject, handle)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (c conn) QueueSubscribe(subject, queue string, handle nats.MsgHandler) (Subscription, error) {
+	sub, err := c.Conn.QueueSubscribe(subject, queue, handle)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Ping -- fails while the connection is lost, otherwise makes a round trip to the server.
+func (c conn) Ping(ctx context.Context) error {
+	if !c.connected.Load() {
+		return nats.ErrDisconnected
+	}
+	return c.FlushWithContext(ctx)
+}
+
+func (c conn) Drain(ctx context.Context) error {
+	if err := c.Conn.Drain(); err != nil {
+		return err
+	}
+
+	select {
+	case <-c.closed:
+		return nil
+	case <-ctx.Done():
+		c.Conn.Close()
+		return ctx.Err()
+	}
 }
 
 type Nats struct {
-	b  *nats.Conn
-	db Storage
+	b     transport
+	db    Storage
+	queue string
+	// js -- is nil unless config.JetStream is enabled.
+	js  jetstream.JetStream
+	cfg config.JetStream
+	// running -- maps ids of requests being handled to *running, see Canceller.
+	running sync.Map
 }
 
+// New -- returns the broker over the transport selected by cfg. The local transport is only available without
+// JetStream, it has nowhere to keep commands.
 func New(cfg *config.Nats, db Storage) (*Nats, error) {
 	const op = "broker.nats.New"
 
+	n := &Nats{db: db, queue: cfg.QueueGroup, cfg: cfg.JetStream}
+	switch cfg.Transport {
+	case config.TransportLocal:
+		if cfg.JetStream.Enabled {
+			return nil, fmt.Errorf("%s: jetstream isn't available on the %s transport", op, cfg.Transport)
+		}
+		n.b = newLocal()
+		return n, nil
+	case config.TransportNats, "":
+	default:
+		return nil, fmt.Errorf("%s: unknown transport %q", op, cfg.Transport)
+	}
+
+	closed := make(chan struct{})
+	var connected atomic.Bool
 	natsService, err := nats.Connect(cfg.Address,
 		nats.RetryOnFailedConnect(cfg.Retry),
 		nats.MaxReconnects(cfg.MaxReconnects),
 		nats.ReconnectWait(cfg.ReconnectWait),
+		nats.ConnectHandler(func(*nats.Conn) { connected.Store(true) }),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			connected.Store(false)
+			if err != nil {
+				slog.Warn("nats disconnected", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			}
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			connected.Store(true)
+			slog.Info("nats reconnected", slogResponse.SlogOp(op), slog.String("url", c.ConnectedUrlRedacted()))
+		}),
+		nats.ClosedHandler(func(*nats.Conn) {
+			connected.Store(false)
+			close(closed)
+		}),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
@@ -40,7 +154,39 @@ func New(cfg *config.Nats, db Storage) (*Nats, error) {
 	if err = natsService.FlushTimeout(time.Second); err != nil {
 		return nil, fmt.Errorf("%s: flush timeout: %w", op, err)
 	}
-	return &Nats{b: natsService, db: db}, nil
+
+	// The connect handler only runs if the connection is retried, the first connect is told by Flush succeeding.
+	connected.Store(natsService.IsConnected())
+	n.b = conn{Conn: natsService, closed: closed, connected: &connected}
+	if cfg.JetStream.Enabled {
+		if n.js, err = newJetStream(natsService, &cfg.JetStream); err != nil {
+			natsService.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return n, nil
+}
+
+// Drain -- stops subscribers taking new requests and domain events, waits until the ones they've taken are handled
+// and replied to, and closes the connection, at once if ctx is done first. Requests can't be sent anymore.
+func (n *Nats) Drain(ctx context.Context) error {
+	const op = "broker.nats.Drain"
+
+	if err := n.b.Drain(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Ping -- returns the error requests can't be sent with, e.g. while the connection to the server is lost. Ctx must
+// have a deadline, the round trip to the server is bounded by it.
+func (n *Nats) Ping(ctx context.Context) error {
+	const op = "broker.nats.Ping"
+
+	if err := n.b.Ping(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
 }
 
 func (n *Nats) Close() {
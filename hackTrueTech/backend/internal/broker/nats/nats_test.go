@@ -0,0 +1,111 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	for name, cfg := range map[string]func(t *testing.T) *config.Nats{
+		config.TransportNats: func(t *testing.T) *config.Nats {
+			cfg := runServer(t)
+			cfg.JetStream.Enabled = false
+			return cfg
+		},
+		config.TransportLocal: func(t *testing.T) *config.Nats {
+			return &config.Nats{Transport: config.TransportLocal}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			n := connect(t, cfg(t), memory.New())
+
+			var handled atomic.Int32
+			if _, err := n.b.Subscribe("slow", func(msg *nats.Msg) {
+				time.Sleep(20 * time.Millisecond)
+				handled.Add(1)
+			}); err != nil {
+				t.Fatal(err)
+			}
+			const published = 3
+			for range published {
+				if err := n.b.PublishMsg(&nats.Msg{Subject: "slow"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := n.Drain(ctx); err != nil {
+				t.Fatalf("Drain: %v", err)
+			}
+			if got := handled.Load(); got != published {
+				t.Errorf("%d messages handled before Drain returned, want %d", got, published)
+			}
+			if err := n.b.PublishMsg(&nats.Msg{Subject: "slow"}); !errors.Is(err, nats.ErrConnectionClosed) {
+				t.Errorf("publish after Drain = %v, want %v", err, nats.ErrConnectionClosed)
+			}
+		})
+	}
+}
+
+// startServer -- starts an embedded nats-server on the port, a random one if it's zero.
+func startServer(t *testing.T, port int) *server.Server {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: port, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server isn't ready")
+	}
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+// eventually -- fails the test unless ping returns want within a second.
+func eventually(t *testing.T, ping func(context.Context) error, want error) {
+	t.Helper()
+
+	var err error
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		err = ping(ctx)
+		cancel()
+		if errors.Is(err, want) {
+			return
+		}
+	}
+	t.Fatalf("Ping = %v, want %v", err, want)
+}
+
+func TestPing(t *testing.T) {
+	srv := startServer(t, server.RANDOM_PORT)
+	n := connect(t, &config.Nats{Address: srv.ClientURL(), Retry: true, MaxReconnects: -1,
+		ReconnectWait: 10 * time.Millisecond}, memory.New())
+	eventually(t, n.Ping, nil)
+
+	port := srv.Addr().(*net.TCPAddr).Port
+	srv.Shutdown()
+	eventually(t, n.Ping, nats.ErrDisconnected)
+
+	startServer(t, port)
+	eventually(t, n.Ping, nil)
+
+	n.Close()
+	eventually(t, n.Ping, nats.ErrDisconnected)
+
+	local := connect(t, &config.Nats{Transport: config.TransportLocal}, memory.New())
+	eventually(t, local.Ping, nil)
+	local.Close()
+	eventually(t, local.Ping, nats.ErrConnectionClosed)
+}
@@ -0,0 +1,71 @@
+package nats
+
+import (
+	"context"
+	"github.com/nats-io/nats.go"
+	"github.com/wlcmtunknwndth/hackBPA/internal/contracts"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const (
+	// outboxBatch -- is the number of outbox messages published at once.
+	outboxBatch = 100
+	// outboxRetention -- is how long published messages are kept in the outbox.
+	outboxRetention = 24 * time.Hour
+)
+
+// RelayOutbox -- publishes pending outbox messages to their subjects every interval until ctx is done. Each message
+// carries its idempotency key in the Nats-Msg-Id header, so redelivered ones can be told apart by consumers,
+// and the version of the contracts.
+func (n *Nats) RelayOutbox(ctx context.Context, interval time.Duration) {
+	const op = "broker.nats.outbox.RelayOutbox"
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var purged time.Time
+	for {
+		for {
+			published, err := n.db.PublishOutbox(ctx, outboxBatch, n.publishOutbox)
+			if err != nil {
+				slog.Error("couldn't publish outbox", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+				break
+			}
+			if published < outboxBatch {
+				break
+			}
+		}
+
+		if time.Since(purged) > time.Hour {
+			if _, err := n.db.PurgeOutbox(ctx, time.Now().Add(-outboxRetention)); err != nil {
+				slog.Error("couldn't purge outbox", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			} else {
+				purged = time.Now()
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publishOutbox -- publishes messages and waits for the server to receive them.
+func (n *Nats) publishOutbox(messages []storage.OutboxMessage) error {
+	for i := range messages {
+		msg := nats.NewMsg(messages[i].Subject)
+		msg.Header.Set(nats.MsgIdHdr, messages[i].Key())
+		msg.Header.Set(contracts.HeaderVersion, strconv.Itoa(contracts.Version))
+		msg.Data = messages[i].Payload
+		if err := n.b.PublishMsg(msg); err != nil {
+			return err
+		}
+	}
+	return n.b.FlushTimeout(5 * time.Second)
+}
@@ -0,0 +1,96 @@
+package nats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"net/http"
+)
+
+// Error codes of a reply, the errors storage returns are passed to the asking side by them.
+const (
+	CodeBadRequest        = "bad_request"
+	CodeNotFound          = "not_found"
+	CodeAlreadyExists     = "already_exists"
+	CodeInvalidTransition = "invalid_transition"
+	CodeVersionConflict   = "version_conflict"
+	CodeInternal          = "internal"
+)
+
+// codes -- are the error codes with the HTTP status codes and the storage errors they stand for.
+var codes = []struct {
+	code   string
+	status int
+	err    error
+}{
+	{code: CodeBadRequest, status: http.StatusBadRequest, err: storage.ErrInvalidInput},
+	{code: CodeNotFound, status: http.StatusNotFound, err: storage.ErrNotFound},
+	{code: CodeAlreadyExists, status: http.StatusConflict, err: storage.ErrAlreadyExists},
+	{code: CodeInvalidTransition, status: http.StatusConflict, err: storage.ErrInvalidTransition},
+	{code: CodeVersionConflict, status: http.StatusPreconditionFailed, err: storage.ErrVersionConflict},
+}
+
+// Reply -- is the envelope every subscriber replies with. Status is the HTTP status code of the outcome, Code and
+// Message describe the error if the request failed, Payload is the JSON result of the request otherwise.
+type Reply struct {
+	Status  int             `json:"status"`
+	Code    string          `json:"code,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// ReplyError -- is the error of a failed request returned by Ask* methods. It wraps the storage error the code
+// stands for, so it can be checked with errors.Is.
+type ReplyError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *ReplyError) Error() string {
+	return fmt.Sprintf("%s (%d): %s", e.Code, e.Status, e.Message)
+}
+
+func (e *ReplyError) Unwrap() error {
+	for _, c := range codes {
+		if c.code == e.Code {
+			return c.err
+		}
+	}
+	return nil
+}
+
+// newReply -- returns the reply with payload, or with err if it isn't nil.
+func newReply(payload any, err error) Reply {
+	if err != nil {
+		for _, c := range codes {
+			if errors.Is(err, c.err) {
+				return Reply{Status: c.status, Code: c.code, Message: err.Error()}
+			}
+		}
+		return Reply{Status: http.StatusInternalServerError, Code: CodeInternal, Message: err.Error()}
+	}
+
+	if payload == nil {
+		return Reply{Status: http.StatusOK}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Reply{Status: http.StatusInternalServerError, Code: CodeInternal, Message: err.Error()}
+	}
+	return Reply{Status: http.StatusOK, Payload: data}
+}
+
+// err -- returns *ReplyError if the request failed.
+func (r Reply) err() error {
+	if r.Code == "" && r.Status < http.StatusBadRequest {
+		return nil
+	}
+	return &ReplyError{Status: r.Status, Code: r.Code, Message: r.Message}
+}
+
+// invalid -- marks the error decoding a request as storage.ErrInvalidInput.
+func invalid(err error) error {
+	return fmt.Errorf("%w: %w", storage.ErrInvalidInput, err)
+}
@@ -0,0 +1,70 @@
+package nats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"net/http"
+	"testing"
+)
+
+func TestReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload any
+		err     error
+		status  int
+		code    string
+	}{
+		{name: "payload", payload: uint64(42), status: http.StatusOK},
+		{name: "no payload", status: http.StatusOK},
+		{name: "invalid input", err: invalid(errors.New("unexpected end of JSON input")),
+			status: http.StatusBadRequest, code: CodeBadRequest},
+		{name: "not found", err: fmt.Errorf("storage.GetEvent: %w", storage.ErrNotFound),
+			status: http.StatusNotFound, code: CodeNotFound},
+		{name: "already exists", err: storage.ErrAlreadyExists, status: http.StatusConflict, code: CodeAlreadyExists},
+		{name: "invalid transition", err: storage.ErrInvalidTransition, status: http.StatusConflict,
+			code: CodeInvalidTransition},
+		{name: "version conflict", err: storage.ErrVersionConflict, status: http.StatusPreconditionFailed,
+			code: CodeVersionConflict},
+		{name: "failure", err: errors.New("connection refused"), status: http.StatusInternalServerError,
+			code: CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(newReply(tt.payload, tt.err))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var reply Reply
+			if err = json.Unmarshal(data, &reply); err != nil {
+				t.Fatal(err)
+			}
+			if reply.Status != tt.status || reply.Code != tt.code {
+				t.Fatalf("reply = %+v, want status %d and code %q", reply, tt.status, tt.code)
+			}
+
+			err = reply.err()
+			if tt.err == nil {
+				var id uint64
+				if err != nil || tt.payload != nil && (json.Unmarshal(reply.Payload, &id) != nil || id != 42) {
+					t.Errorf("reply = %+v, %v; want payload %v", reply, err, tt.payload)
+				}
+				return
+			}
+
+			var replyErr *ReplyError
+			if !errors.As(err, &replyErr) || replyErr.Status != tt.status || replyErr.Message != tt.err.Error() {
+				t.Fatalf("err = %v, want *ReplyError with status %d", err, tt.status)
+			}
+			for _, sentinel := range []error{storage.ErrInvalidInput, storage.ErrNotFound, storage.ErrAlreadyExists,
+				storage.ErrInvalidTransition, storage.ErrVersionConflict} {
+				if want := errors.Is(tt.err, sentinel); errors.Is(err, sentinel) != want {
+					t.Errorf("errors.Is(%v, %v) = %t, want %t", err, sentinel, !want, want)
+				}
+			}
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package cacher
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/patrickmn/go-cache"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
 	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
 	"log/slog"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,6 +29,8 @@ type Storage interface {
 type Cacher struct {
 	handler *cache.Cache
 	db      Storage
+	// restored -- is set once the backup copy is restored, see Restored.
+	restored atomic.Bool
 }
 
 // New -- creates new instance of Cacher with Storage interface and cacher.Cache vars. expTime -- is the standard expiration time of cached item.
@@ -71,21 +79,59 @@ func (c *Cacher) GetOrder(id string) (*storage.Event, bool) {
 	return nil, false
 }
 
+// Invalidate -- drops the cached event a domain event tells is updated or deleted, so it's read from the storage again
+// instead of being served with the version it had before. Every instance watches domain events, see
+// nats.WatchDomainEvents, so a change made through any of them reaches the cache of each. Updates of the version
+// cached or an older one are ignored, the instance which made them has cached them already.
+func (c *Cacher) Invalidate(_ context.Context, subject, _ string, payload []byte) error {
+	const op = "cacher.Invalidate"
+
+	if subject != storage.SubjectEventUpdated && subject != storage.SubjectEventDeleted {
+		return nil
+	}
+	var changed struct {
+		Id      uint64 `json:"id"`
+		Version uint64 `json:"version"`
+	}
+	if err := json.Unmarshal(payload, &changed); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	id := formatUint(changed.Id)
+	if data, found := c.handler.Get(id); found && subject == storage.SubjectEventUpdated &&
+		data.(storage.Event).Version >= changed.Version {
+		return nil
+	}
+	c.handler.Delete(id)
+	return nil
+}
+
 // Restore -- restores cached item from backup copy in storage. Must be used at the start of ur application.
+// An empty backup copy is restored as well, there's just nothing to cache.
 func (c *Cacher) Restore() error {
 	orders, err := c.db.RestoreCache()
 	//fmt.Println(orders)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.restored.Store(true)
+		return nil
+	}
 	if err != nil {
-		slog.Error("couldn't restore cacher: ", err)
+		slog.Error("couldn't restore cacher", slogResponse.SlogErr(err))
 		return err
 	}
 
 	for i := range orders {
 		c.CacheOrder(orders[i])
 	}
+	c.restored.Store(true)
 	return nil
 }
 
+// Restored -- reports whether the backup copy is restored.
+func (c *Cacher) Restored() bool {
+	return c.restored.Load()
+}
+
 // SaveCache -- backups cacher to the storage
 func (c *Cacher) SaveCache() error {
 	var err error
@@ -0,0 +1,56 @@
+// Package contracts defines the messages sent over the broker: the subject each one is sent to, the message itself
+// and the payload of the reply to it. Every message is encoded with JSON and carries Version in HeaderVersion.
+//
+// Adding a field to a message is a compatible change, receivers ignore fields they don't know. Removing, renaming or
+// retyping one is an incompatible change: Version has to be bumped and receivers reject messages of another version,
+// so workers and the API have to be upgraded together. TestCompatibility records the shape of every message
+// of the current Version in testdata and fails on an incompatible change of it.
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Version -- is the version of the contracts every message is sent with.
+const Version = 1
+
+// HeaderVersion -- is the header holding the version of the contracts the message was sent with.
+const HeaderVersion = "Contract-Version"
+
+// ErrUnsupportedVersion -- is returned for messages sent with another version of the contracts.
+var ErrUnsupportedVersion = errors.New("unsupported contract version")
+
+// Marshal -- encodes the message.
+func Marshal(v any) ([]byte, error) {
+	const op = "contracts.Marshal"
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return data, nil
+}
+
+// Unmarshal -- decodes the message data sent with version into v.
+func Unmarshal(version string, data []byte, v any) error {
+	const op = "contracts.Unmarshal"
+
+	if err := Check(version); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Check -- returns ErrUnsupportedVersion unless version is the current Version.
+func Check(version string) error {
+	if version != strconv.Itoa(Version) {
+		return fmt.Errorf("%w %q, want %d", ErrUnsupportedVersion, version, Version)
+	}
+	return nil
+}
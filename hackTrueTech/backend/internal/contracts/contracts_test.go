@@ -0,0 +1,152 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "record shapes of the messages of the current version in testdata")
+
+// shapes -- maps a contract to the JSON type of every field of its message and reply, e.g.
+// "get_event reply.date": "time".
+type shapes map[string]string
+
+// shapeOf -- records the JSON type of t and of its fields and elements under path.
+func (s shapes) shapeOf(path string, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		s[path] = "time"
+	case t == reflect.TypeOf(json.RawMessage{}):
+		s[path] = "json"
+	case t.Kind() == reflect.Struct:
+		s[path] = "object"
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			s.shapeOf(path+"."+name, field.Type)
+		}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s[path] = "array"
+		s.shapeOf(path+"[]", t.Elem())
+	case t.Kind() == reflect.Map:
+		s[path] = "object"
+		s.shapeOf(path+"{}", t.Elem())
+	case t.Kind() == reflect.String:
+		s[path] = "string"
+	case t.Kind() == reflect.Bool:
+		s[path] = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s[path] = "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s[path] = "number"
+	default:
+		s[path] = t.Kind().String()
+	}
+}
+
+func current() shapes {
+	s := shapes{}
+	for _, c := range Contracts {
+		s.shapeOf(c.Subject+" message", reflect.TypeOf(c.Message))
+		if c.Reply != nil {
+			s.shapeOf(c.Subject+" reply", reflect.TypeOf(c.Reply))
+		}
+	}
+	return s
+}
+
+func TestCompatibility(t *testing.T) {
+	path := filepath.Join("testdata", fmt.Sprintf("v%d.json", Version))
+	got := current()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !*update {
+		t.Fatalf("no shapes of version %d are recorded, run go test with -update", Version)
+	} else if err != nil && !*update {
+		t.Fatal(err)
+	}
+
+	recorded := shapes{}
+	if err == nil {
+		if err = json.Unmarshal(data, &recorded); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var incompatible bool
+	for field, typ := range recorded {
+		switch actual, found := got[field]; {
+		case !found:
+			t.Errorf("%s is removed", field)
+			incompatible = true
+		case actual != typ:
+			t.Errorf("%s is %s, it was %s", field, actual, typ)
+			incompatible = true
+		}
+	}
+	if incompatible {
+		t.Fatalf("messages of version %d are changed incompatibly, bump contracts.Version", Version)
+	}
+
+	var added []string
+	for field := range got {
+		if _, found := recorded[field]; !found {
+			added = append(added, field)
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+	if !*update {
+		t.Fatalf("%s are added, record them running go test with -update", strings.Join(added, ", "))
+	}
+
+	if data, err = json.MarshalIndent(got, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll("testdata", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	data, err := Marshal(SetStatusRequest{Id: 1, Status: storage.StatusCancelled})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var request SetStatusRequest
+	if err = Unmarshal(strconv.Itoa(Version), data, &request); err != nil || request.Id != 1 ||
+		request.Status != storage.StatusCancelled {
+		t.Errorf("Unmarshal = %+v, %v; want the request", request, err)
+	}
+
+	for _, version := range []string{"", strconv.Itoa(Version + 1)} {
+		if err = Unmarshal(version, data, &request); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("Unmarshal of version %q = %v, want ErrUnsupportedVersion", version, err)
+		}
+	}
+}
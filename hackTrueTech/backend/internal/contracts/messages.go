@@ -0,0 +1,84 @@
+package contracts
+
+import (
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+)
+
+// Subjects requests are sent to.
+const (
+	SubjectGetEvent       = "get_event"
+	SubjectSaveEvent      = "save_event"
+	SubjectDeleteEvent    = "delete_event"
+	SubjectPatchEvent     = "patch_event"
+	SubjectFilteredEvents = "filtered_events"
+	SubjectSaveSeries     = "save_series"
+	SubjectPatchSeries    = "patch_series"
+	SubjectSetStatus      = "set_status"
+	SubjectCancelRequest  = "cancel_request"
+)
+
+// SubjectEventCancelled -- is the subject bookers of a cancelled event are notified at.
+const SubjectEventCancelled = "event.cancelled"
+
+// EventRequest -- asks for the event or to delete it.
+type EventRequest struct {
+	Id uint64 `json:"id"`
+}
+
+// SaveEventRequest -- asks to save the event, it's owned by Owner.
+type SaveEventRequest = storage.Event
+
+// SaveEventReply -- holds the id of the saved event.
+type SaveEventReply struct {
+	Id uint64 `json:"id"`
+}
+
+// PatchEventRequest -- asks to patch the event or every occurrence of its series.
+type PatchEventRequest = storage.EventPatch
+
+// FilteredEventsRequest -- asks for events of the listing built with the filter.
+type FilteredEventsRequest = storage.Filter
+
+// SaveSeriesRequest -- asks to save the series with its occurrences, the reply is the series with ids set.
+type SaveSeriesRequest = storage.Series
+
+// SetStatusRequest -- asks to move the event to the status.
+type SetStatusRequest struct {
+	Id     uint64 `json:"id"`
+	Status string `json:"status"`
+}
+
+// CancelRequest -- tells workers the request made within the HTTP request RequestId is cancelled by the asker.
+type CancelRequest struct {
+	RequestId string `json:"request_id"`
+}
+
+// EventCancelled -- notifies bookers of the cancelled event.
+type EventCancelled = storage.Cancellation
+
+// EventDeleted -- is the domain event of the deleted event.
+type EventDeleted = storage.EventDeletion
+
+// Contract -- is a message sent to Subject and the payload of the reply to it, nil if nothing is replied.
+type Contract struct {
+	Subject string
+	Message any
+	Reply   any
+}
+
+// Contracts -- are every message sent over the broker.
+var Contracts = []Contract{
+	{Subject: SubjectGetEvent, Message: EventRequest{}, Reply: storage.Event{}},
+	{Subject: SubjectSaveEvent, Message: SaveEventRequest{}, Reply: SaveEventReply{}},
+	{Subject: SubjectDeleteEvent, Message: EventRequest{}},
+	{Subject: SubjectPatchEvent, Message: PatchEventRequest{}, Reply: storage.Event{}},
+	{Subject: SubjectFilteredEvents, Message: FilteredEventsRequest{}, Reply: []storage.Event{}},
+	{Subject: SubjectSaveSeries, Message: SaveSeriesRequest{}, Reply: storage.Series{}},
+	{Subject: SubjectPatchSeries, Message: PatchEventRequest{}, Reply: storage.Event{}},
+	{Subject: SubjectSetStatus, Message: SetStatusRequest{}},
+	{Subject: SubjectCancelRequest, Message: CancelRequest{}},
+	{Subject: SubjectEventCancelled, Message: EventCancelled{}},
+	{Subject: storage.SubjectEventCreated, Message: storage.Event{}},
+	{Subject: storage.SubjectEventUpdated, Message: storage.Event{}},
+	{Subject: storage.SubjectEventDeleted, Message: EventDeleted{}},
+}
@@ -0,0 +1,162 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/corsSkip"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/httpResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Storage interface {
+	Book(ctx context.Context, eventId uint64, username string) error
+	CheckIn(ctx context.Context, eventId uint64, username string) error
+	AddFavorite(ctx context.Context, eventId uint64, username string) error
+	RemoveFavorite(ctx context.Context, eventId uint64, username string) error
+}
+
+type BookingHandler struct {
+	Db Storage
+}
+
+const (
+	StatusNotEnoughPermissions = "Not enough permissions"
+	StatusUnauthorized         = "Unauthorized"
+	StatusBadRequest           = "Bad request"
+	StatusNotFound             = "Not found"
+	StatusInternalServerError  = "Internal server error"
+	StatusBooked               = "Event booked"
+	StatusCheckedIn            = "Checked in"
+	StatusFavoriteAdded        = "Added to favorites"
+	StatusFavoriteRemoved      = "Removed from favorites"
+)
+
+func (b *BookingHandler) Book(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.booking.Book"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	username, id, ok := userAndEvent(w, r, op)
+	if !ok {
+		return
+	}
+
+	if err := b.Db.Book(ctx, id, username); err != nil {
+		writeStorageError(w, op, "couldn't book event", err)
+		return
+	}
+
+	httpResponse.Write(w, http.StatusCreated, StatusBooked)
+}
+
+// CheckIn -- marks visitor as attended the event. Only admins are allowed to check visitors in.
+func (b *BookingHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.booking.CheckIn"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	if ok, err := auth.IsAdmin(r); !ok {
+		if err != nil {
+			slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+			return
+		}
+		httpResponse.Write(w, http.StatusForbidden, StatusNotEnoughPermissions)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	username := r.URL.Query().Get("username")
+	if err != nil || username == "" {
+		slog.Error("couldn't parse query", slogResponse.SlogOp(op))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	if err = b.Db.CheckIn(ctx, id, username); err != nil {
+		writeStorageError(w, op, "couldn't check in", err)
+		return
+	}
+
+	httpResponse.Write(w, http.StatusOK, StatusCheckedIn)
+}
+
+func (b *BookingHandler) AddFavorite(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.booking.AddFavorite"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	username, id, ok := userAndEvent(w, r, op)
+	if !ok {
+		return
+	}
+
+	if err := b.Db.AddFavorite(ctx, id, username); err != nil {
+		writeStorageError(w, op, "couldn't add favorite", err)
+		return
+	}
+
+	httpResponse.Write(w, http.StatusCreated, StatusFavoriteAdded)
+}
+
+func (b *BookingHandler) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.booking.RemoveFavorite"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	username, id, ok := userAndEvent(w, r, op)
+	if !ok {
+		return
+	}
+
+	if err := b.Db.RemoveFavorite(ctx, id, username); err != nil {
+		slog.Error("couldn't remove favorite", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	httpResponse.Write(w, http.StatusOK, StatusFavoriteRemoved)
+}
+
+// userAndEvent -- gets username from jwt and event id from query, writes error response if any of them is missing.
+func userAndEvent(w http.ResponseWriter, r *http.Request, op string) (string, uint64, bool) {
+	username, err := auth.Username(r)
+	if err != nil {
+		slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+		return "", 0, false
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse query", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return "", 0, false
+	}
+
+	return username, id, true
+}
+
+// writeStorageError -- answers 404 if the event is missing, otherwise logs err with msg and answers 500.
+func writeStorageError(w http.ResponseWriter, op, msg string, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return
+	}
+	slog.Error(msg, slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+}
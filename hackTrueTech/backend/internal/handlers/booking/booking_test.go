@@ -0,0 +1,193 @@
+package booking_test
+
+import (
+	"context"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/booking"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	visitor = "visitor"
+	admin   = "idkidkidk"
+)
+
+// session -- logs username in, registering the user with the password equal to the username if needed, and
+// returns the access cookie.
+func session(t *testing.T, db *memory.Storage, username string) *http.Cookie {
+	t.Helper()
+
+	if _, err := db.GetPassword(context.Background(), username); err != nil {
+		if err = db.RegisterUser(context.Background(), &auth.User{Username: username, Password: username}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	srv := auth.Auth{Db: db}
+	srv.LogIn(w, httptest.NewRequest(http.MethodPost, "/login",
+		strings.NewReader(`{"username": "`+username+`", "password": "`+username+`"}`)))
+	for _, cookie := range w.Result().Cookies() {
+		return cookie
+	}
+	t.Fatalf("%s couldn't log in: %d", username, w.Code)
+	return nil
+}
+
+func newEvent(t *testing.T, db *memory.Storage) uint64 {
+	t.Helper()
+
+	id, err := db.CreateEvent(context.Background(), &storage.Event{Name: "booked", Date: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestBookingHandler_Book(t *testing.T) {
+	t.Setenv("auth_key", "test")
+
+	tests := []struct {
+		name       string
+		username   string
+		id         func(id uint64) string
+		statusCode int
+		booked     bool
+		// deleted -- deletes the event before booking it.
+		deleted bool
+	}{
+		{name: "visitor", username: visitor, id: func(id uint64) string { return strconv.FormatUint(id, 10) },
+			statusCode: http.StatusCreated, booked: true},
+		{name: "anonymous", id: func(id uint64) string { return strconv.FormatUint(id, 10) },
+			statusCode: http.StatusUnauthorized},
+		{name: "malformed id", username: visitor, id: func(uint64) string { return "first" },
+			statusCode: http.StatusBadRequest},
+		{name: "missing event", username: visitor, id: func(uint64) string { return "4242" },
+			statusCode: http.StatusNotFound},
+		{name: "deleted event", username: visitor, deleted: true,
+			id: func(id uint64) string { return strconv.FormatUint(id, 10) }, statusCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := memory.New()
+			handler := booking.BookingHandler{Db: db}
+			id := newEvent(t, db)
+			if tt.deleted {
+				if err := db.DeleteEvent(context.Background(), id); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/book?id="+tt.id(id), nil)
+			if tt.username != "" {
+				r.AddCookie(session(t, db, tt.username))
+			}
+			w := httptest.NewRecorder()
+			handler.Book(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+			if booked, _ := db.IsBooked(context.Background(), id, visitor); booked != tt.booked {
+				t.Errorf("booked = %v, want %v", booked, tt.booked)
+			}
+		})
+	}
+}
+
+func TestBookingHandler_CheckIn(t *testing.T) {
+	t.Setenv("auth_key", "test")
+
+	tests := []struct {
+		name       string
+		username   string
+		query      string
+		statusCode int
+		checkedIn  bool
+		// deleted -- deletes the event before checking in.
+		deleted bool
+	}{
+		{name: "admin", username: admin, query: "&username=" + visitor, statusCode: http.StatusOK, checkedIn: true},
+		{name: "not admin", username: visitor, query: "&username=" + visitor, statusCode: http.StatusForbidden},
+		{name: "anonymous", query: "&username=" + visitor, statusCode: http.StatusUnauthorized},
+		{name: "no visitor", username: admin, statusCode: http.StatusBadRequest},
+		{name: "deleted event", username: admin, query: "&username=" + visitor, deleted: true,
+			statusCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := memory.New()
+			handler := booking.BookingHandler{Db: db}
+			id := newEvent(t, db)
+			if tt.deleted {
+				if err := db.DeleteEvent(context.Background(), id); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/check_in?id="+strconv.FormatUint(id, 10)+tt.query, nil)
+			if tt.username != "" {
+				r.AddCookie(session(t, db, tt.username))
+			}
+			w := httptest.NewRecorder()
+			handler.CheckIn(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+			if booked, _ := db.IsBooked(context.Background(), id, visitor); booked != tt.checkedIn {
+				t.Errorf("checked in = %v, want %v", booked, tt.checkedIn)
+			}
+		})
+	}
+}
+
+func TestBookingHandler_Favorites(t *testing.T) {
+	t.Setenv("auth_key", "test")
+
+	db := memory.New()
+	handler := booking.BookingHandler{Db: db}
+	id := newEvent(t, db)
+	cookie := session(t, db, visitor)
+
+	tests := []struct {
+		name       string
+		handle     http.HandlerFunc
+		statusCode int
+		favorite   bool
+	}{
+		{name: "add", handle: handler.AddFavorite, statusCode: http.StatusCreated, favorite: true},
+		{name: "add again", handle: handler.AddFavorite, statusCode: http.StatusCreated, favorite: true},
+		{name: "remove", handle: handler.RemoveFavorite, statusCode: http.StatusOK},
+		{name: "remove again", handle: handler.RemoveFavorite, statusCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/favorite?id="+strconv.FormatUint(id, 10), nil)
+			r.AddCookie(cookie)
+			w := httptest.NewRecorder()
+			tt.handle(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+			events, err := db.GetUserEvents(context.Background(), visitor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if favorite := len(events) == 1; favorite != tt.favorite {
+				t.Errorf("favorite = %v, want %v", favorite, tt.favorite)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/corsSkip"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/httpResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/ical"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Storage interface {
+	GetCalendarToken(ctx context.Context, username string) (string, error)
+	SetCalendarToken(ctx context.Context, username, token string) error
+	GetCalendarUsername(ctx context.Context, token string) (string, error)
+	GetUserEvents(ctx context.Context, username string) ([]storage.Event, error)
+}
+
+type Broker interface {
+	AskEvent(context.Context, uint64) ([]byte, error)
+}
+
+type CalendarHandler struct {
+	Db     Storage
+	Broker Broker
+}
+
+const (
+	StatusUnauthorized        = "Unauthorized"
+	StatusBadRequest          = "Bad request"
+	StatusNotFound            = "Not found"
+	StatusInternalServerError = "Internal server error"
+)
+
+const (
+	formatICS   = "ics"
+	feedName    = "МТС Live: мои события"
+	feedPath    = "/calendar/%s.ics"
+	tokenLength = 24
+	uidFormat   = "event-%d@hackbpa"
+)
+
+type tokenResponse struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// ExportEvent -- sends a single event as iCalendar, GET /events/{id}.ics
+func (c *CalendarHandler) ExportEvent(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.calendar.ExportEvent"
+	corsSkip.EnableCors(w, r)
+
+	if format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string); format != formatICS {
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	data, err := c.Broker.AskEvent(r.Context(), id)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return
+	case err != nil:
+		slog.Error("couldn't get event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	var event storage.Event
+	if err = json.Unmarshal(data, &event); err != nil {
+		slog.Error("couldn't unmarshal event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+	username, _ := auth.Username(r)
+	isAdmin, _ := auth.IsAdmin(r)
+	if !event.VisibleTo(username, isAdmin) {
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"event-%d.ics\"", event.Id))
+	writeCalendar(w, op, event.Name, []storage.Event{event})
+}
+
+// Feed -- sends user's booked and favorite events as subscribable iCalendar feed, GET /calendar/{token}.ics.
+// The feed is authorized with the token only, so calendar apps can fetch it without cookies.
+func (c *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.calendar.Feed"
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	if format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string); format != formatICS {
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return
+	}
+
+	username, err := c.Db.GetCalendarUsername(ctx, chi.URLParam(r, "token"))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+			return
+		}
+		slog.Error("couldn't get calendar owner", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	events, err := c.Db.GetUserEvents(ctx, username)
+	if err != nil {
+		slog.Error("couldn't get user events", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	writeCalendar(w, op, feedName, events)
+}
+
+// GetToken -- sends link to user's calendar feed creating it on the first call.
+func (c *CalendarHandler) GetToken(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.calendar.GetToken"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	username, err := auth.Username(r)
+	if err != nil {
+		slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+		return
+	}
+
+	token, err := c.Db.GetCalendarToken(ctx, username)
+	if errors.Is(err, storage.ErrNotFound) {
+		token, err = c.newToken(ctx, username)
+	}
+	if err != nil {
+		slog.Error("couldn't get calendar token", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	writeToken(w, op, token)
+}
+
+// RotateToken -- replaces user's calendar feed link, previously shared links stop working.
+func (c *CalendarHandler) RotateToken(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.calendar.RotateToken"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	username, err := auth.Username(r)
+	if err != nil {
+		slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+		return
+	}
+
+	token, err := c.newToken(ctx, username)
+	if err != nil {
+		slog.Error("couldn't rotate calendar token", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	writeToken(w, op, token)
+}
+
+func (c *CalendarHandler) newToken(ctx context.Context, username string) (string, error) {
+	const op = "handlers.calendar.newToken"
+
+	buf := make([]byte, tokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := c.Db.SetCalendarToken(ctx, username, token); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return token, nil
+}
+
+func writeToken(w http.ResponseWriter, op string, token string) {
+	data, err := json.Marshal(tokenResponse{Token: token, URL: fmt.Sprintf(feedPath, token)})
+	if err != nil {
+		slog.Error("couldn't marshal token", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+	if _, err = w.Write(data); err != nil {
+		slog.Error("couldn't write token", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
+
+func writeCalendar(w http.ResponseWriter, op string, name string, events []storage.Event) {
+	var entries = make([]ical.Event, 0, len(events))
+	for i := range events {
+		entries = append(entries, toICal(&events[i]))
+	}
+
+	w.Header().Set("Content-Type", ical.ContentType)
+	if err := ical.Write(w, name, entries); err != nil {
+		slog.Error("couldn't write calendar", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
+
+func toICal(event *storage.Event) ical.Event {
+	location := event.Address
+	if event.City != "" {
+		location = fmt.Sprintf("%s, %s", event.Address, event.City)
+	}
+
+	return ical.Event{
+		UID:         fmt.Sprintf(uidFormat, event.Id),
+		Summary:     event.Name,
+		Description: event.Description,
+		Location:    location,
+		Start:       event.Date,
+		Sequence:    event.Version,
+		Cancelled:   event.Status == storage.StatusCancelled,
+	}
+}
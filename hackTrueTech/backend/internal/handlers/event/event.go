@@ -1,13 +1,18 @@
 package event
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/go-chi/chi"
 	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/broker/nats"
 	"github.com/wlcmtunknwndth/hackBPA/internal/lib/compareStrings"
 	"github.com/wlcmtunknwndth/hackBPA/internal/lib/corsSkip"
 	"github.com/wlcmtunknwndth/hackBPA/internal/lib/httpResponse"
 	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/operations"
 	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
 	"io"
 	"log/slog"
@@ -15,6 +20,9 @@ import (
 	"net/url"
 	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type Cache interface {
@@ -23,16 +31,30 @@ type Cache interface {
 }
 
 type Broker interface {
-	AskSave(*storage.Event) (uint64, error)
-	AskFilteredEvents([]string) ([]byte, error)
-	AskEvent(uint64) ([]byte, error)
-	AskPatch(*storage.Event) error
-	AskDelete(uint64) error
+	AskSave(context.Context, *storage.Event) (uint64, error)
+	AskFilteredEvents(context.Context, *storage.Filter) ([]byte, error)
+	AskEvent(context.Context, uint64) ([]byte, error)
+	AskPatch(ctx context.Context, patch *storage.EventPatch, actor string) (*storage.Event, error)
+	AskDelete(ctx context.Context, id uint64, actor string) error
+	AskSaveSeries(context.Context, *storage.Series) (*storage.Series, error)
+	AskPatchSeries(ctx context.Context, patch *storage.EventPatch, actor string) (*storage.Event, error)
+	AskSetStatus(ctx context.Context, id uint64, status, actor string) error
+}
+
+// Operations -- keeps changes requested in async mode, see operations.Operations.
+type Operations interface {
+	Start(ctx context.Context, kind string, eventId uint64, actor string) (operations.Operation, error)
+	Finish(ctx context.Context, id string, code int, message string, result []byte) error
+	Get(ctx context.Context, id string) (*operations.Operation, error)
 }
 
 type EventsHandler struct {
-	Broker Broker
-	Cache  Cache
+	Broker     Broker
+	Cache      Cache
+	Operations Operations
+
+	// async -- tracks changes requested in async mode which are running, see WaitAsync.
+	async sync.WaitGroup
 }
 
 const (
@@ -44,6 +66,21 @@ const (
 	StatusDeleted              = "Event deleted"
 	StatusPatched              = "Event patched"
 	StatusFound                = "Found"
+	StatusSeriesCreated        = "Series created"
+	StatusNotFound             = "Not found"
+	StatusInvalidTransition    = "Invalid status transition"
+	StatusStatusChanged        = "Status changed"
+	StatusUseModeration        = "Status is changed through moderation only"
+	StatusPreconditionRequired = "If-Match header is required"
+	StatusVersionConflict      = "Event has been changed, get it again"
+	StatusAlreadyExists        = "Already exists"
+	StatusAccepted             = "Accepted, the change is going to be applied"
+	StatusGatewayTimeout       = "Gateway timeout"
+)
+
+const (
+	ScopeOccurrence = "occurrence"
+	ScopeSeries     = "series"
 )
 
 func (e *EventsHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
@@ -61,11 +98,14 @@ func (e *EventsHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
 		return
 	}
+	if !prepareNewEvent(r, event) {
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
 
-	id, err := e.Broker.AskSave(event)
+	id, err := e.Broker.AskSave(r.Context(), event)
 	if err != nil {
-		slog.Error("couldn't send event to broker", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		writeBrokerError(w, op, "couldn't send event to broker", err)
 		return
 	}
 	event.Id = id
@@ -74,15 +114,75 @@ func (e *EventsHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	httpResponse.Write(w, http.StatusCreated, fmt.Sprintf("%s: id: %d", StatusEventCreated, id))
 }
 
+// CreateSeries -- creates recurring event. Form-data is the same as for CreateEvent, where date is the start of
+// the series, plus "recurrence" RRULE and optional "exception" dates in RFC3339.
+func (e *EventsHandler) CreateSeries(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.event.CreateSeries"
+
+	corsSkip.EnableCors(w, r)
+
+	event, err := storage.ParseFormData(r)
+	if err != nil {
+		slog.Error("couldn't parse form-data", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+	if !prepareNewEvent(r, event) {
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	recurrence := r.MultipartForm.Value["recurrence"]
+	if len(recurrence) == 0 {
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	series := storage.Series{
+		Recurrence: recurrence[0],
+		Start:      event.Date,
+		Event:      *event,
+	}
+	for _, exception := range r.MultipartForm.Value["exception"] {
+		date, err := time.Parse(time.RFC3339, exception)
+		if err != nil {
+			slog.Error("couldn't parse exception", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+			return
+		}
+		series.Exceptions = append(series.Exceptions, date)
+	}
+	if _, err = series.Dates(); err != nil {
+		slog.Error("invalid recurrence", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	saved, err := e.Broker.AskSaveSeries(r.Context(), &series)
+	if err != nil {
+		writeBrokerError(w, op, "couldn't send series to broker", err)
+		return
+	}
+
+	httpResponse.Write(w, http.StatusCreated, fmt.Sprintf("%s: id: %d, occurrences: %v",
+		StatusSeriesCreated, saved.Id, saved.Occurrences))
+}
+
 func (e *EventsHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.event.GetEvent"
 	corsSkip.EnableCors(w, r)
+	username, isAdmin := viewer(r)
 	event, found := e.Cache.GetOrder(r.URL.Query().Get("id"))
 	if found {
+		if !event.VisibleTo(username, isAdmin) {
+			httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+			return
+		}
 		data, err := json.Marshal(event)
 		if err != nil {
 			slog.Error("couldn't marshall event from cacher", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
 		}
+		w.Header().Set("ETag", etag(event.Version))
 		if _, err = w.Write(data); err != nil {
 			slog.Error("couldn't send event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
 			httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
@@ -98,13 +198,24 @@ func (e *EventsHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
 		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
 		return
 	}
-	data, err := e.Broker.AskEvent(id)
+	data, err := e.Broker.AskEvent(r.Context(), id)
 	if err != nil {
-		slog.Error("couldn't get event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		writeBrokerError(w, op, "couldn't get event", err)
+		return
+	}
+
+	var asked storage.Event
+	if err = json.Unmarshal(data, &asked); err != nil {
+		slog.Error("couldn't unmarshal event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
 		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
 		return
 	}
+	if !asked.VisibleTo(username, isAdmin) {
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return
+	}
 
+	w.Header().Set("ETag", etag(asked.Version))
 	if _, err = w.Write(data); err != nil {
 		slog.Error("couldn't send event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
 		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
@@ -130,10 +241,19 @@ func (e *EventsHandler) GetEventsByFeature(w http.ResponseWriter, r *http.Reques
 
 	slices.SortFunc(features, compareStrings.CmpStr)
 
-	data, err := e.Broker.AskFilteredEvents(features)
+	username, isAdmin := viewer(r)
+	filter := storage.Filter{
+		Features:      features,
+		SortBy:        params.Get("ordering"),
+		Order:         params.Get("order"),
+		Statuses:      params["status"],
+		Viewer:        username,
+		ViewerIsAdmin: isAdmin,
+	}
+
+	data, err := e.Broker.AskFilteredEvents(r.Context(), &filter)
 	if err != nil {
-		slog.Error("couldn't wait for filtered features", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		writeBrokerError(w, op, "couldn't wait for filtered features", err)
 		return
 	}
 
@@ -147,6 +267,8 @@ func (e *EventsHandler) GetEventsByFeature(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusOK)
 }
 
+// PatchEvent -- applies JSON Merge Patch from the body to the event "id" of it. If-Match header must hold the ETag
+// of the event the patch was made against, "*" applies it to any version.
 func (e *EventsHandler) PatchEvent(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.event.PatchEvent"
 
@@ -155,6 +277,19 @@ func (e *EventsHandler) PatchEvent(w http.ResponseWriter, r *http.Request) {
 	//}
 
 	corsSkip.EnableCors(w, r)
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		httpResponse.Write(w, http.StatusPreconditionRequired, StatusPreconditionRequired)
+		return
+	}
+	version, err := parseIfMatch(ifMatch)
+	if err != nil {
+		slog.Error("couldn't parse If-Match", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
 	body := r.Body
 	defer func(body io.ReadCloser) {
 		err := body.Close()
@@ -169,22 +304,65 @@ func (e *EventsHandler) PatchEvent(w http.ResponseWriter, r *http.Request) {
 		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
 		return
 	}
-	var event storage.Event
-	if err = json.Unmarshal(data, &event); err != nil {
+	patch, err := storage.ParseEventPatch(data)
+	if err != nil {
 		slog.Error("couldn't decode body", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
 		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
 		return
 	}
+	patch.Version = version
+	username, _ := viewer(r)
 
-	if err = e.Broker.AskPatch(&event); err != nil {
-		slog.Error("couldn't publish patch ask", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+	scope := r.URL.Query().Get("scope")
+	if scope != "" && scope != ScopeOccurrence && scope != ScopeSeries {
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	if isAsync(r) {
+		e.runAsync(w, r, op, operations.KindPatchEvent, patch.Id, username,
+			func(ctx context.Context) (int, string, any) {
+				patched, code, status := e.patch(ctx, op, patch, scope, username)
+				if patched == nil {
+					return code, status, nil
+				}
+				return code, status, patched
+			})
 		return
 	}
 
-	httpResponse.Write(w, http.StatusOK, StatusPatched)
+	patched, code, status := e.patch(r.Context(), op, patch, scope, username)
+	if patched == nil {
+		httpResponse.Write(w, code, status)
+		return
+	}
+	w.Header().Set("ETag", etag(patched.Version))
+	writeJSON(w, op, code, patched)
 }
 
+// patch -- applies the patch on behalf of the actor in the scope within ctx and refreshes the cached event. It returns
+// the patched event, or nil with the status code and the status of the response if the patch failed.
+func (e *EventsHandler) patch(ctx context.Context, op string, patch *storage.EventPatch, scope,
+	actor string) (*storage.Event, int, string) {
+	var patched *storage.Event
+	var err error
+	if scope == ScopeSeries {
+		patched, err = e.Broker.AskPatchSeries(ctx, patch, actor)
+	} else {
+		patched, err = e.Broker.AskPatch(ctx, patch, actor)
+	}
+	if err != nil {
+		code, status := brokerStatus(op, "couldn't patch event", err)
+		return nil, code, status
+	}
+
+	if _, found := e.Cache.GetOrder(strconv.FormatUint(patched.Id, 10)); found {
+		e.Cache.CacheOrder(*patched)
+	}
+	return patched, http.StatusOK, StatusPatched
+}
+
+// DeleteEvent -- deletes the event "id" once the deletion is confirmed, DELETE /delete?id=<id>.
 func (e *EventsHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.event.DeleteEvent"
 	//if !checkAdminRights(w, r) {
@@ -200,13 +378,262 @@ func (e *EventsHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = e.Broker.AskDelete(id); err != nil {
-		slog.Error("couldn't delete event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	username, _ := viewer(r)
+	if isAsync(r) {
+		e.runAsync(w, r, op, operations.KindDeleteEvent, id, username,
+			func(ctx context.Context) (int, string, any) {
+				code, status := e.delete(ctx, op, id, username)
+				return code, status, nil
+			})
+		return
+	}
+
+	code, status := e.delete(r.Context(), op, id, username)
+	httpResponse.Write(w, code, status)
+}
+
+// delete -- deletes the event on behalf of the actor within ctx and returns the status code and the status
+// of the response.
+func (e *EventsHandler) delete(ctx context.Context, op string, id uint64, actor string) (int, string) {
+	if err := e.Broker.AskDelete(ctx, id, actor); err != nil {
+		return brokerStatus(op, "couldn't delete event", err)
+	}
+	return http.StatusOK, StatusDeleted
+}
+
+// GetOperation -- sends the change requested in async mode with its outcome once it's finished,
+// GET /operations/{id}. Operations are visible to the user who requested them and admins only.
+func (e *EventsHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.event.GetOperation"
+	corsSkip.EnableCors(w, r)
+
+	username, isAdmin := viewer(r)
+	operation, err := e.Operations.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		slog.Error("couldn't get operation", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
 		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
 		return
 	}
+	if err != nil || operation.Actor != username && !isAdmin {
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return
+	}
+	writeJSON(w, op, http.StatusOK, operation)
+}
+
+// runAsync -- runs the change of kind on the event id requested by the actor in background and responds with
+// 202 and the operation to poll with GetOperation. run returns the status code, the status and the body of
+// the response the change would get in sync mode. It's run within the context of r which isn't cancelled once
+// the response is sent, and is waited for on shutdown with WaitAsync.
+func (e *EventsHandler) runAsync(w http.ResponseWriter, r *http.Request, op, kind string, id uint64, actor string,
+	run func(context.Context) (int, string, any)) {
+	operation, err := e.Operations.Start(r.Context(), kind, id, actor)
+	if err != nil {
+		slog.Error("couldn't start operation", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+	ctx := context.WithoutCancel(r.Context())
+	e.async.Add(1)
+	go func() {
+		defer e.async.Done()
+		code, status, result := run(ctx)
+
+		var data []byte
+		if result != nil {
+			var err error
+			if data, err = json.Marshal(result); err != nil {
+				slog.Error("couldn't marshal operation result", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			}
+		}
+		if err := e.Operations.Finish(ctx, operation.Id, code, status, data); err != nil {
+			slog.Error("couldn't finish operation", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		}
+	}()
+
+	w.Header().Set("Location", "/operations/"+operation.Id)
+	writeJSON(w, op, http.StatusAccepted, operation)
+}
+
+// WaitAsync -- waits for changes requested in async mode to finish until ctx is done. They aren't cancelled with
+// requests, so they're waited for on shutdown not to be cut off.
+func (e *EventsHandler) WaitAsync(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		e.async.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	httpResponse.Write(w, http.StatusOK, StatusDeleted)
+// SetStatus -- moves event to another lifecycle status, POST /event_status?id=<id>&status=<status>.
+// Only the owner of the event and admins are allowed to do it.
+func (e *EventsHandler) SetStatus(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.event.SetStatus"
+	corsSkip.EnableCors(w, r)
+
+	username, err := auth.Username(r)
+	if err != nil {
+		slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+		return
+	}
+	isAdmin, _ := auth.IsAdmin(r)
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	status := r.URL.Query().Get("status")
+	if err != nil || !storage.IsStatus(status) {
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	data, err := e.Broker.AskEvent(r.Context(), id)
+	if err != nil {
+		writeBrokerError(w, op, "couldn't get event", err)
+		return
+	}
+	var event storage.Event
+	if err = json.Unmarshal(data, &event); err != nil {
+		slog.Error("couldn't unmarshal event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	if !isAdmin && event.Owner != username {
+		httpResponse.Write(w, http.StatusForbidden, StatusNotEnoughPermissions)
+		return
+	}
+	if storage.IsModerated(status) {
+		httpResponse.Write(w, http.StatusBadRequest, StatusUseModeration)
+		return
+	}
+	if !storage.CanTransition(event.Status, status) {
+		httpResponse.Write(w, http.StatusConflict, StatusInvalidTransition)
+		return
+	}
+
+	if err = e.Broker.AskSetStatus(r.Context(), id, status, username); err != nil {
+		writeBrokerError(w, op, "couldn't set status", err)
+		return
+	}
+
+	if _, found := e.Cache.GetOrder(r.URL.Query().Get("id")); found {
+		event.Status = status
+		event.Version++
+		e.Cache.CacheOrder(event)
+	}
+
+	httpResponse.Write(w, http.StatusOK, StatusStatusChanged)
+}
+
+// prepareNewEvent -- sets the author of the request as the owner of the event and checks that the event is
+// created either as a draft or for publishing. Events for publishing submitted by anyone but admins enter
+// the moderation queue.
+func prepareNewEvent(r *http.Request, event *storage.Event) bool {
+	username, isAdmin := viewer(r)
+	event.Owner = username
+	switch event.Status {
+	case "", storage.StatusPublished:
+		if isAdmin {
+			event.Status = storage.StatusPublished
+		} else {
+			event.Status = storage.StatusPendingReview
+		}
+	case storage.StatusDraft:
+	default:
+		return false
+	}
+	return true
+}
+
+// writeBrokerError -- writes the response to the request the broker failed with err, see brokerStatus.
+func writeBrokerError(w http.ResponseWriter, op, msg string, err error) {
+	code, status := brokerStatus(op, msg, err)
+	httpResponse.Write(w, code, status)
+}
+
+// brokerStatus -- returns the status code and the status of the response to the request the broker failed with
+// err: errors storage rejected the request with are mapped to 4xx, a command kept in JetStream whose reply isn't
+// received in time to 202, another request timed out to 504, the rest are logged with msg and mapped to 500.
+func brokerStatus(op, msg string, err error) (int, string) {
+	switch {
+	case errors.Is(err, nats.ErrAccepted):
+		return http.StatusAccepted, StatusAccepted
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.Error(msg, slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		return http.StatusGatewayTimeout, StatusGatewayTimeout
+	case errors.Is(err, storage.ErrInvalidInput):
+		return http.StatusBadRequest, StatusBadRequest
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound, StatusNotFound
+	case errors.Is(err, storage.ErrAlreadyExists):
+		return http.StatusConflict, StatusAlreadyExists
+	case errors.Is(err, storage.ErrInvalidTransition):
+		return http.StatusConflict, StatusInvalidTransition
+	case errors.Is(err, storage.ErrVersionConflict):
+		return http.StatusPreconditionFailed, StatusVersionConflict
+	default:
+		slog.Error(msg, slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		return http.StatusInternalServerError, StatusInternalServerError
+	}
+}
+
+// isAsync -- reports whether the change is requested in async mode with "async=true".
+func isAsync(r *http.Request) bool {
+	async, _ := strconv.ParseBool(r.URL.Query().Get("async"))
+	return async
+}
+
+func writeJSON(w http.ResponseWriter, op string, statusCode int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("couldn't marshal response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(statusCode)
+	if _, err = w.Write(data); err != nil {
+		slog.Error("couldn't write response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
+
+// etag -- returns the entity tag of the event version.
+func etag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// parseIfMatch -- returns the event version If-Match header value holds, 0 for "*" matching any version.
+func parseIfMatch(value string) (uint64, error) {
+	value = strings.TrimSpace(value)
+	if value == "*" {
+		return 0, nil
+	}
+	unquoted, err := strconv.Unquote(strings.TrimPrefix(value, "W/"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid entity tag %s: %w", value, err)
+	}
+	version, err := strconv.ParseUint(unquoted, 10, 64)
+	if err != nil || version == 0 {
+		return 0, fmt.Errorf("invalid entity tag %s", value)
+	}
+	return version, nil
+}
+
+// viewer -- returns the username and the admin flag of the requester, empty if the request is anonymous.
+func viewer(r *http.Request) (string, bool) {
+	username, err := auth.Username(r)
+	if err != nil {
+		return "", false
+	}
+	isAdmin, _ := auth.IsAdmin(r)
+	return username, isAdmin
 }
 
 func checkAdminRights(w http.ResponseWriter, r *http.Request) bool {
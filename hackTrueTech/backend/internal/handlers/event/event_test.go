@@ -0,0 +1,663 @@
+package event_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/broker/nats"
+	"github.com/wlcmtunknwndth/hackBPA/internal/cacher"
+	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/event"
+	"github.com/wlcmtunknwndth/hackBPA/internal/operations"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	owner = "owner"
+	other = "other"
+	admin = "idkidkidk"
+)
+
+// fixture -- is a handler over a fresh storage with a published event and a draft of owner.
+type fixture struct {
+	db        *memory.Storage
+	handler   *event.EventsHandler
+	published uint64
+	draft     uint64
+	sessions  map[string]*http.Cookie
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	t.Setenv("auth_key", "test")
+
+	db := memory.New()
+	f := &fixture{
+		db: db,
+		handler: &event.EventsHandler{
+			Broker:     runBroker(t, db),
+			Cache:      cacher.New(db, time.Minute, time.Minute),
+			Operations: operations.New(db),
+		},
+		sessions: make(map[string]*http.Cookie),
+	}
+
+	f.published = f.create(t, &storage.Event{Name: "published", Price: 100, Feature: []string{"deaf"}, Owner: owner,
+		Date: time.Now().Add(time.Hour)})
+	f.draft = f.create(t, &storage.Event{Name: "draft", Price: 200, Feature: []string{"deaf", "blind"}, Owner: owner,
+		Status: storage.StatusDraft, Date: time.Now().Add(2 * time.Hour)})
+
+	srv := auth.Auth{Db: db}
+	for _, username := range []string{owner, other, admin} {
+		if username != admin {
+			if err := db.RegisterUser(context.Background(), &auth.User{Username: username, Password: username}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w := httptest.NewRecorder()
+		srv.LogIn(w, httptest.NewRequest(http.MethodPost, "/login",
+			strings.NewReader(`{"username": "`+username+`", "password": "`+username+`"}`)))
+		for _, cookie := range w.Result().Cookies() {
+			f.sessions[username] = cookie
+		}
+		if f.sessions[username] == nil {
+			t.Fatalf("%s couldn't log in: %d", username, w.Code)
+		}
+	}
+	return f
+}
+
+// runBroker -- returns the broker over the local transport with every worker subscriber running on db, so requests
+// take the same path as in production.
+func runBroker(t *testing.T, db *memory.Storage) *nats.Nats {
+	t.Helper()
+
+	ns, err := nats.New(&config.Nats{Transport: config.TransportLocal, QueueGroup: "event-workers"}, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ns.Close)
+
+	for _, run := range []func(context.Context) (nats.Subscription, error){ns.EventSaver, ns.EventSender,
+		ns.EventPatcher, ns.EventDeleter, ns.FilteredEventsSender, ns.SeriesSaver, ns.SeriesPatcher,
+		ns.StatusChanger, ns.Canceller} {
+		if _, err = run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ns
+}
+
+func (f *fixture) create(t *testing.T, e *storage.Event) uint64 {
+	t.Helper()
+
+	id, err := f.db.CreateEvent(context.Background(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// serve -- runs handle on the request made by username, anonymous if it's empty.
+func (f *fixture) serve(handle http.HandlerFunc, method, target, body, username string) *httptest.ResponseRecorder {
+	return f.do(handle, httptest.NewRequest(method, target, strings.NewReader(body)), username)
+}
+
+// do -- runs handle on r made by username, anonymous if it's empty.
+func (f *fixture) do(handle http.HandlerFunc, r *http.Request, username string) *httptest.ResponseRecorder {
+	if username != "" {
+		r.AddCookie(f.sessions[username])
+	}
+	w := httptest.NewRecorder()
+	handle(w, r)
+	return w
+}
+
+// poll -- gets the operation at location on behalf of username until it's finished.
+func (f *fixture) poll(t *testing.T, location, username string) (int, *operations.Operation) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", path.Base(location))
+		r := httptest.NewRequest(http.MethodGet, location, nil)
+		w := f.do(f.handler.GetOperation, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)), username)
+		if w.Code != http.StatusOK {
+			return w.Code, nil
+		}
+
+		var operation operations.Operation
+		if err := json.Unmarshal(w.Body.Bytes(), &operation); err != nil {
+			t.Fatalf("couldn't decode operation %s: %v", w.Body, err)
+		}
+		if operation.Status != operations.StatusPending || time.Now().After(deadline) {
+			return w.Code, &operation
+		}
+	}
+}
+
+func itoa(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
+
+func TestEventsHandler_GetEvent(t *testing.T) {
+	f := newFixture(t)
+
+	tests := []struct {
+		name       string
+		id         string
+		username   string
+		statusCode int
+		want       string
+	}{
+		{name: "published to anonymous", id: itoa(f.published), statusCode: http.StatusOK, want: "published"},
+		{name: "draft to anonymous", id: itoa(f.draft), statusCode: http.StatusNotFound},
+		{name: "draft to another user", id: itoa(f.draft), username: other, statusCode: http.StatusNotFound},
+		{name: "draft to owner", id: itoa(f.draft), username: owner, statusCode: http.StatusOK, want: "draft"},
+		{name: "draft to admin", id: itoa(f.draft), username: admin, statusCode: http.StatusOK, want: "draft"},
+		{name: "malformed id", id: "first", statusCode: http.StatusBadRequest},
+		{name: "missing event", id: "4242", statusCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := f.serve(f.handler.GetEvent, http.MethodGet, "/event?id="+tt.id, "", tt.username)
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+			if tt.want == "" {
+				return
+			}
+
+			var got storage.Event
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Name != tt.want {
+				t.Errorf("got %s, %v; want event %q", w.Body, err, tt.want)
+			}
+			if etag := w.Header().Get("ETag"); etag != `"1"` {
+				t.Errorf("ETag = %s, want \"1\"", etag)
+			}
+		})
+	}
+}
+
+func TestEventsHandler_GetEventsByFeature(t *testing.T) {
+	f := newFixture(t)
+	f.create(t, &storage.Event{Name: "blind", Price: 50, Feature: []string{"blind"}, Date: time.Now().Add(3 * time.Hour)})
+
+	tests := []struct {
+		name     string
+		query    string
+		username string
+		want     []string
+	}{
+		{name: "no filter", query: "", want: []string{"published", "blind"}},
+		{name: "by feature", query: "?feature=deaf", want: []string{"published"}},
+		{name: "published only by default", query: "?feature=deaf&feature=blind", username: owner, want: []string{}},
+		{name: "sorted by price", query: "?ordering=price", want: []string{"blind", "published"}},
+		{name: "sorted by price descending", query: "?ordering=price&order=descending",
+			want: []string{"published", "blind"}},
+		{name: "drafts to anonymous", query: "?status=draft", want: []string{}},
+		{name: "drafts to owner", query: "?status=draft&feature=blind&feature=deaf", username: owner,
+			want: []string{"draft"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := f.serve(f.handler.GetEventsByFeature, http.MethodGet, "/events"+tt.query, "", tt.username)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			var events []storage.Event
+			if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil {
+				t.Fatalf("couldn't decode %s: %v", w.Body, err)
+			}
+			var got = make([]string, 0, len(events))
+			for _, e := range events {
+				got = append(got, e.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("listed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// racingBroker -- runs race after every event it gets, as if another request changed the event right after it.
+type racingBroker struct {
+	event.Broker
+	race func()
+}
+
+func (b racingBroker) AskEvent(ctx context.Context, id uint64) ([]byte, error) {
+	data, err := b.Broker.AskEvent(ctx, id)
+	b.race()
+	return data, err
+}
+
+func TestEventsHandler_SetStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		username   string
+		statusCode int
+		want       string
+	}{
+		{name: "anonymous", status: storage.StatusCancelled, statusCode: http.StatusUnauthorized,
+			want: storage.StatusPublished},
+		{name: "not owner", status: storage.StatusCancelled, username: other, statusCode: http.StatusForbidden,
+			want: storage.StatusPublished},
+		{name: "owner", status: storage.StatusCancelled, username: owner, statusCode: http.StatusOK,
+			want: storage.StatusCancelled},
+		{name: "admin", status: storage.StatusDraft, username: admin, statusCode: http.StatusOK,
+			want: storage.StatusDraft},
+		{name: "moderated status", status: storage.StatusPendingReview, username: owner,
+			statusCode: http.StatusBadRequest, want: storage.StatusPublished},
+		{name: "unknown status", status: "gone", username: owner, statusCode: http.StatusBadRequest,
+			want: storage.StatusPublished},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			cached, err := f.db.GetEvent(context.Background(), f.published)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.handler.Cache.CacheOrder(*cached)
+
+			w := f.serve(f.handler.SetStatus, http.MethodPost,
+				"/event_status?id="+itoa(f.published)+"&status="+tt.status, "", tt.username)
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+
+			// The handler replies once the worker changed the status.
+			saved, err := f.db.GetEvent(context.Background(), f.published)
+			if err != nil || saved.Status != tt.want {
+				t.Errorf("stored status = %v, %v; want %q", saved, err, tt.want)
+			}
+			if cached, _ := f.handler.Cache.GetOrder(itoa(f.published)); cached.Status != tt.want {
+				t.Errorf("cached status = %q, want %q", cached.Status, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid transition", func(t *testing.T) {
+		f := newFixture(t)
+		if err := f.db.SetEventStatus(context.Background(), f.published, storage.StatusCancelled); err != nil {
+			t.Fatal(err)
+		}
+
+		w := f.serve(f.handler.SetStatus, http.MethodPost,
+			"/event_status?id="+itoa(f.published)+"&status="+storage.StatusDraft, "", owner)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("status code = %d, want %d", w.Code, http.StatusConflict)
+		}
+	})
+
+	rejected := []struct {
+		name       string
+		race       func(f *fixture) error
+		statusCode int
+	}{
+		{name: "cancelled meanwhile", race: func(f *fixture) error {
+			return f.db.SetEventStatus(context.Background(), f.published, storage.StatusCancelled)
+		}, statusCode: http.StatusConflict},
+		{name: "deleted meanwhile", race: func(f *fixture) error {
+			return f.db.DeleteEvent(context.Background(), f.published)
+		}, statusCode: http.StatusConflict},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			cached, err := f.db.GetEvent(context.Background(), f.published)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.handler.Cache.CacheOrder(*cached)
+			f.handler.Broker = racingBroker{Broker: f.handler.Broker, race: func() {
+				if err := tt.race(f); err != nil {
+					t.Error(err)
+				}
+			}}
+
+			w := f.serve(f.handler.SetStatus, http.MethodPost,
+				"/event_status?id="+itoa(f.published)+"&status="+storage.StatusDraft, "", owner)
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+			if cached, _ := f.handler.Cache.GetOrder(itoa(f.published)); cached.Status != storage.StatusPublished {
+				t.Errorf("cached status = %q, want the status before the rejected change", cached.Status)
+			}
+		})
+	}
+
+	t.Run("no worker", func(t *testing.T) {
+		f := newFixture(t)
+		ns, err := nats.New(&config.Nats{Transport: config.TransportLocal}, f.db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(ns.Close)
+		if _, err = ns.EventSender(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		f.handler.Broker = ns
+
+		w := f.serve(f.handler.SetStatus, http.MethodPost,
+			"/event_status?id="+itoa(f.published)+"&status="+storage.StatusCancelled, "", owner)
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("status code = %d, want %d", w.Code, http.StatusInternalServerError)
+		}
+		if saved, err := f.db.GetEvent(context.Background(), f.published); err != nil ||
+			saved.Status != storage.StatusPublished {
+			t.Errorf("stored status = %v, %v; want %q", saved, err, storage.StatusPublished)
+		}
+	})
+}
+
+func TestEventsHandler_PatchEvent(t *testing.T) {
+	patched := func(id uint64) string { return `{"id": ` + itoa(id) + `, "name": "patched"}` }
+
+	tests := []struct {
+		name       string
+		scope      string
+		ifMatch    string
+		body       func(id uint64) string
+		statusCode int
+		want       string
+		etag       string
+	}{
+		{name: "occurrence", ifMatch: `"1"`, body: patched, statusCode: http.StatusOK, want: "patched", etag: `"2"`},
+		{name: "any version", ifMatch: "*", body: patched, statusCode: http.StatusOK, want: "patched", etag: `"2"`},
+		{name: "stale version", ifMatch: `"2"`, body: patched, statusCode: http.StatusPreconditionFailed,
+			want: "published"},
+		{name: "no If-Match", body: patched, statusCode: http.StatusPreconditionRequired, want: "published"},
+		{name: "malformed If-Match", ifMatch: "1", body: patched, statusCode: http.StatusBadRequest,
+			want: "published"},
+		{name: "series of single event", scope: event.ScopeSeries, ifMatch: `"1"`, body: patched,
+			statusCode: http.StatusNotFound, want: "published"},
+		{name: "unknown scope", scope: "all", ifMatch: `"1"`, body: func(id uint64) string { return `{"id": ` + itoa(id) + `}` },
+			statusCode: http.StatusBadRequest, want: "published"},
+		{name: "malformed body", ifMatch: `"1"`, body: func(uint64) string { return `{"id": ` },
+			statusCode: http.StatusBadRequest, want: "published"},
+		{name: "missing event", ifMatch: "*", body: func(uint64) string { return `{"id": 4242, "name": "patched"}` },
+			statusCode: http.StatusNotFound, want: "published"},
+		{name: "null date", ifMatch: `"1"`, body: func(id uint64) string { return `{"id": ` + itoa(id) + `, "date": null}` },
+			statusCode: http.StatusBadRequest, want: "published"},
+		{name: "null name", ifMatch: `"1"`, body: func(id uint64) string { return `{"id": ` + itoa(id) + `, "name": null}` },
+			statusCode: http.StatusBadRequest, want: "published"},
+		{name: "null description", ifMatch: `"1"`, body: func(id uint64) string {
+			return `{"id": ` + itoa(id) + `, "name": "patched", "description": null}`
+		}, statusCode: http.StatusOK, want: "patched", etag: `"2"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+
+			target := "/patch_events"
+			if tt.scope != "" {
+				target += "?scope=" + tt.scope
+			}
+			r := httptest.NewRequest(http.MethodGet, target, strings.NewReader(tt.body(f.published)))
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := f.do(f.handler.PatchEvent, r, owner)
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+			if etag := w.Header().Get("ETag"); etag != tt.etag {
+				t.Errorf("ETag = %s, want %s", etag, tt.etag)
+			}
+			if tt.statusCode == http.StatusOK {
+				var got storage.Event
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Name != tt.want ||
+					strconv.Quote(itoa(got.Version)) != tt.etag {
+					t.Errorf("got %s, %v; want patched event", w.Body, err)
+				}
+			}
+
+			saved, err := f.db.GetEvent(context.Background(), f.published)
+			if err != nil || saved.Name != tt.want || saved.Price != 100 {
+				t.Errorf("stored event = %v, %v; want name %q and price kept", saved, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("cached event", func(t *testing.T) {
+		f := newFixture(t)
+		cached, err := f.db.GetEvent(context.Background(), f.published)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.handler.Cache.CacheOrder(*cached)
+
+		r := httptest.NewRequest(http.MethodGet, "/patch_events", strings.NewReader(patched(f.published)))
+		r.Header.Set("If-Match", `"1"`)
+		if w := f.do(f.handler.PatchEvent, r, owner); w.Code != http.StatusOK {
+			t.Fatalf("status code = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		w := f.serve(f.handler.GetEvent, http.MethodGet, "/event?id="+itoa(f.published), "", "")
+		var got storage.Event
+		if err = json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Name != "patched" {
+			t.Errorf("got %s, %v; want patched event", w.Body, err)
+		}
+		if etag := w.Header().Get("ETag"); etag != `"2"` {
+			t.Errorf("ETag = %s, want \"2\"", etag)
+		}
+	})
+
+	t.Run("cached event changed by another instance", func(t *testing.T) {
+		f := newFixture(t)
+		ns := f.handler.Broker.(*nats.Nats)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if _, err := ns.WatchDomainEvents(ctx, f.handler.Cache.(*cacher.Cacher).Invalidate); err != nil {
+			t.Fatal(err)
+		}
+		go ns.RelayOutbox(ctx, 10*time.Millisecond)
+
+		cached, err := f.db.GetEvent(context.Background(), f.published)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.handler.Cache.CacheOrder(*cached)
+		name := "changed elsewhere"
+		if _, err = f.db.PatchEvent(context.Background(), &storage.EventPatch{Id: f.published, Name: &name}); err != nil {
+			t.Fatal(err)
+		}
+
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+			w := f.serve(f.handler.GetEvent, http.MethodGet, "/event?id="+itoa(f.published), "", "")
+			if etag := w.Header().Get("ETag"); etag == `"2"` {
+				break
+			} else if time.Now().After(deadline) {
+				t.Fatalf("ETag = %s, want \"2\" once the update is published", etag)
+			}
+		}
+
+		r := httptest.NewRequest(http.MethodGet, "/patch_events", strings.NewReader(patched(f.published)))
+		r.Header.Set("If-Match", `"2"`)
+		if w := f.do(f.handler.PatchEvent, r, owner); w.Code != http.StatusOK {
+			t.Errorf("patch with the ETag got: status code = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("async", func(t *testing.T) {
+		f := newFixture(t)
+
+		r := httptest.NewRequest(http.MethodGet, "/patch_events?async=true", strings.NewReader(patched(f.published)))
+		r.Header.Set("If-Match", `"1"`)
+		w := f.do(f.handler.PatchEvent, r, owner)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("status code = %d, want %d", w.Code, http.StatusAccepted)
+		}
+
+		location := w.Header().Get("Location")
+		if code, _ := f.poll(t, location, other); code != http.StatusNotFound {
+			t.Errorf("operation of another user: status code = %d, want %d", code, http.StatusNotFound)
+		}
+		_, operation := f.poll(t, location, owner)
+		var got storage.Event
+		if operation == nil || operation.Status != operations.StatusSucceeded || operation.Code != http.StatusOK ||
+			json.Unmarshal(operation.Result, &got) != nil || got.Name != "patched" || got.Version != 2 {
+			t.Errorf("operation = %+v, want succeeded with patched event", operation)
+		}
+
+		// Another instance of the API finds the operation in the storage they share.
+		replica := &event.EventsHandler{Broker: f.handler.Broker, Cache: cacher.New(f.db, time.Minute, time.Minute),
+			Operations: operations.New(f.db)}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", path.Base(location))
+		r = httptest.NewRequest(http.MethodGet, location, nil)
+		w = f.do(replica.GetOperation, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)), owner)
+		var shared operations.Operation
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &shared) != nil ||
+			shared.Status != operations.StatusSucceeded {
+			t.Errorf("operation on another instance = %d %s, want the succeeded one", w.Code, w.Body)
+		}
+	})
+}
+
+// failingBroker -- fails every deletion with err.
+type failingBroker struct {
+	event.Broker
+	err error
+}
+
+func (b failingBroker) AskDelete(context.Context, uint64, string) error {
+	return b.err
+}
+
+func TestEventsHandler_BrokerTimeouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{name: "command accepted", err: fmt.Errorf("publishCommand: %w: %w", nats.ErrAccepted,
+			context.DeadlineExceeded), statusCode: http.StatusAccepted},
+		{name: "duplicate command", err: fmt.Errorf("publishCommand: %w: duplicate of 1", nats.ErrAccepted),
+			statusCode: http.StatusAccepted},
+		{name: "request timed out", err: fmt.Errorf("request: %w", context.DeadlineExceeded),
+			statusCode: http.StatusGatewayTimeout},
+		{name: "broker failed", err: errors.New("connection closed"), statusCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.handler.Broker = failingBroker{Broker: f.handler.Broker, err: tt.err}
+
+			w := f.serve(f.handler.DeleteEvent, http.MethodDelete, "/delete?id="+itoa(f.published), "", owner)
+			if w.Code != tt.statusCode {
+				t.Errorf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+		})
+	}
+}
+
+// blockingBroker -- deletes events once released is closed.
+type blockingBroker struct {
+	event.Broker
+	released chan struct{}
+}
+
+func (b blockingBroker) AskDelete(ctx context.Context, id uint64, actor string) error {
+	<-b.released
+	return b.Broker.AskDelete(ctx, id, actor)
+}
+
+func TestEventsHandler_WaitAsync(t *testing.T) {
+	f := newFixture(t)
+	released := make(chan struct{})
+	f.handler.Broker = blockingBroker{Broker: f.handler.Broker, released: released}
+
+	w := f.serve(f.handler.DeleteEvent, http.MethodDelete, "/delete?async=true&id="+itoa(f.published), "", owner)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status code = %d, want %d", w.Code, http.StatusAccepted)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := f.handler.WaitAsync(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitAsync of the running deletion = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(released)
+	if err := f.handler.WaitAsync(context.Background()); err != nil {
+		t.Fatalf("WaitAsync: %v", err)
+	}
+	// The operation is finished by the time WaitAsync returns.
+	_, operation := f.poll(t, w.Header().Get("Location"), owner)
+	if operation == nil || operation.Status != operations.StatusSucceeded {
+		t.Errorf("operation = %+v, want succeeded", operation)
+	}
+}
+
+func TestEventsHandler_DeleteEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         func(f *fixture) string
+		statusCode int
+		deleted    bool
+	}{
+		{name: "existing event", id: func(f *fixture) string { return itoa(f.published) },
+			statusCode: http.StatusOK, deleted: true},
+		{name: "missing event", id: func(*fixture) string { return "4242" },
+			statusCode: http.StatusNotFound},
+		{name: "malformed id", id: func(*fixture) string { return "first" }, statusCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+
+			w := f.serve(f.handler.DeleteEvent, http.MethodDelete, "/delete?id="+tt.id(f), "", owner)
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+
+			_, err := f.db.GetEvent(context.Background(), f.published)
+			if deleted := err != nil; deleted != tt.deleted {
+				t.Errorf("event deleted = %v, want %v", deleted, tt.deleted)
+			}
+		})
+	}
+
+	t.Run("async", func(t *testing.T) {
+		f := newFixture(t)
+
+		for id, want := range map[uint64]int{f.published: http.StatusOK, 4242: http.StatusNotFound} {
+			w := f.serve(f.handler.DeleteEvent, http.MethodDelete, "/delete?async=true&id="+itoa(id), "", owner)
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("status code = %d, want %d", w.Code, http.StatusAccepted)
+			}
+
+			_, operation := f.poll(t, w.Header().Get("Location"), owner)
+			if operation == nil || operation.Code != want || operation.EventId != id ||
+				operation.Kind != operations.KindDeleteEvent {
+				t.Errorf("operation = %+v, want deletion of %d with code %d", operation, id, want)
+			}
+		}
+		if _, err := f.db.GetEvent(context.Background(), f.published); err == nil {
+			t.Error("event isn't deleted")
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/httpResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusInternalServerError = "Internal server error"
+)
+
+// Statuses of the server and its dependencies.
+const (
+	StatusAlive    = "alive"
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+	StatusStopping = "stopping"
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+)
+
+// checkTimeout -- is how long a dependency is waited for before it's reported failing.
+const checkTimeout = 2 * time.Second
+
+// Dependency -- is something the server can't handle requests without.
+type Dependency struct {
+	Name string
+	// Check -- returns the error the dependency can't be used with, nil if it can.
+	Check func(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	Dependencies []Dependency
+	// stopping -- is set once the server shuts down, see Stop.
+	stopping atomic.Bool
+}
+
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+type report struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]dependencyStatus `json:"dependencies,omitempty"`
+}
+
+// Stop -- makes the server not ready, so no more requests are routed to it while it shuts down.
+func (h *HealthHandler) Stop() {
+	h.stopping.Store(true)
+}
+
+// Live -- reports the process is alive, GET /healthz. It's 200 {"status": "alive"} whatever the dependencies are.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.health.Live"
+
+	writeJSON(w, op, http.StatusOK, report{Status: StatusAlive})
+}
+
+// Ready -- reports whether the server can handle requests, GET /readyz. Every dependency is checked in parallel
+// and reported with its status, "ok" or "failing", and the latency of the check in milliseconds. The response is
+// 200 {"status": "ready", ...} if every dependency is ok, 503 {"status": "not ready", ...} otherwise, and 503
+// {"status": "stopping"} once the server shuts down. Errors of failing dependencies are logged, not sent.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.health.Ready"
+
+	if h.stopping.Load() {
+		writeJSON(w, op, http.StatusServiceUnavailable, report{Status: StatusStopping})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	res := report{Status: StatusReady, Dependencies: make(map[string]dependencyStatus, len(h.Dependencies))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, dependency := range h.Dependencies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			started := time.Now()
+			err := dependency.Check(ctx)
+			status := dependencyStatus{Status: StatusOK, LatencyMs: float64(time.Since(started).Microseconds()) / 1000}
+			if err != nil {
+				slog.Warn("dependency is failing", slogResponse.SlogOp(op), slog.String("dependency", dependency.Name),
+					slogResponse.SlogErr(err))
+				status.Status = StatusFailing
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			res.Dependencies[dependency.Name] = status
+			if err != nil {
+				res.Status = StatusNotReady
+			}
+		}()
+	}
+	wg.Wait()
+
+	if res.Status != StatusReady {
+		writeJSON(w, op, http.StatusServiceUnavailable, res)
+		return
+	}
+	writeJSON(w, op, http.StatusOK, res)
+}
+
+func writeJSON(w http.ResponseWriter, op string, statusCode int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("couldn't marshal response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if _, err = w.Write(data); err != nil {
+		slog.Error("couldn't write response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
@@ -0,0 +1,103 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/health"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func ok(context.Context) error {
+	return nil
+}
+
+func failing(context.Context) error {
+	return errors.New("connection refused")
+}
+
+// blocked -- is a dependency which doesn't answer until it's given up on.
+func blocked(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+type report struct {
+	Status       string `json:"status"`
+	Dependencies map[string]struct {
+		Status    string   `json:"status"`
+		LatencyMs *float64 `json:"latency_ms"`
+	} `json:"dependencies"`
+}
+
+func serve(t *testing.T, handle http.HandlerFunc, target string) (int, report) {
+	t.Helper()
+
+	// Probes give up on the server sooner than it gives up on its dependencies.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	w := httptest.NewRecorder()
+	handle(w, httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx))
+
+	var res report
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("couldn't decode %q: %v", w.Body.String(), err)
+	}
+	return w.Code, res
+}
+
+func TestHealthHandler_Ready(t *testing.T) {
+	tests := []struct {
+		name         string
+		dependencies []health.Dependency
+		statusCode   int
+		status       string
+		want         map[string]string
+	}{
+		{name: "every dependency ok", dependencies: []health.Dependency{{Name: "storage", Check: ok},
+			{Name: "broker", Check: ok}}, statusCode: http.StatusOK, status: health.StatusReady,
+			want: map[string]string{"storage": health.StatusOK, "broker": health.StatusOK}},
+		{name: "dependency failing", dependencies: []health.Dependency{{Name: "storage", Check: ok},
+			{Name: "broker", Check: failing}}, statusCode: http.StatusServiceUnavailable, status: health.StatusNotReady,
+			want: map[string]string{"storage": health.StatusOK, "broker": health.StatusFailing}},
+		{name: "dependency not answering", dependencies: []health.Dependency{{Name: "storage", Check: blocked}},
+			statusCode: http.StatusServiceUnavailable, status: health.StatusNotReady,
+			want: map[string]string{"storage": health.StatusFailing}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &health.HealthHandler{Dependencies: tt.dependencies}
+
+			code, res := serve(t, h.Ready, "/readyz")
+			if code != tt.statusCode || res.Status != tt.status {
+				t.Fatalf("readyz = %d %q, want %d %q", code, res.Status, tt.statusCode, tt.status)
+			}
+			if len(res.Dependencies) != len(tt.want) {
+				t.Errorf("dependencies = %+v, want %v", res.Dependencies, tt.want)
+			}
+			for name, want := range tt.want {
+				if got := res.Dependencies[name]; got.Status != want || got.LatencyMs == nil {
+					t.Errorf("%s = %+v, want %q with latency", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthHandler_Stop(t *testing.T) {
+	h := &health.HealthHandler{Dependencies: []health.Dependency{{Name: "storage", Check: ok}}}
+	h.Stop()
+
+	if code, res := serve(t, h.Ready, "/readyz"); code != http.StatusServiceUnavailable ||
+		res.Status != health.StatusStopping {
+		t.Errorf("readyz while stopping = %d %q, want %d %q", code, res.Status, http.StatusServiceUnavailable,
+			health.StatusStopping)
+	}
+	if code, res := serve(t, h.Live, "/healthz"); code != http.StatusOK || res.Status != health.StatusAlive {
+		t.Errorf("healthz while stopping = %d %q, want %d %q", code, res.Status, http.StatusOK, health.StatusAlive)
+	}
+}
@@ -0,0 +1,166 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/corsSkip"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/httpResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Storage interface {
+	GetEvent(ctx context.Context, id uint64) (*storage.Event, error)
+	GetEventHistory(ctx context.Context, id uint64) ([]storage.HistoryRecord, error)
+	RestoreEvent(ctx context.Context, id, version uint64) (uint64, error)
+}
+
+type Cache interface {
+	CacheOrder(event storage.Event)
+	GetOrder(uuid string) (*storage.Event, bool)
+}
+
+type HistoryHandler struct {
+	Db    Storage
+	Cache Cache
+}
+
+const (
+	StatusNotEnoughPermissions = "Not enough permissions"
+	StatusUnauthorized         = "Unauthorized"
+	StatusBadRequest           = "Bad request"
+	StatusNotFound             = "Not found"
+	StatusInternalServerError  = "Internal server error"
+	StatusRestored             = "Event restored"
+)
+
+// History -- sends every change of the event with who made it and what changed, GET /events/{id}/history,
+// admins only. Deleted events keep their history.
+func (h *HistoryHandler) History(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.history.History"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	if _, ok := admin(w, r, op); !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	records, err := h.Db.GetEventHistory(ctx, id)
+	if err != nil {
+		slog.Error("couldn't get event history", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+	if len(records) == 0 {
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return
+	}
+
+	entries, err := storage.History(records)
+	if err != nil {
+		slog.Error("couldn't diff event history", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, op, entries)
+}
+
+// Restore -- brings the event back to the state it had at the version from the history,
+// POST /events/{id}/restore?version=<version>, admins only. Deleted events are recreated with the same id.
+func (h *HistoryHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.history.Restore"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	username, ok := admin(w, r, op)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+	version, err := strconv.ParseUint(r.URL.Query().Get("version"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse version", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	restored, err := h.Db.RestoreEvent(storage.WithActor(ctx, username), id, version)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return
+	case err != nil:
+		slog.Error("couldn't restore event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	if _, found := h.Cache.GetOrder(strconv.FormatUint(id, 10)); found {
+		event, err := h.Db.GetEvent(storage.WithReadYourWrites(ctx), id)
+		if err != nil {
+			slog.Error("couldn't get restored event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		} else {
+			h.Cache.CacheOrder(*event)
+		}
+	}
+
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatUint(restored, 10)))
+	httpResponse.Write(w, http.StatusOK, StatusRestored)
+}
+
+func admin(w http.ResponseWriter, r *http.Request, op string) (string, bool) {
+	if ok, err := auth.IsAdmin(r); !ok {
+		if err != nil {
+			slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+			return "", false
+		}
+		httpResponse.Write(w, http.StatusForbidden, StatusNotEnoughPermissions)
+		return "", false
+	}
+
+	username, err := auth.Username(r)
+	if err != nil {
+		httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+		return "", false
+	}
+	return username, true
+}
+
+func writeJSON(w http.ResponseWriter, op string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("couldn't marshal response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	if _, err = w.Write(data); err != nil {
+		slog.Error("couldn't write response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
@@ -0,0 +1,187 @@
+package history_test
+
+import (
+	"context"
+	"github.com/go-chi/chi"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/cacher"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/history"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	visitor = "visitor"
+	admin   = "idkidkidk"
+)
+
+// session -- logs username in, registering the user with the password equal to the username if needed, and
+// returns the access cookie.
+func session(t *testing.T, db *memory.Storage, username string) *http.Cookie {
+	t.Helper()
+
+	if _, err := db.GetPassword(context.Background(), username); err != nil {
+		if err = db.RegisterUser(context.Background(), &auth.User{Username: username, Password: username}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	srv := auth.Auth{Db: db}
+	srv.LogIn(w, httptest.NewRequest(http.MethodPost, "/login",
+		strings.NewReader(`{"username": "`+username+`", "password": "`+username+`"}`)))
+	for _, cookie := range w.Result().Cookies() {
+		return cookie
+	}
+	t.Fatalf("%s couldn't log in: %d", username, w.Code)
+	return nil
+}
+
+// newEvent -- creates the event named "first" and renames it to "second", so it has two versions in the history.
+func newEvent(t *testing.T, db *memory.Storage) uint64 {
+	t.Helper()
+
+	id, err := db.CreateEvent(context.Background(), &storage.Event{Name: "first", Date: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "second"
+	if _, err = db.PatchEvent(context.Background(), &storage.EventPatch{Id: id, Name: &name}); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// request -- returns the request with the {id} route parameter set as chi does.
+func request(method, target, id string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	r := httptest.NewRequest(method, target, nil)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestHistoryHandler_History(t *testing.T) {
+	t.Setenv("auth_key", "test")
+
+	tests := []struct {
+		name       string
+		username   string
+		id         func(id uint64) string
+		statusCode int
+	}{
+		{name: "admin", username: admin, id: formatId, statusCode: http.StatusOK},
+		{name: "not admin", username: visitor, id: formatId, statusCode: http.StatusForbidden},
+		{name: "anonymous", id: formatId, statusCode: http.StatusUnauthorized},
+		{name: "unknown event", username: admin, id: func(id uint64) string { return formatId(id + 1) },
+			statusCode: http.StatusNotFound},
+		{name: "malformed id", username: admin, id: func(uint64) string { return "first" },
+			statusCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := memory.New()
+			handler := history.HistoryHandler{Db: db, Cache: cacher.New(db, time.Minute, time.Minute)}
+			id := newEvent(t, db)
+
+			r := request(http.MethodGet, "/events/"+tt.id(id)+"/history", tt.id(id))
+			if tt.username != "" {
+				r.AddCookie(session(t, db, tt.username))
+			}
+			w := httptest.NewRecorder()
+			handler.History(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+			if tt.statusCode == http.StatusOK && !strings.Contains(w.Body.String(), `"second"`) {
+				t.Errorf("history = %s, want the rename", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHistoryHandler_Restore(t *testing.T) {
+	t.Setenv("auth_key", "test")
+
+	tests := []struct {
+		name       string
+		username   string
+		id         func(id uint64) string
+		version    string
+		statusCode int
+		// want -- is the name of the event after the request.
+		want string
+	}{
+		{name: "admin", username: admin, id: formatId, version: "1", statusCode: http.StatusOK, want: "first"},
+		{name: "not admin", username: visitor, id: formatId, version: "1", statusCode: http.StatusForbidden,
+			want: "second"},
+		{name: "anonymous", id: formatId, version: "1", statusCode: http.StatusUnauthorized, want: "second"},
+		{name: "unknown event", username: admin, id: func(id uint64) string { return formatId(id + 1) },
+			version: "1", statusCode: http.StatusNotFound, want: "second"},
+		{name: "unknown version", username: admin, id: formatId, version: "3", statusCode: http.StatusNotFound,
+			want: "second"},
+		{name: "malformed version", username: admin, id: formatId, version: "first",
+			statusCode: http.StatusBadRequest, want: "second"},
+		{name: "no version", username: admin, id: formatId, statusCode: http.StatusBadRequest, want: "second"},
+		{name: "malformed id", username: admin, id: func(uint64) string { return "first" }, version: "1",
+			statusCode: http.StatusBadRequest, want: "second"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := memory.New()
+			cache := cacher.New(db, time.Minute, time.Minute)
+			handler := history.HistoryHandler{Db: db, Cache: cache}
+			id := newEvent(t, db)
+			event, err := db.GetEvent(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cache.CacheOrder(*event)
+
+			r := request(http.MethodPost, "/events/"+tt.id(id)+"/restore?version="+tt.version, tt.id(id))
+			if tt.username != "" {
+				r.AddCookie(session(t, db, tt.username))
+			}
+			w := httptest.NewRecorder()
+			handler.Restore(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+
+			version := event.Version
+			if tt.statusCode == http.StatusOK {
+				version++
+				if etag, want := w.Header().Get("ETag"), strconv.Quote(formatId(version)); etag != want {
+					t.Errorf("ETag = %s, want %s", etag, want)
+				}
+			}
+			stored, err := db.GetEvent(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Name != tt.want || stored.Version != version {
+				t.Errorf("stored event = %q at %d, want %q at %d", stored.Name, stored.Version, tt.want, version)
+			}
+			cached, found := cache.GetOrder(formatId(id))
+			if !found {
+				t.Fatal("event isn't cached")
+			}
+			if cached.Name != tt.want || cached.Version != version {
+				t.Errorf("cached event = %q at %d, want %q at %d", cached.Name, cached.Version, tt.want, version)
+			}
+		})
+	}
+}
+
+func formatId(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
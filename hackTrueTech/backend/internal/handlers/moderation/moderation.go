@@ -0,0 +1,263 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/corsSkip"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/httpResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Storage interface {
+	GetEvent(ctx context.Context, id uint64) (*storage.Event, error)
+	GetModerationQueue(ctx context.Context) ([]storage.Event, error)
+	Moderate(ctx context.Context, decision *storage.Decision) error
+	GetDecisions(ctx context.Context, eventId uint64) ([]storage.Decision, error)
+}
+
+type Cache interface {
+	CacheOrder(event storage.Event)
+	GetOrder(uuid string) (*storage.Event, bool)
+}
+
+type ModerationHandler struct {
+	Db    Storage
+	Cache Cache
+}
+
+const (
+	StatusNotEnoughPermissions = "Not enough permissions"
+	StatusUnauthorized         = "Unauthorized"
+	StatusBadRequest           = "Bad request"
+	StatusNotFound             = "Not found"
+	StatusInternalServerError  = "Internal server error"
+	StatusInvalidTransition    = "Invalid status transition"
+	StatusReasonRequired       = "Reason is required"
+	StatusApproved             = "Event approved"
+	StatusRejected             = "Event rejected"
+	StatusSubmitted            = "Event submitted for review"
+)
+
+const maxReasonLength = 1024
+
+type rejection struct {
+	Reason string `json:"reason"`
+}
+
+// Queue -- sends events waiting for review, admins only.
+func (m *ModerationHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.moderation.Queue"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	if _, ok := admin(w, r, op); !ok {
+		return
+	}
+
+	events, err := m.Db.GetModerationQueue(ctx)
+	if err != nil {
+		slog.Error("couldn't get moderation queue", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, op, events)
+}
+
+// Approve -- publishes the event waiting for review, admins only.
+func (m *ModerationHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.moderation.Approve"
+	corsSkip.EnableCors(w, r)
+
+	username, ok := admin(w, r, op)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse query", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	m.decide(w, r, op, &storage.Decision{EventId: id, Actor: username, Decision: storage.DecisionApproved}, StatusApproved)
+}
+
+// Reject -- rejects the event waiting for review with the reason from the body, admins only.
+func (m *ModerationHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.moderation.Reject"
+	corsSkip.EnableCors(w, r)
+
+	username, ok := admin(w, r, op)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse query", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	var body rejection
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		slog.Error("couldn't decode body", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Reason == "" || len(body.Reason) > maxReasonLength {
+		httpResponse.Write(w, http.StatusBadRequest, StatusReasonRequired)
+		return
+	}
+
+	m.decide(w, r, op, &storage.Decision{
+		EventId:  id,
+		Actor:    username,
+		Decision: storage.DecisionRejected,
+		Reason:   body.Reason,
+	}, StatusRejected)
+}
+
+// Submit -- sends a draft or a rejected event to the moderation queue, owner of the event only.
+func (m *ModerationHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.moderation.Submit"
+	corsSkip.EnableCors(w, r)
+
+	event, username, ok := m.ownEvent(w, r, op)
+	if !ok {
+		return
+	}
+
+	m.decide(w, r, op, &storage.Decision{
+		EventId:  event.Id,
+		Actor:    username,
+		Decision: storage.DecisionSubmitted,
+	}, StatusSubmitted)
+}
+
+// Decisions -- sends moderation history of the event to its owner or admins.
+func (m *ModerationHandler) Decisions(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.moderation.Decisions"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	event, _, ok := m.ownEvent(w, r, op)
+	if !ok {
+		return
+	}
+
+	decisions, err := m.Db.GetDecisions(ctx, event.Id)
+	if err != nil {
+		slog.Error("couldn't get decisions", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, op, decisions)
+}
+
+func (m *ModerationHandler) decide(w http.ResponseWriter, r *http.Request, op string, decision *storage.Decision, status string) {
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	if err := m.Db.Moderate(ctx, decision); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		case errors.Is(err, storage.ErrInvalidTransition):
+			httpResponse.Write(w, http.StatusConflict, StatusInvalidTransition)
+		default:
+			slog.Error("couldn't moderate event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		}
+		return
+	}
+
+	if cached, found := m.Cache.GetOrder(strconv.FormatUint(decision.EventId, 10)); found {
+		cached.Status = decision.Status()
+		m.Cache.CacheOrder(*cached)
+	}
+
+	httpResponse.Write(w, http.StatusOK, status)
+}
+
+// ownEvent -- gets the event from id query param and checks that requester is either its owner or an admin.
+func (m *ModerationHandler) ownEvent(w http.ResponseWriter, r *http.Request, op string) (*storage.Event, string, bool) {
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	username, err := auth.Username(r)
+	if err != nil {
+		slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+		return nil, "", false
+	}
+	isAdmin, _ := auth.IsAdmin(r)
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse query", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return nil, "", false
+	}
+
+	event, err := m.Db.GetEvent(ctx, id)
+	if err != nil {
+		slog.Error("couldn't get event", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return nil, "", false
+	}
+	if !isAdmin && event.Owner != username {
+		httpResponse.Write(w, http.StatusForbidden, StatusNotEnoughPermissions)
+		return nil, "", false
+	}
+
+	return event, username, true
+}
+
+func admin(w http.ResponseWriter, r *http.Request, op string) (string, bool) {
+	if ok, err := auth.IsAdmin(r); !ok {
+		if err != nil {
+			slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+			return "", false
+		}
+		httpResponse.Write(w, http.StatusForbidden, StatusNotEnoughPermissions)
+		return "", false
+	}
+
+	username, err := auth.Username(r)
+	if err != nil {
+		httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+		return "", false
+	}
+	return username, true
+}
+
+func writeJSON(w http.ResponseWriter, op string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("couldn't marshal response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	if _, err = w.Write(data); err != nil {
+		slog.Error("couldn't write response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
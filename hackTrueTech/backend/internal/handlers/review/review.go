@@ -0,0 +1,206 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/corsSkip"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/httpResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Storage interface {
+	IsBooked(ctx context.Context, eventId uint64, username string) (bool, error)
+	CreateReview(ctx context.Context, review *storage.Review) (uint64, error)
+	GetReviews(ctx context.Context, eventId uint64, withHidden bool) ([]storage.Review, error)
+	SetReviewHidden(ctx context.Context, id uint64, hidden bool) error
+	GetVenueRating(ctx context.Context, city, address string) (*storage.VenueRating, error)
+}
+
+type ReviewHandler struct {
+	Db Storage
+}
+
+const (
+	StatusNotEnoughPermissions = "Not enough permissions"
+	StatusUnauthorized         = "Unauthorized"
+	StatusBadRequest           = "Bad request"
+	StatusNotFound             = "Not found"
+	StatusInternalServerError  = "Internal server error"
+	StatusReviewCreated        = "Review created"
+	StatusAlreadyReviewed      = "Event already reviewed"
+	StatusNotVisited           = "Only visitors who booked or checked in can review the event"
+	StatusHidden               = "Review hidden"
+	StatusRestored             = "Review restored"
+)
+
+const maxCommentLength = 2048
+
+func (rv *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.review.CreateReview"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	username, err := auth.Username(r)
+	if err != nil {
+		slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+		return
+	}
+
+	var review storage.Review
+	if err = json.NewDecoder(r.Body).Decode(&review); err != nil {
+		slog.Error("couldn't decode review", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+	if review.EventId == 0 || len(review.Comment) > maxCommentLength || !storage.ValidateRatings(review.Ratings) {
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+	review.Username = username
+
+	booked, err := rv.Db.IsBooked(ctx, review.EventId, username)
+	if err != nil {
+		slog.Error("couldn't check booking", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+	if !booked {
+		httpResponse.Write(w, http.StatusForbidden, StatusNotVisited)
+		return
+	}
+
+	id, err := rv.Db.CreateReview(ctx, &review)
+	if err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			httpResponse.Write(w, http.StatusConflict, StatusAlreadyReviewed)
+			return
+		}
+		slog.Error("couldn't create review", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	httpResponse.Write(w, http.StatusCreated, fmt.Sprintf("%s: id: %d", StatusReviewCreated, id))
+}
+
+// GetReviews -- sends reviews of the event. Hidden reviews are sent to admins only.
+func (rv *ReviewHandler) GetReviews(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.review.GetReviews"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse query", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	isAdmin, _ := auth.IsAdmin(r)
+	reviews, err := rv.Db.GetReviews(ctx, id, isAdmin)
+	if err != nil {
+		slog.Error("couldn't get reviews", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, op, reviews)
+}
+
+func (rv *ReviewHandler) HideReview(w http.ResponseWriter, r *http.Request) {
+	rv.setHidden(w, r, true)
+}
+
+func (rv *ReviewHandler) RestoreReview(w http.ResponseWriter, r *http.Request) {
+	rv.setHidden(w, r, false)
+}
+
+func (rv *ReviewHandler) setHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	const op = "handlers.review.setHidden"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	if ok, err := auth.IsAdmin(r); !ok {
+		if err != nil {
+			slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+			return
+		}
+		httpResponse.Write(w, http.StatusForbidden, StatusNotEnoughPermissions)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse query", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	if err = rv.Db.SetReviewHidden(ctx, id, hidden); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+			return
+		}
+		slog.Error("couldn't moderate review", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	if hidden {
+		httpResponse.Write(w, http.StatusOK, StatusHidden)
+		return
+	}
+	httpResponse.Write(w, http.StatusOK, StatusRestored)
+}
+
+func (rv *ReviewHandler) GetVenueRating(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.review.GetVenueRating"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	city, address := r.URL.Query().Get("city"), r.URL.Query().Get("address")
+	if city == "" || address == "" {
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	venue, err := rv.Db.GetVenueRating(ctx, city, address)
+	if err != nil {
+		slog.Error("couldn't get venue rating", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, op, venue)
+}
+
+func writeJSON(w http.ResponseWriter, op string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("couldn't marshal response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	if _, err = w.Write(data); err != nil {
+		slog.Error("couldn't write response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
@@ -0,0 +1,165 @@
+package review_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/review"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	visitor  = "visitor"
+	stranger = "stranger"
+	admin    = "idkidkidk"
+)
+
+// session -- logs username in, registering the user with the password equal to the username if needed, and
+// returns the access cookie.
+func session(t *testing.T, db *memory.Storage, username string) *http.Cookie {
+	t.Helper()
+
+	if _, err := db.GetPassword(context.Background(), username); err != nil {
+		if err = db.RegisterUser(context.Background(), &auth.User{Username: username, Password: username}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	srv := auth.Auth{Db: db}
+	srv.LogIn(w, httptest.NewRequest(http.MethodPost, "/login",
+		strings.NewReader(`{"username": "`+username+`", "password": "`+username+`"}`)))
+	for _, cookie := range w.Result().Cookies() {
+		return cookie
+	}
+	t.Fatalf("%s couldn't log in: %d", username, w.Code)
+	return nil
+}
+
+// bookedEvent -- creates an event booked by visitor.
+func bookedEvent(t *testing.T, db *memory.Storage) uint64 {
+	t.Helper()
+
+	ctx := context.Background()
+	id, err := db.CreateEvent(ctx, &storage.Event{Name: "reviewed", Date: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Book(ctx, id, visitor); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestReviewHandler_CreateReview(t *testing.T) {
+	t.Setenv("auth_key", "test")
+
+	db := memory.New()
+	handler := review.ReviewHandler{Db: db}
+	id := strconv.FormatUint(bookedEvent(t, db), 10)
+	visitorCookie, strangerCookie := session(t, db, visitor), session(t, db, stranger)
+
+	tests := []struct {
+		name       string
+		cookie     *http.Cookie
+		body       string
+		statusCode int
+	}{
+		{name: "anonymous", body: `{"event_id": ` + id + `, "ratings": {"staff": 5}}`,
+			statusCode: http.StatusUnauthorized},
+		{name: "not booked", cookie: strangerCookie, body: `{"event_id": ` + id + `, "ratings": {"staff": 5}}`,
+			statusCode: http.StatusForbidden},
+		{name: "unknown aspect", cookie: visitorCookie, body: `{"event_id": ` + id + `, "ratings": {"food": 5}}`,
+			statusCode: http.StatusBadRequest},
+		{name: "score out of range", cookie: visitorCookie, body: `{"event_id": ` + id + `, "ratings": {"staff": 6}}`,
+			statusCode: http.StatusBadRequest},
+		{name: "booked", cookie: visitorCookie,
+			body:       `{"event_id": ` + id + `, "comment": "great", "ratings": {"staff": 5, "wheelchair": 3}}`,
+			statusCode: http.StatusCreated},
+		{name: "reviewed twice", cookie: visitorCookie, body: `{"event_id": ` + id + `, "ratings": {"staff": 1}}`,
+			statusCode: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/create_review", strings.NewReader(tt.body))
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			w := httptest.NewRecorder()
+			handler.CreateReview(w, r)
+
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d: %s", w.Code, tt.statusCode, w.Body)
+			}
+		})
+	}
+
+	eventId, _ := strconv.ParseUint(id, 10, 64)
+	event, err := db.GetEvent(context.Background(), eventId)
+	if err != nil || event.ReviewsCount != 1 || event.Rating != 4 {
+		t.Errorf("reviewed event = %+v, %v; want one review rated 4", event, err)
+	}
+}
+
+func TestReviewHandler_HideReview(t *testing.T) {
+	t.Setenv("auth_key", "test")
+
+	tests := []struct {
+		name       string
+		username   string
+		id         func(id uint64) string
+		statusCode int
+		hidden     bool
+	}{
+		{name: "admin", username: admin, id: func(id uint64) string { return strconv.FormatUint(id, 10) },
+			statusCode: http.StatusOK, hidden: true},
+		{name: "not admin", username: visitor, id: func(id uint64) string { return strconv.FormatUint(id, 10) },
+			statusCode: http.StatusForbidden},
+		{name: "anonymous", id: func(id uint64) string { return strconv.FormatUint(id, 10) },
+			statusCode: http.StatusUnauthorized},
+		{name: "missing review", username: admin, id: func(uint64) string { return "4242" },
+			statusCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := memory.New()
+			handler := review.ReviewHandler{Db: db}
+			eventId := bookedEvent(t, db)
+			id, err := db.CreateReview(context.Background(), &storage.Review{EventId: eventId, Username: visitor,
+				Ratings: map[string]uint8{"staff": 4}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/hide_review?id="+tt.id(id), nil)
+			if tt.username != "" {
+				r.AddCookie(session(t, db, tt.username))
+			}
+			w := httptest.NewRecorder()
+			handler.HideReview(w, r)
+			if w.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d", w.Code, tt.statusCode)
+			}
+
+			w = httptest.NewRecorder()
+			handler.GetReviews(w, httptest.NewRequest(http.MethodGet,
+				"/reviews?id="+strconv.FormatUint(eventId, 10), nil))
+			var reviews []storage.Review
+			if err = json.Unmarshal(w.Body.Bytes(), &reviews); err != nil {
+				t.Fatalf("couldn't decode %s: %v", w.Body, err)
+			}
+			if hidden := len(reviews) == 0; hidden != tt.hidden {
+				t.Errorf("hidden from anonymous = %v, want %v", hidden, tt.hidden)
+			}
+		})
+	}
+}
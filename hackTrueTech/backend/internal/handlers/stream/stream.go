@@ -0,0 +1,193 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/corsSkip"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/httpResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	StatusInternalServerError = "Internal server error"
+)
+
+// heartbeatInterval -- is how often a comment is sent to idle clients, so proxies don't close their connections.
+const heartbeatInterval = 15 * time.Second
+
+// clientBuffer -- is the number of domain events a client may fall behind by, it misses the ones past it.
+const clientBuffer = 64
+
+// message -- is a domain event sent to clients.
+type message struct {
+	subject string
+	key     string
+	payload []byte
+	event   *storage.Event
+}
+
+// Hub -- fans domain events out to every client streaming them from this instance.
+type Hub struct {
+	mu      sync.Mutex
+	clients map[chan *message]struct{}
+	// closed -- is closed once the hub is, see Close.
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: make(map[chan *message]struct{}), closed: make(chan struct{})}
+}
+
+// Close -- ends every stream, e.g. once the server shuts down, as streams don't end on their own.
+func (h *Hub) Close() {
+	h.closeOnce.Do(func() { close(h.closed) })
+}
+
+// Publish -- sends the domain event with the idempotency key to every client. Clients which don't keep up miss it
+// instead of holding the others back.
+func (h *Hub) Publish(ctx context.Context, subject, key string, payload []byte) error {
+	const op = "handlers.stream.Publish"
+
+	// Data of a message ends at a line break, so the payload is sent on a single line.
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, payload); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	msg := &message{subject: subject, key: key, payload: compacted.Bytes()}
+	if subject == storage.SubjectEventCreated || subject == storage.SubjectEventUpdated {
+		msg.event = &storage.Event{}
+		if err := json.Unmarshal(payload, msg.event); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients {
+		select {
+		case client <- msg:
+		default:
+			slog.Warn("stream client falls behind, domain event is dropped", slogResponse.SlogOp(op),
+				slog.String("key", key))
+		}
+	}
+	return nil
+}
+
+// subscribe -- returns the channel domain events are sent to and the func closing it.
+func (h *Hub) subscribe() (<-chan *message, func()) {
+	client := make(chan *message, clientBuffer)
+
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+
+	return client, func() {
+		h.mu.Lock()
+		delete(h.clients, client)
+		h.mu.Unlock()
+	}
+}
+
+type StreamHandler struct {
+	Hub *Hub
+}
+
+// Events -- streams domain events as Server-Sent Events, GET /events/stream[?feature=<feature>...]. Every message
+// has the idempotency key of the domain event as its id, the subject as its event type, e.g. events.updated, and
+// its payload as data. Created and updated events are sent only if the requester may see them and, if features
+// are given, only if the event has every one of them. Deleted events are sent as {"id": <id>} to everyone.
+func (s *StreamHandler) Events(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.stream.Events"
+	corsSkip.EnableCors(w, r)
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the write timeout of the server.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Error("couldn't clear write deadline", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	username, isAdmin := viewer(r)
+	features := r.URL.Query()["feature"]
+
+	messages, unsubscribe := s.Hub.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if !send(rc, w, op, ": connected\n\n") {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case msg := <-messages:
+			if msg.event != nil && (!msg.event.VisibleTo(username, isAdmin) || !hasFeatures(msg.event, features)) {
+				continue
+			}
+			if !send(rc, w, op, fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", msg.key, msg.subject, msg.payload)) {
+				return
+			}
+		case <-heartbeat.C:
+			if !send(rc, w, op, ": heartbeat\n\n") {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.Hub.closed:
+			return
+		}
+	}
+}
+
+// send -- writes the chunk of the stream and flushes it to the client, reports whether it succeeded.
+func send(rc *http.ResponseController, w http.ResponseWriter, op, chunk string) bool {
+	if _, err := w.Write([]byte(chunk)); err != nil {
+		slog.Info("stream client is gone", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		return false
+	}
+	if err := rc.Flush(); err != nil {
+		slog.Error("couldn't flush stream", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		return false
+	}
+	return true
+}
+
+// hasFeatures -- reports whether the event has every one of the features.
+func hasFeatures(event *storage.Event, features []string) bool {
+	for _, feature := range features {
+		if !slices.Contains(event.Feature, feature) {
+			return false
+		}
+	}
+	return true
+}
+
+// viewer -- returns the username and the admin flag of the requester, empty if the request is anonymous.
+func viewer(r *http.Request) (string, bool) {
+	username, err := auth.Username(r)
+	if err != nil {
+		return "", false
+	}
+	isAdmin, _ := auth.IsAdmin(r)
+	return username, isAdmin
+}
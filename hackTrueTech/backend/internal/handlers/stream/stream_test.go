@@ -0,0 +1,149 @@
+package stream_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/handlers/stream"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const admin = "idkidkidk"
+
+// session -- logs the admin in and returns the access cookie.
+func session(t *testing.T) *http.Cookie {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	srv := auth.Auth{Db: memory.New()}
+	srv.LogIn(w, httptest.NewRequest(http.MethodPost, "/login",
+		strings.NewReader(`{"username": "`+admin+`", "password": "`+admin+`"}`)))
+	for _, cookie := range w.Result().Cookies() {
+		return cookie
+	}
+	t.Fatalf("admin couldn't log in: %d", w.Code)
+	return nil
+}
+
+// sse -- is a message of the stream.
+type sse struct {
+	id, event, data string
+}
+
+// open -- starts streaming with the query and the cookie, if any, and returns the stream once it's connected.
+func open(t *testing.T, srv *httptest.Server, query string, cookie *http.Cookie) *bufio.Reader {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events/stream"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	stream := bufio.NewReader(resp.Body)
+	if line, err := stream.ReadString('\n'); err != nil || line != ": connected\n" {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+	_, _ = stream.ReadString('\n')
+	return stream
+}
+
+// next -- reads the next message of the stream.
+func next(t *testing.T, stream *bufio.Reader) sse {
+	t.Helper()
+
+	var msg sse
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return msg
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			msg.id = value
+		case "event":
+			msg.event = value
+		case "data":
+			msg.data = value
+		}
+	}
+}
+
+func publish(t *testing.T, hub *stream.Hub, subject, key string, v any) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = hub.Publish(context.Background(), subject, key, data); err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestEvents(t *testing.T) {
+	t.Setenv("auth_key", "test")
+	hub := stream.NewHub()
+	handler := stream.StreamHandler{Hub: hub}
+	srv := httptest.NewServer(http.HandlerFunc(handler.Events))
+	t.Cleanup(srv.Close)
+
+	anonymous := open(t, srv, "?feature=deaf", nil)
+	admins := open(t, srv, "", session(t))
+
+	draft := publish(t, hub, storage.SubjectEventCreated, "outbox-1",
+		storage.Event{Id: 1, Feature: []string{"deaf"}, Status: storage.StatusDraft, Owner: "organizer"})
+	publish(t, hub, storage.SubjectEventCreated, "outbox-2",
+		storage.Event{Id: 2, Feature: []string{"blind"}, Status: storage.StatusPublished})
+	published := publish(t, hub, storage.SubjectEventUpdated, "outbox-3",
+		storage.Event{Id: 3, Feature: []string{"blind", "deaf"}, Status: storage.StatusPublished})
+	deleted := publish(t, hub, storage.SubjectEventDeleted, "outbox-4", storage.EventDeletion{Id: 1})
+
+	for _, want := range []sse{
+		{id: "outbox-3", event: storage.SubjectEventUpdated, data: published},
+		{id: "outbox-4", event: storage.SubjectEventDeleted, data: deleted},
+	} {
+		if got := next(t, anonymous); got != want {
+			t.Errorf("anonymous got %+v, want %+v", got, want)
+		}
+	}
+
+	if got := next(t, admins); got.id != "outbox-1" || got.data != draft {
+		t.Errorf("admin got %+v, want the draft", got)
+	}
+	for _, want := range []string{"outbox-2", "outbox-3", "outbox-4"} {
+		if got := next(t, admins); got.id != want {
+			t.Errorf("admin got %+v, want %s", got, want)
+		}
+	}
+
+	hub.Close()
+	if line, err := anonymous.ReadString('\n'); err != io.EOF {
+		t.Errorf("stream goes on after Close: %q, %v", line, err)
+	}
+}
@@ -0,0 +1,240 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/corsSkip"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/httpResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/webhooks"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type Storage interface {
+	CreateWebhook(ctx context.Context, webhook *storage.Webhook) (uint64, error)
+	GetWebhooks(ctx context.Context) ([]storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uint64) error
+	GetDeliveries(ctx context.Context, webhookId uint64, limit int) ([]storage.Delivery, error)
+}
+
+type WebhookHandler struct {
+	Db Storage
+}
+
+const (
+	StatusNotEnoughPermissions = "Not enough permissions"
+	StatusUnauthorized         = "Unauthorized"
+	StatusBadRequest           = "Bad request"
+	StatusNotFound             = "Not found"
+	StatusInternalServerError  = "Internal server error"
+	StatusDeleted              = "Webhook deleted"
+)
+
+// Limits of the delivery log sent.
+const (
+	defaultDeliveries = 50
+	maxDeliveries     = 500
+)
+
+// subjects -- are the domain events a webhook can be subscribed to.
+var subjects = []string{storage.SubjectEventCreated, storage.SubjectEventUpdated, storage.SubjectEventDeleted}
+
+type registration struct {
+	URL      string   `json:"url"`
+	Subjects []string `json:"subjects"`
+}
+
+// Create -- registers the webhook domain events are delivered to, POST /webhooks, admins only. The body is
+// {"url": "https://...", "subjects": ["events.created", ...]}, every domain event is delivered if subjects are
+// omitted. The webhook is sent back with the secret deliveries are signed with, it isn't shown again.
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.webhook.Create"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	username, ok := admin(w, r, op)
+	if !ok {
+		return
+	}
+
+	var body registration
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		slog.Error("couldn't decode webhook", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+	if target, err := url.Parse(body.URL); err != nil || (target.Scheme != "https" && target.Scheme != "http") ||
+		target.Host == "" {
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+	for _, subject := range body.Subjects {
+		if !slices.Contains(subjects, subject) {
+			httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+			return
+		}
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		slog.Error("couldn't generate secret", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	webhook := storage.Webhook{URL: body.URL, Secret: secret, Subjects: body.Subjects, Owner: username}
+	if webhook.Subjects == nil {
+		webhook.Subjects = []string{}
+	}
+	if webhook.Id, err = h.Db.CreateWebhook(ctx, &webhook); err != nil {
+		slog.Error("couldn't create webhook", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+	webhook.CreatedAt = time.Now().UTC()
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, op, webhook)
+}
+
+// List -- sends every webhook without its secret, GET /webhooks, admins only.
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.webhook.List"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	if _, ok := admin(w, r, op); !ok {
+		return
+	}
+
+	list, err := h.Db.GetWebhooks(ctx)
+	if err != nil {
+		slog.Error("couldn't get webhooks", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+	for i := range list {
+		list[i].Secret = ""
+	}
+
+	writeJSON(w, op, list)
+}
+
+// Delete -- deletes the webhook with its delivery log, DELETE /webhooks/{id}, admins only. Pending deliveries
+// aren't attempted anymore.
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.webhook.Delete"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	if _, ok := admin(w, r, op); !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+
+	switch err = h.Db.DeleteWebhook(ctx, id); {
+	case errors.Is(err, storage.ErrNotFound):
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+	case err != nil:
+		slog.Error("couldn't delete webhook", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+	default:
+		httpResponse.Write(w, http.StatusOK, StatusDeleted)
+	}
+}
+
+// Deliveries -- sends the delivery log of the webhook, the latest deliveries first, with the outcome of the last
+// attempt of each one, GET /webhooks/{id}/deliveries[?limit=<limit>], admins only. 50 deliveries are sent
+// by default, 500 at most.
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.webhook.Deliveries"
+	corsSkip.EnableCors(w, r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	if _, ok := admin(w, r, op); !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		slog.Error("couldn't parse id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+		return
+	}
+	limit := defaultDeliveries
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			httpResponse.Write(w, http.StatusBadRequest, StatusBadRequest)
+			return
+		}
+		limit = min(limit, maxDeliveries)
+	}
+
+	deliveries, err := h.Db.GetDeliveries(ctx, id, limit)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		httpResponse.Write(w, http.StatusNotFound, StatusNotFound)
+		return
+	case err != nil:
+		slog.Error("couldn't get deliveries", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, op, deliveries)
+}
+
+func admin(w http.ResponseWriter, r *http.Request, op string) (string, bool) {
+	if ok, err := auth.IsAdmin(r); !ok {
+		if err != nil {
+			slog.Error("not authorized", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+			httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+			return "", false
+		}
+		httpResponse.Write(w, http.StatusForbidden, StatusNotEnoughPermissions)
+		return "", false
+	}
+
+	username, err := auth.Username(r)
+	if err != nil {
+		httpResponse.Write(w, http.StatusUnauthorized, StatusUnauthorized)
+		return "", false
+	}
+	return username, true
+}
+
+func writeJSON(w http.ResponseWriter, op string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("couldn't marshal response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		httpResponse.Write(w, http.StatusInternalServerError, StatusInternalServerError)
+		return
+	}
+
+	if _, err = w.Write(data); err != nil {
+		slog.Error("couldn't write response", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+	}
+}
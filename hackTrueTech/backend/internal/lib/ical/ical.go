@@ -0,0 +1,106 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ContentType = "text/calendar; charset=utf-8"
+
+	prodId       = "-//hackBPA//Events//RU"
+	dateLayout   = "20060102T150405Z"
+	maxLineBytes = 75
+)
+
+// Event -- is a single VEVENT. UID must stay the same for every export of the same event, so that subscribed
+// calendars update the entry instead of duplicating it.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	// Sequence -- grows with every change of the event, so clients replace the entry they have with a newer one.
+	Sequence  uint64
+	Cancelled bool
+}
+
+// Write -- writes VCALENDAR with given events to w. Dates are written in UTC, so clients convert them to
+// the local time of the device themselves.
+func Write(w io.Writer, name string, events []Event) error {
+	const op = "lib.ical.Write"
+
+	buf := bufio.NewWriter(w)
+	now := time.Now().UTC().Format(dateLayout)
+
+	writeLine(buf, "BEGIN:VCALENDAR")
+	writeLine(buf, "VERSION:2.0")
+	writeLine(buf, "PRODID:"+prodId)
+	writeLine(buf, "CALSCALE:GREGORIAN")
+	writeLine(buf, "METHOD:PUBLISH")
+	if name != "" {
+		writeLine(buf, "X-WR-CALNAME:"+escape(name))
+	}
+
+	for i := range events {
+		event := &events[i]
+		writeLine(buf, "BEGIN:VEVENT")
+		writeLine(buf, "UID:"+escape(event.UID))
+		writeLine(buf, "DTSTAMP:"+now)
+		writeLine(buf, "DTSTART:"+event.Start.UTC().Format(dateLayout))
+		writeLine(buf, "SEQUENCE:"+strconv.FormatUint(event.Sequence, 10))
+		writeLine(buf, "SUMMARY:"+escape(event.Summary))
+		if event.Description != "" {
+			writeLine(buf, "DESCRIPTION:"+escape(event.Description))
+		}
+		if event.Location != "" {
+			writeLine(buf, "LOCATION:"+escape(event.Location))
+		}
+		if event.URL != "" {
+			writeLine(buf, "URL:"+event.URL)
+		}
+		if event.Cancelled {
+			writeLine(buf, "STATUS:CANCELLED")
+		} else {
+			writeLine(buf, "STATUS:CONFIRMED")
+		}
+		writeLine(buf, "END:VEVENT")
+	}
+
+	writeLine(buf, "END:VCALENDAR")
+
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func escape(text string) string {
+	return escaper.Replace(text)
+}
+
+// writeLine -- writes content line folded at 75 octets as RFC 5545 requires, never splitting a multibyte rune.
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineBytes
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineBytes - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
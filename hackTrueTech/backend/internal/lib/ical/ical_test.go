@@ -0,0 +1,166 @@
+package ical_test
+
+import (
+	"bytes"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/ical"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// write -- returns the calendar with the events as physical lines and as unfolded content lines.
+func write(t *testing.T, events ...ical.Event) (physical, content []string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, "Календарь", events); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.String()
+	if !strings.HasSuffix(data, "\r\n") {
+		t.Fatalf("calendar doesn't end with CRLF: %q", data)
+	}
+
+	physical = strings.Split(strings.TrimSuffix(data, "\r\n"), "\r\n")
+	content = strings.Split(strings.TrimSuffix(strings.ReplaceAll(data, "\r\n ", ""), "\r\n"), "\r\n")
+	return physical, content
+}
+
+// property -- returns the value of the first content line with the property name, reports whether there is one.
+func property(content []string, name string) (string, bool) {
+	for _, line := range content {
+		if value, ok := strings.CutPrefix(line, name+":"); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func TestWrite_Folding(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+	}{
+		{name: "short", summary: "Лекция"},
+		{name: "exactly 75 octets", summary: strings.Repeat("a", 75-len("SUMMARY:"))},
+		{name: "76 octets", summary: strings.Repeat("a", 76-len("SUMMARY:"))},
+		{name: "long ascii", summary: strings.Repeat("abcdefghij", 30)},
+		// Two-byte runes start at even octets after "SUMMARY:", so one straddles the 75th.
+		{name: "long cyrillic", summary: strings.Repeat("Экскурсия по Москве ", 12)},
+		{name: "four-byte runes", summary: "x" + strings.Repeat("🎭", 50)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			physical, content := write(t, ical.Event{UID: "event-1", Summary: tt.summary,
+				Start: time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)})
+
+			for i, line := range physical {
+				if len(line) > 75 {
+					t.Errorf("line %d is %d octets: %q", i, len(line), line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a rune: %q", i, line)
+				}
+			}
+
+			if got, _ := property(content, "SUMMARY"); got != tt.summary {
+				t.Errorf("unfolded SUMMARY = %q, want %q", got, tt.summary)
+			}
+			i := slices.IndexFunc(physical, func(line string) bool { return strings.HasPrefix(line, "SUMMARY:") })
+			folded := strings.HasPrefix(physical[i+1], " ")
+			if want := len("SUMMARY:"+tt.summary) > 75; folded != want {
+				t.Errorf("SUMMARY folded = %v, want %v: %q", folded, want, physical[i:])
+			}
+		})
+	}
+}
+
+func TestWrite_Escaping(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain", text: "Лекция о театре", want: "Лекция о театре"},
+		{name: "comma", text: "Москва, Красная площадь", want: `Москва\, Красная площадь`},
+		{name: "semicolon", text: "вход; выход", want: `вход\; выход`},
+		{name: "backslash", text: `C:\events`, want: `C:\\events`},
+		{name: "lf", text: "первая\nвторая", want: `первая\nвторая`},
+		{name: "crlf", text: "первая\r\nвторая", want: `первая\nвторая`},
+		{name: "cr", text: "первая\rвторая", want: `первая\nвторая`},
+		{name: "backslash before comma", text: `\,`, want: `\\\,`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, content := write(t, ical.Event{UID: "event-1", Summary: tt.text, Description: tt.text,
+				Location: tt.text, Start: time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)})
+
+			for _, name := range []string{"SUMMARY", "DESCRIPTION", "LOCATION"} {
+				if got, _ := property(content, name); got != tt.want {
+					t.Errorf("%s = %q, want %q", name, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWrite_Dates(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+
+	tests := []struct {
+		name      string
+		event     ical.Event
+		wantStart string
+	}{
+		{
+			name:      "utc",
+			event:     ical.Event{Start: time.Date(2024, time.May, 1, 10, 30, 0, 0, time.UTC)},
+			wantStart: "20240501T103000Z",
+		},
+		{
+			name:      "converted to utc",
+			event:     ical.Event{Start: time.Date(2024, time.May, 1, 10, 30, 15, 0, msk)},
+			wantStart: "20240501T073015Z",
+		},
+		{
+			name:      "previous day in utc",
+			event:     ical.Event{Start: time.Date(2024, time.January, 1, 1, 0, 0, 999, msk)},
+			wantStart: "20231231T220000Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.event.UID = "event-1"
+			_, content := write(t, tt.event)
+
+			if got, _ := property(content, "DTSTART"); got != tt.wantStart {
+				t.Errorf("DTSTART = %q, want %q", got, tt.wantStart)
+			}
+			if stamp, _ := property(content, "DTSTAMP"); !strings.HasSuffix(stamp, "Z") || len(stamp) != 16 {
+				t.Errorf("DTSTAMP = %q, want UTC date-time", stamp)
+			}
+		})
+	}
+}
+
+func TestWrite_Sequence(t *testing.T) {
+	_, content := write(t,
+		ical.Event{UID: "event-1", Start: time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)},
+		ical.Event{UID: "event-2", Start: time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC), Sequence: 3},
+	)
+
+	var got []string
+	for _, line := range content {
+		if value, ok := strings.CutPrefix(line, "SEQUENCE:"); ok {
+			got = append(got, value)
+		}
+	}
+	if want := []string{"0", "3"}; !slices.Equal(got, want) {
+		t.Errorf("SEQUENCE = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package readYourWrites
+
+import (
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"net/http"
+	"time"
+)
+
+// CookieName -- is the cookie marking a client which has recently changed data.
+const CookieName = "read_your_writes"
+
+// Middleware -- makes storage reads of a client see its own writes: requests which may change data and requests made
+// within window after them are served with storage.WithReadYourWrites context, so they don't hit lagging replicas.
+// The window is rounded up to whole seconds, cookies don't expire sooner; only the writes are marked if it's zero.
+func Middleware(window time.Duration) func(next http.Handler) http.Handler {
+	maxAge := int((window + time.Second - 1) / time.Second)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				if _, err := r.Cookie(CookieName); err != nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+			default:
+				if maxAge <= 0 {
+					break
+				}
+				http.SetCookie(w, &http.Cookie{
+					Name:     CookieName,
+					Value:    "1",
+					Path:     "/",
+					MaxAge:   maxAge,
+					HttpOnly: true,
+				})
+			}
+			next.ServeHTTP(w, r.WithContext(storage.WithReadYourWrites(r.Context())))
+		})
+	}
+}
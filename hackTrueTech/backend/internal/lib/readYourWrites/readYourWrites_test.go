@@ -0,0 +1,70 @@
+package readYourWrites_test
+
+import (
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/readYourWrites"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		method string
+		cookie bool
+		marked bool
+		// maxAge -- is Max-Age of the cookie set, 0 if none is.
+		maxAge int
+	}{
+		{name: "read", window: 5 * time.Second, method: http.MethodGet},
+		{name: "head", window: 5 * time.Second, method: http.MethodHead},
+		{name: "options", window: 5 * time.Second, method: http.MethodOptions},
+		{name: "read after write", window: 5 * time.Second, method: http.MethodGet, cookie: true, marked: true},
+		{name: "post", window: 5 * time.Second, method: http.MethodPost, marked: true, maxAge: 5},
+		{name: "patch", window: 5 * time.Second, method: http.MethodPatch, marked: true, maxAge: 5},
+		{name: "delete", window: 5 * time.Second, method: http.MethodDelete, marked: true, maxAge: 5},
+		{name: "write after write", window: 5 * time.Second, method: http.MethodPost, cookie: true, marked: true,
+			maxAge: 5},
+		{name: "window rounded up", window: 1500 * time.Millisecond, method: http.MethodPost, marked: true, maxAge: 2},
+		{name: "window under a second", window: time.Millisecond, method: http.MethodPost, marked: true, maxAge: 1},
+		{name: "no window", method: http.MethodPost, marked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var marked bool
+			handler := readYourWrites.Middleware(tt.window)(http.HandlerFunc(func(w http.ResponseWriter,
+				r *http.Request) {
+				marked = storage.ReadsYourWrites(r.Context())
+			}))
+
+			r := httptest.NewRequest(tt.method, "/events", nil)
+			if tt.cookie {
+				r.AddCookie(&http.Cookie{Name: readYourWrites.CookieName, Value: "1"})
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if marked != tt.marked {
+				t.Errorf("request read your writes = %v, want %v", marked, tt.marked)
+			}
+			var cookie *http.Cookie
+			for _, c := range w.Result().Cookies() {
+				if c.Name == readYourWrites.CookieName {
+					cookie = c
+				}
+			}
+			switch {
+			case tt.maxAge == 0 && cookie != nil:
+				t.Errorf("cookie %v is set, want none", cookie)
+			case tt.maxAge != 0 && cookie == nil:
+				t.Errorf("no cookie is set, want one for %ds", tt.maxAge)
+			case cookie != nil && (cookie.MaxAge != tt.maxAge || !cookie.HttpOnly || cookie.Path != "/"):
+				t.Errorf("cookie %v, want an HTTP-only one for the whole site for %ds", cookie, tt.maxAge)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FreqDaily  = "DAILY"
+	FreqWeekly = "WEEKLY"
+
+	// MaxOccurrences -- is the upper bound of occurrences a single rule can be expanded to.
+	MaxOccurrences = 366
+	// MaxInterval -- is the upper bound of INTERVAL, a rule repeating less often than yearly isn't a series.
+	MaxInterval = 365
+	// horizonDays -- is how many days past start a rule is expanded to, occurrences after it are dropped whatever
+	// UNTIL is, so a rule with a far UNTIL and every date excepted can't keep the expansion running.
+	horizonDays = 10 * 366
+
+	untilLayout     = "20060102T150405Z"
+	untilDateLayout = "20060102"
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported rule")
+	ErrUnbounded   = errors.New("rule must have either UNTIL or COUNT")
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Rule -- is the subset of RFC 5545 RRULE the service understands: FREQ=DAILY|WEEKLY with optional INTERVAL,
+// BYDAY (weekly only), UNTIL and COUNT.
+type Rule struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Until    time.Time
+	Count    int
+}
+
+// Parse -- parses RRULE value, e.g. "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20241231T000000Z". "RRULE:" prefix is optional.
+func Parse(rrule string) (*Rule, error) {
+	const op = "lib.recurrence.Parse"
+
+	rule := Rule{Interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(rrule, "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s: malformed part %q", op, part)
+		}
+
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+			if rule.Freq != FreqDaily && rule.Freq != FreqWeekly {
+				return nil, fmt.Errorf("%s: %w: FREQ=%s", op, ErrUnsupported, value)
+			}
+		case "INTERVAL":
+			if rule.Interval, err = strconv.Atoi(value); err != nil || rule.Interval < 1 || rule.Interval > MaxInterval {
+				return nil, fmt.Errorf("%s: invalid INTERVAL %q", op, value)
+			}
+		case "COUNT":
+			if rule.Count, err = strconv.Atoi(value); err != nil || rule.Count < 1 {
+				return nil, fmt.Errorf("%s: invalid COUNT %q", op, value)
+			}
+		case "UNTIL":
+			if rule.Until, err = time.Parse(untilLayout, value); err != nil {
+				if rule.Until, err = time.Parse(untilDateLayout, value); err != nil {
+					return nil, fmt.Errorf("%s: invalid UNTIL %q: %w", op, value, err)
+				}
+				rule.Until = rule.Until.Add(24*time.Hour - time.Second)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					return nil, fmt.Errorf("%s: invalid BYDAY %q", op, day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		default:
+			return nil, fmt.Errorf("%s: %w: %s", op, ErrUnsupported, key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%s: FREQ is required", op)
+	}
+	if rule.Until.IsZero() && rule.Count == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrUnbounded)
+	}
+	if len(rule.ByDay) != 0 && rule.Freq != FreqWeekly {
+		return nil, fmt.Errorf("%s: %w: BYDAY with FREQ=%s", op, ErrUnsupported, rule.Freq)
+	}
+
+	return &rule, nil
+}
+
+// Occurrences -- expands the rule starting at start. Every occurrence keeps start's wall clock time in start's
+// location, occurrences equal to one of exceptions are skipped. Start itself is the first occurrence if it matches
+// the rule. Occurrences are at most MaxOccurrences and at most ten years after start.
+func (r *Rule) Occurrences(start time.Time, exceptions []time.Time) []time.Time {
+	// The rule repeats every period of step days, offsets are the days of a period matching it counted from
+	// the period's first day. Weekly periods start on Monday, so the first one may start before start.
+	step, offsets := r.Interval, []int{0}
+	if r.Freq == FreqWeekly {
+		byDay := r.ByDay
+		if len(byDay) == 0 {
+			byDay = []time.Weekday{start.Weekday()}
+		}
+		monday := -int((start.Weekday() + 6) % 7)
+		step, offsets = 7*r.Interval, nil
+		for offset := monday; offset < monday+7; offset++ {
+			if slices.Contains(byDay, time.Weekday((int(start.Weekday())+offset+7)%7)) {
+				offsets = append(offsets, offset)
+			}
+		}
+	}
+
+	var occurrences = make([]time.Time, 0, 8)
+	emitted := 0
+	for period := 0; period <= horizonDays; period += step {
+		for _, offset := range offsets {
+			day := period + offset
+			if day < 0 {
+				continue
+			}
+			date := time.Date(start.Year(), start.Month(), start.Day()+day,
+				start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+			if day > horizonDays || !r.Until.IsZero() && date.After(r.Until) {
+				return occurrences
+			}
+
+			emitted++
+			if r.Count != 0 && emitted > r.Count {
+				return occurrences
+			}
+			if slices.ContainsFunc(exceptions, date.Equal) {
+				continue
+			}
+			if occurrences = append(occurrences, date); len(occurrences) == MaxOccurrences {
+				return occurrences
+			}
+		}
+	}
+
+	return occurrences
+}
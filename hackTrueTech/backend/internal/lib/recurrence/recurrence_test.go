@@ -0,0 +1,192 @@
+package recurrence_test
+
+import (
+	"errors"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/recurrence"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		rrule string
+		want  *recurrence.Rule
+		err   error
+	}{
+		{
+			name:  "weekly until",
+			rrule: "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20241231T000000Z",
+			want: &recurrence.Rule{Freq: recurrence.FreqWeekly, Interval: 1,
+				ByDay: []time.Weekday{time.Monday, time.Wednesday},
+				Until: time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:  "daily count with interval",
+			rrule: "freq=daily;interval=3;count=5",
+			want:  &recurrence.Rule{Freq: recurrence.FreqDaily, Interval: 3, Count: 5},
+		},
+		{
+			name:  "until date lasts the whole day",
+			rrule: "FREQ=DAILY;UNTIL=20241231",
+			want: &recurrence.Rule{Freq: recurrence.FreqDaily, Interval: 1,
+				Until: time.Date(2024, time.December, 31, 23, 59, 59, 0, time.UTC)},
+		},
+		{
+			name:  "largest interval",
+			rrule: "FREQ=DAILY;INTERVAL=365;COUNT=2",
+			want:  &recurrence.Rule{Freq: recurrence.FreqDaily, Interval: recurrence.MaxInterval, Count: 2},
+		},
+		{name: "interval too large", rrule: "FREQ=DAILY;INTERVAL=366;COUNT=2"},
+		{name: "zero interval", rrule: "FREQ=DAILY;INTERVAL=0;COUNT=2"},
+		{name: "interval not a number", rrule: "FREQ=DAILY;INTERVAL=1000000000000000000000;COUNT=2"},
+		{name: "zero count", rrule: "FREQ=DAILY;COUNT=0"},
+		{name: "malformed until", rrule: "FREQ=DAILY;UNTIL=tomorrow"},
+		{name: "unknown weekday", rrule: "FREQ=WEEKLY;BYDAY=XX;COUNT=2"},
+		{name: "malformed part", rrule: "FREQ=DAILY;COUNT"},
+		{name: "no freq", rrule: "COUNT=2"},
+		{name: "monthly", rrule: "FREQ=MONTHLY;COUNT=2", err: recurrence.ErrUnsupported},
+		{name: "unknown part", rrule: "FREQ=DAILY;BYMONTH=1;COUNT=2", err: recurrence.ErrUnsupported},
+		{name: "byday with daily", rrule: "FREQ=DAILY;BYDAY=MO;COUNT=2", err: recurrence.ErrUnsupported},
+		{name: "unbounded", rrule: "FREQ=DAILY", err: recurrence.ErrUnbounded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := recurrence.Parse(tt.rrule)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %+v, want error", tt.rrule, rule)
+				}
+				if tt.err != nil && !errors.Is(err, tt.err) {
+					t.Fatalf("Parse(%q) = %v, want %v", tt.rrule, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rrule, err)
+			}
+			if !reflect.DeepEqual(rule, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.rrule, rule, tt.want)
+			}
+		})
+	}
+}
+
+// date -- returns 10:30 of the day in Moscow, the time series in tests start at.
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 10, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+}
+
+func TestRule_Occurrences(t *testing.T) {
+	// Wednesday.
+	start := date(2024, time.May, 1)
+
+	tests := []struct {
+		name       string
+		rrule      string
+		exceptions []time.Time
+		want       []time.Time
+		// count -- is checked instead of want if want is nil, last is the last occurrence then.
+		count int
+		last  time.Time
+	}{
+		{
+			name:  "daily count",
+			rrule: "FREQ=DAILY;COUNT=3",
+			want:  []time.Time{start, date(2024, time.May, 2), date(2024, time.May, 3)},
+		},
+		{
+			name:  "daily interval until",
+			rrule: "FREQ=DAILY;INTERVAL=10;UNTIL=20240521T073000Z",
+			want:  []time.Time{start, date(2024, time.May, 11), date(2024, time.May, 21)},
+		},
+		{
+			name:  "until before occurrence time",
+			rrule: "FREQ=DAILY;INTERVAL=10;UNTIL=20240521T072959Z",
+			want:  []time.Time{start, date(2024, time.May, 11)},
+		},
+		{
+			name:  "weekly on start weekday",
+			rrule: "FREQ=WEEKLY;COUNT=3",
+			want:  []time.Time{start, date(2024, time.May, 8), date(2024, time.May, 15)},
+		},
+		{
+			name:  "weekly byday skips days before start",
+			rrule: "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=4",
+			want: []time.Time{start, date(2024, time.May, 3), date(2024, time.May, 6),
+				date(2024, time.May, 8)},
+		},
+		{
+			name:  "biweekly byday",
+			rrule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=4",
+			want: []time.Time{date(2024, time.May, 2), date(2024, time.May, 13), date(2024, time.May, 16),
+				date(2024, time.May, 27)},
+		},
+		{
+			name:       "exceptions count towards count",
+			rrule:      "FREQ=DAILY;COUNT=3",
+			exceptions: []time.Time{date(2024, time.May, 2)},
+			want:       []time.Time{start, date(2024, time.May, 3)},
+		},
+		{
+			name:  "weekly on sunday after start",
+			rrule: "FREQ=WEEKLY;BYDAY=SU;COUNT=2",
+			want:  []time.Time{date(2024, time.May, 5), date(2024, time.May, 12)},
+		},
+		{
+			name:  "capped by max occurrences",
+			rrule: "FREQ=DAILY;UNTIL=20991231T000000Z",
+			count: recurrence.MaxOccurrences,
+			last:  date(2025, time.May, 1),
+		},
+		{
+			name:  "largest interval capped by horizon",
+			rrule: "FREQ=WEEKLY;INTERVAL=365;UNTIL=99991231T000000Z",
+			count: 2,
+			last:  date(2031, time.April, 30),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := recurrence.Parse(tt.rrule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rrule, err)
+			}
+
+			got := rule.Occurrences(start, tt.exceptions)
+			if tt.want == nil {
+				if len(got) != tt.count || !got[len(got)-1].Equal(tt.last) {
+					t.Fatalf("%d occurrences till %v, want %d till %v", len(got), got[len(got)-1], tt.count, tt.last)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Occurrences = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) || got[i].Location() != start.Location() {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRule_Occurrences_AllExcepted(t *testing.T) {
+	rule, err := recurrence.Parse("FREQ=DAILY;INTERVAL=365;UNTIL=99991231T000000Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := date(2024, time.May, 1)
+	var exceptions []time.Time
+	for day := 0; day <= 10*366; day += recurrence.MaxInterval {
+		exceptions = append(exceptions, start.AddDate(0, 0, day))
+	}
+
+	if got := rule.Occurrences(start, exceptions); len(got) != 0 {
+		t.Errorf("Occurrences = %v, want none", got)
+	}
+}
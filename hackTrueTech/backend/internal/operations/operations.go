@@ -0,0 +1,93 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"time"
+)
+
+// Kinds of operations.
+const (
+	KindPatchEvent  = "patch_event"
+	KindDeleteEvent = "delete_event"
+)
+
+// Statuses of an operation.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Retention -- is the time an operation is kept after it's started.
+const Retention = time.Hour
+
+// Operation -- is a change of the event requested in async mode, see storage.Operation.
+type Operation = storage.Operation
+
+// Storage -- keeps operations, so any instance of the API finds an operation whichever one started it.
+type Storage interface {
+	CreateOperation(ctx context.Context, operation *storage.Operation) error
+	FinishOperation(ctx context.Context, operation *storage.Operation) error
+	GetOperation(ctx context.Context, id string) (*storage.Operation, error)
+}
+
+// Operations -- keeps operations in the storage until they expire, expired ones are purged by workers.
+type Operations struct {
+	db Storage
+}
+
+// New -- creates new instance of Operations with Storage interface.
+func New(db Storage) *Operations {
+	return &Operations{db: db}
+}
+
+// Start -- saves new pending operation on the event made by actor and returns it.
+func (o *Operations) Start(ctx context.Context, kind string, eventId uint64, actor string) (Operation, error) {
+	const op = "operations.Start"
+
+	operation := Operation{
+		Id:        uuid.NewString(),
+		Kind:      kind,
+		EventId:   eventId,
+		Actor:     actor,
+		Status:    StatusPending,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err := o.db.CreateOperation(ctx, &operation); err != nil {
+		return Operation{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return operation, nil
+}
+
+// Finish -- saves the outcome of the operation, it has failed if code isn't 2xx.
+func (o *Operations) Finish(ctx context.Context, id string, code int, message string, result []byte) error {
+	const op = "operations.Finish"
+
+	status := StatusSucceeded
+	if code < 200 || code > 299 {
+		status = StatusFailed
+	}
+	finishedAt := time.Now().UTC().Truncate(time.Microsecond)
+	if err := o.db.FinishOperation(ctx, &Operation{Id: id, Status: status, Code: code, Message: message,
+		Result: result, FinishedAt: &finishedAt}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Get -- returns the operation. storage.ErrNotFound is returned if there is no such operation or it has expired.
+func (o *Operations) Get(ctx context.Context, id string) (*Operation, error) {
+	const op = "operations.Get"
+
+	operation, err := o.db.GetOperation(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if operation.CreatedAt.Before(time.Now().Add(-Retention)) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return operation, nil
+}
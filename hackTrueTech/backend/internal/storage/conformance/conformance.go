@@ -0,0 +1,848 @@
+// Package conformance is the test suite every storage backend runs, so they keep the same semantics.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/broker/nats"
+	"github.com/wlcmtunknwndth/hackBPA/internal/cacher"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Storage -- is the part of a backend the suite checks.
+type Storage interface {
+	auth.Storage
+	nats.Storage
+	cacher.Storage
+	Book(ctx context.Context, eventId uint64, username string) error
+	CheckIn(ctx context.Context, eventId uint64, username string) error
+	AddFavorite(ctx context.Context, eventId uint64, username string) error
+	ArchivePastEvents(ctx context.Context, before time.Time) (int64, error)
+	GetEventHistory(ctx context.Context, id uint64) ([]storage.HistoryRecord, error)
+	RestoreEvent(ctx context.Context, id, version uint64) (uint64, error)
+	CreateWebhook(ctx context.Context, webhook *storage.Webhook) (uint64, error)
+	GetWebhooks(ctx context.Context) ([]storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uint64) error
+	EnqueueDeliveries(ctx context.Context, subject, key string, payload []byte) (int, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storage.Delivery, error)
+	RecordAttempt(ctx context.Context, id uint64, attempt *storage.Attempt) error
+	GetDeliveries(ctx context.Context, webhookId uint64, limit int) ([]storage.Delivery, error)
+	CreateOperation(ctx context.Context, operation *storage.Operation) error
+	FinishOperation(ctx context.Context, operation *storage.Operation) error
+	GetOperation(ctx context.Context, id string) (*storage.Operation, error)
+	PurgeOperations(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Run -- runs the suite, open must return an empty migrated storage on every call.
+func Run(t *testing.T, open func(t *testing.T) Storage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s Storage)
+	}{
+		{name: "auth", test: testAuth},
+		{name: "create and get event", test: testCreateAndGetEvent},
+		{name: "get missing event", test: testGetMissingEvent},
+		{name: "filter by features", test: testFilterByFeatures},
+		{name: "filter by status", test: testFilterByStatus},
+		{name: "sort events", test: testSortEvents},
+		{name: "patch event", test: testPatchEvent},
+		{name: "patch conflict", test: testPatchConflict},
+		{name: "delete event", test: testDeleteEvent},
+		{name: "set event status", test: testSetEventStatus},
+		{name: "archive past events", test: testArchivePastEvents},
+		{name: "series", test: testSeries},
+		{name: "bookers", test: testBookers},
+		{name: "cache", test: testCache},
+		{name: "outbox", test: testOutbox},
+		{name: "history", test: testHistory},
+		{name: "restore", test: testRestore},
+		{name: "webhooks", test: testWebhooks},
+		{name: "operations", test: testOperations},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, open(t))
+		})
+	}
+}
+
+// date -- is a future date with a precision every backend keeps.
+var date = time.Date(2030, time.June, 12, 19, 30, 0, 0, time.UTC)
+
+func newEvent(name string, features ...string) *storage.Event {
+	return &storage.Event{
+		Price:        1500,
+		Restrictions: 12,
+		Date:         date,
+		Feature:      features,
+		City:         "moscow",
+		Address:      "Malaya Ordinka, 3",
+		Name:         name,
+		ImgPath:      "/data/events/1",
+		Description:  "description of " + name,
+	}
+}
+
+func mustCreate(t *testing.T, s Storage, event *storage.Event) uint64 {
+	t.Helper()
+
+	id, err := s.CreateEvent(context.Background(), event)
+	if err != nil {
+		t.Fatalf("CreateEvent(%q): %v", event.Name, err)
+	}
+	if id == 0 {
+		t.Fatalf("CreateEvent(%q) returned zero id", event.Name)
+	}
+	return id
+}
+
+func mustGet(t *testing.T, s Storage, id uint64) *storage.Event {
+	t.Helper()
+
+	event, err := s.GetEvent(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetEvent(%d): %v", id, err)
+	}
+	return event
+}
+
+func names(events []storage.Event) []string {
+	var res = make([]string, 0, len(events))
+	for i := range events {
+		res = append(res, events[i].Name)
+	}
+	return res
+}
+
+func listed(t *testing.T, s Storage, filter storage.Filter) []string {
+	t.Helper()
+
+	events, err := s.GetEventsByFeature(context.Background(), &filter)
+	if err != nil {
+		t.Fatalf("GetEventsByFeature(%+v): %v", filter, err)
+	}
+	res := names(events)
+	if filter.SortBy == "" {
+		slices.Sort(res)
+	}
+	return res
+}
+
+func testAuth(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	user := auth.User{Username: "conformance", Password: "hash", Age: "30"}
+	if err := s.RegisterUser(ctx, &user); err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+
+	password, err := s.GetPassword(ctx, user.Username)
+	if err != nil || password != user.Password {
+		t.Fatalf("GetPassword = %q, %v; want %q", password, err, user.Password)
+	}
+	if admin, err := s.IsAdmin(ctx, user.Username); err != nil || admin {
+		t.Fatalf("IsAdmin = %v, %v; want false", admin, err)
+	}
+
+	if err = s.DeleteUser(ctx, user.Username); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err = s.GetPassword(ctx, user.Username); err == nil {
+		t.Fatal("GetPassword of deleted user succeeded")
+	}
+}
+
+func testCreateAndGetEvent(t *testing.T, s Storage) {
+	created := newEvent("Mayhem", "neuro", "deaf", "unknown")
+	created.Owner = "owner"
+	id := mustCreate(t, s, created)
+
+	got := mustGet(t, s, id)
+	if got.Id != id {
+		t.Errorf("Id = %d, want %d", got.Id, id)
+	}
+	if got.Price != created.Price || got.Restrictions != created.Restrictions {
+		t.Errorf("Price, Restrictions = %d, %d; want %d, %d",
+			got.Price, got.Restrictions, created.Price, created.Restrictions)
+	}
+	if !got.Date.Equal(created.Date) {
+		t.Errorf("Date = %v, want %v", got.Date, created.Date)
+	}
+	if got.City != created.City || got.Address != created.Address || got.Name != created.Name ||
+		got.ImgPath != created.ImgPath || got.Description != created.Description {
+		t.Errorf("got %+v, want %+v", got, created)
+	}
+	if !slices.Equal(got.Feature, []string{"deaf", "neuro"}) {
+		t.Errorf("Feature = %v, want known features sorted", got.Feature)
+	}
+	if got.Status != storage.StatusPublished {
+		t.Errorf("Status = %q, want %q by default", got.Status, storage.StatusPublished)
+	}
+	if got.Owner != created.Owner {
+		t.Errorf("Owner = %q, want %q", got.Owner, created.Owner)
+	}
+	if got.SeriesId != 0 || got.Rating != 0 || got.ReviewsCount != 0 || len(got.Ratings) != 0 {
+		t.Errorf("SeriesId, Rating, ReviewsCount, Ratings = %d, %v, %d, %v; want zero",
+			got.SeriesId, got.Rating, got.ReviewsCount, got.Ratings)
+	}
+}
+
+func testGetMissingEvent(t *testing.T, s Storage) {
+	if _, err := s.GetEvent(context.Background(), 4242); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetEvent of missing event = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func testFilterByFeatures(t *testing.T, s Storage) {
+	mustCreate(t, s, newEvent("deaf", "deaf"))
+	mustCreate(t, s, newEvent("blind and deaf", "deaf", "blind"))
+	mustCreate(t, s, newEvent("none"))
+	mustCreate(t, s, newEvent("all", "neuro", "disability", "deaf", "blind"))
+
+	tests := []struct {
+		features []string
+		want     []string
+	}{
+		{features: nil, want: []string{"all", "blind and deaf", "deaf", "none"}},
+		{features: []string{"deaf"}, want: []string{"deaf"}},
+		{features: []string{"deaf", "blind"}, want: []string{"blind and deaf"}},
+		{features: []string{"blind", "deaf"}, want: []string{"blind and deaf"}},
+		{features: []string{"unknown"}, want: []string{"all"}},
+		{features: []string{"neuro"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		if got := listed(t, s, storage.Filter{Features: tt.features}); !slices.Equal(got, tt.want) {
+			t.Errorf("features %v: listed %v, want %v", tt.features, got, tt.want)
+		}
+	}
+}
+
+func testFilterByStatus(t *testing.T, s Storage) {
+	mustCreate(t, s, newEvent("published"))
+	draft := newEvent("draft")
+	draft.Status = storage.StatusDraft
+	draft.Owner = "owner"
+	mustCreate(t, s, draft)
+
+	tests := []struct {
+		filter storage.Filter
+		want   []string
+	}{
+		{filter: storage.Filter{}, want: []string{"published"}},
+		{filter: storage.Filter{Statuses: []string{storage.StatusDraft}}, want: []string{}},
+		{filter: storage.Filter{Statuses: []string{storage.StatusDraft}, Viewer: "owner"}, want: []string{"draft"}},
+		{filter: storage.Filter{Statuses: []string{storage.StatusDraft, storage.StatusPublished}, ViewerIsAdmin: true},
+			want: []string{"draft", "published"}},
+	}
+	for _, tt := range tests {
+		if got := listed(t, s, tt.filter); !slices.Equal(got, tt.want) {
+			t.Errorf("filter %+v: listed %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func testSortEvents(t *testing.T, s Storage) {
+	for i, name := range []string{"cheap", "expensive", "middle"} {
+		event := newEvent(name, "deaf")
+		event.Price = []uint64{100, 900, 500}[i]
+		event.Date = date.AddDate(0, 0, []int{2, 0, 1}[i])
+		mustCreate(t, s, event)
+	}
+
+	tests := []struct {
+		filter storage.Filter
+		want   []string
+	}{
+		{filter: storage.Filter{SortBy: storage.SortByPrice}, want: []string{"cheap", "middle", "expensive"}},
+		{filter: storage.Filter{SortBy: storage.SortByPrice, Order: storage.OrderDescending},
+			want: []string{"expensive", "middle", "cheap"}},
+		{filter: storage.Filter{Features: []string{"deaf"}, SortBy: storage.SortByDate},
+			want: []string{"expensive", "middle", "cheap"}},
+	}
+	for _, tt := range tests {
+		if got := listed(t, s, tt.filter); !slices.Equal(got, tt.want) {
+			t.Errorf("filter %+v: listed %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func testPatchEvent(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id := mustCreate(t, s, newEvent("before", "deaf"))
+	if got := mustGet(t, s, id); got.Version != 1 {
+		t.Fatalf("Version of created event = %d, want 1", got.Version)
+	}
+
+	patch, err := storage.ParseEventPatch([]byte(`{"price": 2000, "date": "2030-06-12T20:30:00Z", "description": null}`))
+	if err != nil {
+		t.Fatalf("ParseEventPatch: %v", err)
+	}
+	patch.Id, patch.Version = id, 1
+	version, err := s.PatchEvent(ctx, patch)
+	if err != nil {
+		t.Fatalf("PatchEvent: %v", err)
+	}
+	if version != 2 {
+		t.Errorf("PatchEvent = %d, want version 2", version)
+	}
+
+	got := mustGet(t, s, id)
+	if got.Name != "before" || got.City != "moscow" || got.Price != 2000 || !got.Date.Equal(date.Add(time.Hour)) {
+		t.Errorf("patched event = %+v, patch must change the fields given only", got)
+	}
+	if got.Description != "" {
+		t.Errorf("Description = %q, null must reset it", got.Description)
+	}
+	if !slices.Equal(got.Feature, []string{"deaf"}) {
+		t.Errorf("Feature = %v, patch must keep features", got.Feature)
+	}
+	if got.Version != 2 {
+		t.Errorf("Version = %d, want 2", got.Version)
+	}
+
+	patch.Id = id + 100
+	if _, err = s.PatchEvent(ctx, patch); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("PatchEvent of missing event = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func testPatchConflict(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id := mustCreate(t, s, newEvent("contested"))
+
+	first, second := "first", "second"
+	if _, err := s.PatchEvent(ctx, &storage.EventPatch{Id: id, Version: 1, Name: &first}); err != nil {
+		t.Fatalf("PatchEvent: %v", err)
+	}
+	if _, err := s.PatchEvent(ctx, &storage.EventPatch{Id: id, Version: 1, Name: &second}); !errors.Is(err,
+		storage.ErrVersionConflict) {
+		t.Errorf("PatchEvent of stale version = %v, want storage.ErrVersionConflict", err)
+	}
+	if got := mustGet(t, s, id); got.Name != first {
+		t.Errorf("Name = %q, conflicting patch must not be applied", got.Name)
+	}
+
+	if err := s.SetEventStatus(ctx, id, storage.StatusCancelled); err != nil {
+		t.Fatalf("SetEventStatus: %v", err)
+	}
+	if got := mustGet(t, s, id); got.Version != 3 {
+		t.Errorf("Version after status change = %d, want 3", got.Version)
+	}
+
+	version, err := s.PatchEvent(ctx, &storage.EventPatch{Id: id, Name: &second})
+	if err != nil || version != 4 {
+		t.Errorf("PatchEvent of any version = %d, %v; want 4", version, err)
+	}
+}
+
+func testDeleteEvent(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id := mustCreate(t, s, newEvent("deleted", "deaf"))
+	if err := s.Book(ctx, id, "booker"); err != nil {
+		t.Fatalf("Book: %v", err)
+	}
+
+	if err := s.DeleteEvent(ctx, id); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+	if _, err := s.GetEvent(ctx, id); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetEvent of deleted event = %v, want storage.ErrNotFound", err)
+	}
+	if got := listed(t, s, storage.Filter{Features: []string{"deaf"}}); len(got) != 0 {
+		t.Errorf("deleted event is still listed: %v", got)
+	}
+	if err := s.DeleteEvent(ctx, id); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("DeleteEvent of deleted event = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func testSetEventStatus(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id := mustCreate(t, s, newEvent("cancelled"))
+
+	if err := s.SetEventStatus(ctx, id, storage.StatusCancelled); err != nil {
+		t.Fatalf("SetEventStatus(cancelled): %v", err)
+	}
+	if got := mustGet(t, s, id).Status; got != storage.StatusCancelled {
+		t.Errorf("Status = %q, want %q", got, storage.StatusCancelled)
+	}
+
+	if err := s.SetEventStatus(ctx, id, storage.StatusPublished); !errors.Is(err, storage.ErrInvalidTransition) {
+		t.Errorf("SetEventStatus(cancelled -> published) = %v, want storage.ErrInvalidTransition", err)
+	}
+	if err := s.SetEventStatus(ctx, id+100, storage.StatusCancelled); !errors.Is(err, storage.ErrInvalidTransition) {
+		t.Errorf("SetEventStatus of missing event = %v, want storage.ErrInvalidTransition", err)
+	}
+}
+
+func testArchivePastEvents(t *testing.T, s Storage) {
+	past := newEvent("past")
+	past.Date = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	pastId := mustCreate(t, s, past)
+	futureId := mustCreate(t, s, newEvent("future"))
+
+	archived, err := s.ArchivePastEvents(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("ArchivePastEvents: %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("archived %d events, want 1", archived)
+	}
+	if got := mustGet(t, s, pastId).Status; got != storage.StatusArchived {
+		t.Errorf("past event Status = %q, want %q", got, storage.StatusArchived)
+	}
+	if got := mustGet(t, s, futureId).Status; got != storage.StatusPublished {
+		t.Errorf("future event Status = %q, want %q", got, storage.StatusPublished)
+	}
+}
+
+func testSeries(t *testing.T, s Storage) {
+	ctx := context.Background()
+	series := storage.Series{
+		Recurrence: "FREQ=DAILY;COUNT=3",
+		Start:      date,
+		Exceptions: []time.Time{date.AddDate(0, 0, 1)},
+		Event:      *newEvent("series", "blind"),
+	}
+
+	seriesId, err := s.CreateSeries(ctx, &series)
+	if err != nil {
+		t.Fatalf("CreateSeries: %v", err)
+	}
+	if seriesId == 0 || len(series.Occurrences) != 2 {
+		t.Fatalf("CreateSeries = %d, occurrences %v; want 2 occurrences", seriesId, series.Occurrences)
+	}
+
+	name := "renamed series"
+	patch := &storage.EventPatch{Id: series.Occurrences[0], Version: 2, Name: &name}
+	if _, err = s.PatchSeries(ctx, patch); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Errorf("PatchSeries of stale version = %v, want storage.ErrVersionConflict", err)
+	}
+	// The second occurrence is edited alone, so the occurrences are at different versions.
+	address := "edited alone"
+	if _, err = s.PatchEvent(ctx, &storage.EventPatch{Id: series.Occurrences[1], Address: &address}); err != nil {
+		t.Fatalf("PatchEvent: %v", err)
+	}
+	patch.Version = 1
+	if version, err := s.PatchSeries(ctx, patch); err != nil || version != 2 {
+		t.Fatalf("PatchSeries = %d, %v; want version 2", version, err)
+	}
+	patch = &storage.EventPatch{Id: series.Occurrences[1], Version: 3, Name: &name}
+	if version, err := s.PatchSeries(ctx, patch); err != nil || version != 4 {
+		t.Fatalf("PatchSeries through the occurrence edited alone = %d, %v; want version 4", version, err)
+	}
+
+	wantDates := []time.Time{date, date.AddDate(0, 0, 2)}
+	wantVersions := []uint64{3, 4}
+	for i, id := range series.Occurrences {
+		got := mustGet(t, s, id)
+		if got.SeriesId != seriesId || got.Name != name || got.Price != 1500 || !got.Date.Equal(wantDates[i]) ||
+			got.Version != wantVersions[i] {
+			t.Errorf("occurrence %d = %+v", i, got)
+		}
+	}
+}
+
+func testBookers(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id := mustCreate(t, s, newEvent("booked"))
+
+	for _, username := range []string{"second", "first", "second"} {
+		if err := s.Book(ctx, id, username); err != nil {
+			t.Fatalf("Book(%q): %v", username, err)
+		}
+	}
+
+	bookers, err := s.GetBookers(ctx, id)
+	if err != nil {
+		t.Fatalf("GetBookers: %v", err)
+	}
+	slices.Sort(bookers)
+	if !slices.Equal(bookers, []string{"first", "second"}) {
+		t.Errorf("GetBookers = %v, want every booker once", bookers)
+	}
+
+	for name, book := range map[string]func(context.Context, uint64, string) error{
+		"Book": s.Book, "CheckIn": s.CheckIn, "AddFavorite": s.AddFavorite,
+	} {
+		if err = book(ctx, id+100, "first"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s of missing event = %v, want storage.ErrNotFound", name, err)
+		}
+	}
+}
+
+func testCache(t *testing.T, s Storage) {
+	if _, err := s.RestoreCache(); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("RestoreCache of empty cache = %v, want %v", err, storage.ErrNotFound)
+	}
+
+	id := mustCreate(t, s, newEvent("cached", "deaf"))
+	if s.IsAlreadyCached(uint(id)) {
+		t.Error("IsAlreadyCached before SaveCache = true")
+	}
+	if err := s.SaveCache(uint(id)); err != nil {
+		t.Fatalf("SaveCache: %v", err)
+	}
+	if !s.IsAlreadyCached(uint(id)) {
+		t.Error("IsAlreadyCached after SaveCache = false")
+	}
+
+	events, err := s.RestoreCache()
+	if err != nil {
+		t.Fatalf("RestoreCache: %v", err)
+	}
+	if len(events) != 1 || events[0].Id != id || !slices.Equal(events[0].Feature, []string{"deaf"}) {
+		t.Errorf("RestoreCache = %+v", events)
+	}
+
+	if err = s.DeleteCache(uint(id)); err != nil {
+		t.Fatalf("DeleteCache: %v", err)
+	}
+	if s.IsAlreadyCached(uint(id)) {
+		t.Error("IsAlreadyCached after DeleteCache = true")
+	}
+}
+
+func testOutbox(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	id := mustCreate(t, s, newEvent("outboxed"))
+	name := "patched"
+	if _, err := s.PatchEvent(ctx, &storage.EventPatch{Id: id, Name: &name}); err != nil {
+		t.Fatalf("PatchEvent: %v", err)
+	}
+	if err := s.DeleteEvent(ctx, id); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+
+	failed := errors.New("broker is down")
+	if _, err := s.PublishOutbox(ctx, 10, func([]storage.OutboxMessage) error { return failed }); err == nil {
+		t.Fatal("PublishOutbox succeeded although publish failed")
+	}
+
+	var published []storage.OutboxMessage
+	count, err := s.PublishOutbox(ctx, 10, func(messages []storage.OutboxMessage) error {
+		published = append(published, messages...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("PublishOutbox: %v", err)
+	}
+	if count != 3 || len(published) != 3 {
+		t.Fatalf("published %d messages, want 3 including ones failed before", count)
+	}
+
+	wantSubjects := []string{storage.SubjectEventCreated, storage.SubjectEventUpdated, storage.SubjectEventDeleted}
+	for i, message := range published {
+		if message.Subject != wantSubjects[i] {
+			t.Errorf("message %d subject = %q, want %q", i, message.Subject, wantSubjects[i])
+		}
+		if i > 0 && message.Key() == published[i-1].Key() {
+			t.Errorf("messages %d and %d share idempotency key %q", i-1, i, message.Key())
+		}
+	}
+
+	var updated storage.Event
+	if err = json.Unmarshal(published[1].Payload, &updated); err != nil || updated.Id != id || updated.Name != "patched" {
+		t.Errorf("updated payload = %s, %v", published[1].Payload, err)
+	}
+	var deleted storage.EventDeletion
+	if err = json.Unmarshal(published[2].Payload, &deleted); err != nil || deleted.Id != id {
+		t.Errorf("deleted payload = %s, %v", published[2].Payload, err)
+	}
+
+	count, err = s.PublishOutbox(ctx, 10, func([]storage.OutboxMessage) error {
+		t.Error("published messages are published again")
+		return nil
+	})
+	if err != nil || count != 0 {
+		t.Errorf("PublishOutbox of empty outbox = %d, %v", count, err)
+	}
+}
+
+func testHistory(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	id, err := s.CreateEvent(storage.WithActor(ctx, "owner"), newEvent("before", "deaf"))
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	name := "after"
+	if _, err = s.PatchEvent(storage.WithActor(ctx, "editor"), &storage.EventPatch{Id: id, Name: &name}); err != nil {
+		t.Fatalf("PatchEvent: %v", err)
+	}
+	if err = s.SetEventStatus(ctx, id, storage.StatusCancelled); err != nil {
+		t.Fatalf("SetEventStatus: %v", err)
+	}
+	if err = s.DeleteEvent(storage.WithActor(ctx, "admin"), id); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+
+	records, err := s.GetEventHistory(ctx, id)
+	if err != nil {
+		t.Fatalf("GetEventHistory: %v", err)
+	}
+	want := []storage.HistoryRecord{
+		{Version: 1, Operation: storage.OperationCreated, Actor: "owner"},
+		{Version: 2, Operation: storage.OperationUpdated, Actor: "editor"},
+		{Version: 3, Operation: storage.OperationStatusChanged},
+		{Version: 3, Operation: storage.OperationDeleted, Actor: "admin"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("GetEventHistory = %+v, want %d records", records, len(want))
+	}
+	for i, record := range records {
+		if record.EventId != id || record.Version != want[i].Version || record.Operation != want[i].Operation ||
+			record.Actor != want[i].Actor || record.CreatedAt.IsZero() {
+			t.Errorf("record %d = %+v, want %+v", i, record, want[i])
+		}
+	}
+	if got := records[0].Event; got.Name != "before" || !slices.Equal(got.Feature, []string{"deaf"}) ||
+		!got.Date.Equal(date) {
+		t.Errorf("created state = %+v", got)
+	}
+
+	entries, err := storage.History(records)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if changes := entries[1].Changes; len(changes) != 1 || changes[0].Field != "name" ||
+		string(changes[0].Before) != `"before"` || string(changes[0].After) != `"after"` {
+		t.Errorf("changes of the patch = %+v, want name only", changes)
+	}
+	if entries[0].Before != nil || entries[3].After != nil {
+		t.Errorf("creation = %+v, deletion = %+v; want no state before creation and after deletion",
+			entries[0], entries[3])
+	}
+
+	if records, err = s.GetEventHistory(ctx, id+100); err != nil || len(records) != 0 {
+		t.Errorf("GetEventHistory of missing event = %v, %v; want none", records, err)
+	}
+}
+
+func testRestore(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id := mustCreate(t, s, newEvent("original", "blind"))
+	name := "renamed"
+	if _, err := s.PatchEvent(ctx, &storage.EventPatch{Id: id, Name: &name}); err != nil {
+		t.Fatalf("PatchEvent: %v", err)
+	}
+
+	version, err := s.RestoreEvent(storage.WithActor(ctx, "admin"), id, 1)
+	if err != nil || version != 3 {
+		t.Fatalf("RestoreEvent of previous version = %d, %v; want 3", version, err)
+	}
+	if got := mustGet(t, s, id); got.Name != "original" || got.Version != 3 {
+		t.Errorf("restored event = %+v", got)
+	}
+
+	if err = s.DeleteEvent(ctx, id); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+	if version, err = s.RestoreEvent(ctx, id, 2); err != nil || version != 4 {
+		t.Fatalf("RestoreEvent of deleted event = %d, %v; want 4", version, err)
+	}
+	got := mustGet(t, s, id)
+	if got.Name != "renamed" || got.Version != 4 || !slices.Equal(got.Feature, []string{"blind"}) ||
+		!got.Date.Equal(date) || got.Status != storage.StatusPublished {
+		t.Errorf("recreated event = %+v", got)
+	}
+	if listed := listed(t, s, storage.Filter{Features: []string{"blind"}}); !slices.Equal(listed, []string{"renamed"}) {
+		t.Errorf("listed %v, recreated event must be listed", listed)
+	}
+
+	records, err := s.GetEventHistory(ctx, id)
+	if err != nil || len(records) == 0 || records[len(records)-1].Operation != storage.OperationRestored ||
+		records[len(records)-1].Actor != "" {
+		t.Errorf("GetEventHistory = %+v, %v; want restoration last", records, err)
+	}
+
+	if _, err = s.RestoreEvent(ctx, id, 42); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("RestoreEvent of missing version = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func testWebhooks(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	all, err := s.CreateWebhook(ctx, &storage.Webhook{URL: "https://tickets.example/hook", Secret: "secret",
+		Owner: "idkidkidk"})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	deletions, err := s.CreateWebhook(ctx, &storage.Webhook{URL: "https://live.example/hook", Secret: "another",
+		Subjects: []string{storage.SubjectEventDeleted}})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+
+	webhooks, err := s.GetWebhooks(ctx)
+	if err != nil || len(webhooks) != 2 {
+		t.Fatalf("GetWebhooks = %+v, %v; want both webhooks", webhooks, err)
+	}
+	if webhooks[0].Id != all || webhooks[0].Secret != "secret" || webhooks[0].Owner != "idkidkidk" ||
+		len(webhooks[0].Subjects) != 0 {
+		t.Errorf("webhook = %+v", webhooks[0])
+	}
+	if webhooks[1].Id != deletions || !slices.Equal(webhooks[1].Subjects, []string{storage.SubjectEventDeleted}) {
+		t.Errorf("webhook = %+v", webhooks[1])
+	}
+
+	payload := []byte(`{"id":1}`)
+	for _, enqueue := range []struct {
+		subject, key string
+		want         int
+	}{
+		{subject: storage.SubjectEventCreated, key: "outbox-1", want: 1},
+		{subject: storage.SubjectEventCreated, key: "outbox-1", want: 0},
+		{subject: storage.SubjectEventDeleted, key: "outbox-2", want: 2},
+	} {
+		created, err := s.EnqueueDeliveries(ctx, enqueue.subject, enqueue.key, payload)
+		if err != nil || created != enqueue.want {
+			t.Errorf("EnqueueDeliveries(%s, %s) = %d, %v; want %d", enqueue.subject, enqueue.key, created, err,
+				enqueue.want)
+		}
+	}
+
+	claimed, err := s.ClaimDeliveries(ctx, 10, time.Minute)
+	if err != nil || len(claimed) != 3 {
+		t.Fatalf("ClaimDeliveries = %+v, %v; want 3 deliveries", claimed, err)
+	}
+	for _, delivery := range claimed {
+		var decoded storage.EventDeletion
+		if err = json.Unmarshal(delivery.Payload, &decoded); err != nil || decoded.Id != 1 {
+			t.Errorf("payload = %s, %v", delivery.Payload, err)
+		}
+		if delivery.Status != storage.DeliveryPending || delivery.Attempts != 0 {
+			t.Errorf("claimed delivery = %+v", delivery)
+		}
+	}
+	if again, err := s.ClaimDeliveries(ctx, 10, time.Minute); err != nil || len(again) != 0 {
+		t.Errorf("ClaimDeliveries of leased deliveries = %+v, %v; want none", again, err)
+	}
+
+	first := claimed[0]
+	if err = s.RecordAttempt(ctx, first.Id, &storage.Attempt{Status: storage.DeliveryPending, ResponseCode: 503,
+		Error: "unavailable", NextAttemptAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatalf("RecordAttempt: %v", err)
+	}
+	retried, err := s.ClaimDeliveries(ctx, 10, time.Minute)
+	if err != nil || len(retried) != 1 || retried[0].Id != first.Id || retried[0].Attempts != 1 ||
+		retried[0].ResponseCode != 503 || retried[0].Error != "unavailable" {
+		t.Fatalf("ClaimDeliveries of the retried delivery = %+v, %v", retried, err)
+	}
+	if err = s.RecordAttempt(ctx, first.Id, &storage.Attempt{Status: storage.DeliverySucceeded,
+		ResponseCode: 200}); err != nil {
+		t.Fatalf("RecordAttempt: %v", err)
+	}
+	if err = s.RecordAttempt(ctx, first.Id+100, &storage.Attempt{Status: storage.DeliveryFailed}); !errors.Is(err,
+		storage.ErrNotFound) {
+		t.Errorf("RecordAttempt of missing delivery = %v, want ErrNotFound", err)
+	}
+
+	log, err := s.GetDeliveries(ctx, first.WebhookId, 10)
+	if err != nil || len(log) == 0 {
+		t.Fatalf("GetDeliveries = %+v, %v", log, err)
+	}
+	for i := 1; i < len(log); i++ {
+		if log[i].Id > log[i-1].Id {
+			t.Errorf("deliveries aren't ordered latest first: %+v", log)
+		}
+	}
+	i := slices.IndexFunc(log, func(delivery storage.Delivery) bool { return delivery.Id == first.Id })
+	if i == -1 || log[i].Status != storage.DeliverySucceeded || log[i].Attempts != 2 || log[i].ResponseCode != 200 ||
+		log[i].Error != "" {
+		t.Errorf("delivery log = %+v, want the succeeded delivery", log)
+	}
+	if limited, err := s.GetDeliveries(ctx, all, 1); err != nil || len(limited) != 1 {
+		t.Errorf("GetDeliveries limited to 1 = %+v, %v", limited, err)
+	}
+
+	if err = s.DeleteWebhook(ctx, deletions); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if err = s.DeleteWebhook(ctx, deletions); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("DeleteWebhook of deleted webhook = %v, want ErrNotFound", err)
+	}
+	if _, err = s.GetDeliveries(ctx, deletions, 10); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetDeliveries of deleted webhook = %v, want ErrNotFound", err)
+	}
+	if created, err := s.EnqueueDeliveries(ctx, storage.SubjectEventDeleted, "outbox-3", payload); err != nil ||
+		created != 1 {
+		t.Errorf("EnqueueDeliveries after deletion = %d, %v; want 1", created, err)
+	}
+}
+
+func testOperations(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	started := date.Add(-2 * time.Hour)
+	for _, operation := range []*storage.Operation{
+		{Id: "patch", Kind: "patch_event", EventId: 1, Actor: "idkidkidk", Status: "pending", CreatedAt: started},
+		{Id: "delete", Kind: "delete_event", EventId: 2, Status: "pending", CreatedAt: date},
+	} {
+		if err := s.CreateOperation(ctx, operation); err != nil {
+			t.Fatalf("CreateOperation(%s): %v", operation.Id, err)
+		}
+	}
+	if err := s.CreateOperation(ctx, &storage.Operation{Id: "patch", Kind: "patch_event", Status: "pending",
+		CreatedAt: date}); !errors.Is(err, storage.ErrAlreadyExists) {
+		t.Errorf("CreateOperation of existing operation = %v, want ErrAlreadyExists", err)
+	}
+
+	pending, err := s.GetOperation(ctx, "patch")
+	if err != nil {
+		t.Fatalf("GetOperation: %v", err)
+	}
+	if pending.Kind != "patch_event" || pending.EventId != 1 || pending.Actor != "idkidkidk" ||
+		pending.Status != "pending" || !pending.CreatedAt.Equal(started) || pending.FinishedAt != nil ||
+		pending.Result != nil {
+		t.Errorf("pending operation = %+v", pending)
+	}
+
+	finished := date.Add(time.Second)
+	result := json.RawMessage(`{"id":1,"name":"patched"}`)
+	if err = s.FinishOperation(ctx, &storage.Operation{Id: "patch", Status: "succeeded", Code: 200,
+		Message: "Event patched", Result: result, FinishedAt: &finished}); err != nil {
+		t.Fatalf("FinishOperation: %v", err)
+	}
+	if err = s.FinishOperation(ctx, &storage.Operation{Id: "delete", Status: "failed", Code: 404,
+		Message: "Not found", FinishedAt: &finished}); err != nil {
+		t.Fatalf("FinishOperation: %v", err)
+	}
+	if err = s.FinishOperation(ctx, &storage.Operation{Id: "missing", Status: "failed",
+		FinishedAt: &finished}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("FinishOperation of missing operation = %v, want ErrNotFound", err)
+	}
+
+	succeeded, err := s.GetOperation(ctx, "patch")
+	if err != nil {
+		t.Fatalf("GetOperation: %v", err)
+	}
+	var event storage.Event
+	if succeeded.Status != "succeeded" || succeeded.Code != 200 || succeeded.Message != "Event patched" ||
+		json.Unmarshal(succeeded.Result, &event) != nil || event.Name != "patched" || succeeded.FinishedAt == nil ||
+		!succeeded.FinishedAt.Equal(finished) || succeeded.Actor != "idkidkidk" || !succeeded.CreatedAt.Equal(started) {
+		t.Errorf("succeeded operation = %+v", succeeded)
+	}
+	failed, err := s.GetOperation(ctx, "delete")
+	if err != nil || failed.Status != "failed" || failed.Code != 404 || failed.Result != nil || failed.Actor != "" {
+		t.Errorf("failed operation = %+v, %v", failed, err)
+	}
+
+	if purged, err := s.PurgeOperations(ctx, date.Add(-time.Hour)); err != nil || purged != 1 {
+		t.Errorf("PurgeOperations = %d, %v; want 1", purged, err)
+	}
+	if _, err = s.GetOperation(ctx, "patch"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetOperation of purged operation = %v, want ErrNotFound", err)
+	}
+	if _, err = s.GetOperation(ctx, "delete"); err != nil {
+		t.Errorf("GetOperation of operation started later = %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+package storage
+
+import "context"
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites -- marks ctx so reads made with it observe every write committed before them, i.e. aren't
+// served by a lagging replica.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// ReadsYourWrites -- reports whether ctx is marked with WithReadYourWrites.
+func ReadsYourWrites(ctx context.Context) bool {
+	marked, _ := ctx.Value(readYourWritesKey{}).(bool)
+	return marked
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Operations recorded in the event history.
+const (
+	OperationCreated       = "created"
+	OperationUpdated       = "updated"
+	OperationStatusChanged = "status_changed"
+	OperationDeleted       = "deleted"
+	OperationRestored      = "restored"
+)
+
+// HistoryRecord -- is an append-only record of a change of the event. Event is the state of the event after
+// the change, or right before it for OperationDeleted. Actor is empty for changes made by the server itself,
+// e.g. archiving of past events.
+type HistoryRecord struct {
+	Id        uint64    `json:"id"`
+	EventId   uint64    `json:"event_id"`
+	Version   uint64    `json:"version"`
+	Operation string    `json:"operation"`
+	Actor     string    `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Event     Event     `json:"event"`
+}
+
+// HistoryEntry -- is a change of the event with its state before and after the change. Before is nil for
+// the creation, After is nil for the deletion.
+type HistoryEntry struct {
+	Id        uint64    `json:"id"`
+	EventId   uint64    `json:"event_id"`
+	Version   uint64    `json:"version"`
+	Operation string    `json:"operation"`
+	Actor     string    `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Before    *Event    `json:"before"`
+	After     *Event    `json:"after"`
+	Changes   []Change  `json:"changes"`
+}
+
+// Change -- is a field of the event changed, values are as they are in the event JSON.
+type Change struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// unversioned -- are the fields of the event JSON which aren't part of its history.
+var unversioned = []string{"id", "version", "rating", "ratings", "reviews_count"}
+
+// Snapshot -- returns the state of the event kept in its history, without the aggregates of its reviews.
+func Snapshot(event Event) Event {
+	event.Rating, event.Ratings, event.ReviewsCount = 0, nil, 0
+	return event
+}
+
+// History -- turns records of a single event ordered by id into entries with the changes each record made.
+func History(records []HistoryRecord) ([]HistoryEntry, error) {
+	var entries = make([]HistoryEntry, 0, len(records))
+	var previous *Event
+	for i := range records {
+		record := &records[i]
+		entry := HistoryEntry{
+			Id:        record.Id,
+			EventId:   record.EventId,
+			Version:   record.Version,
+			Operation: record.Operation,
+			Actor:     record.Actor,
+			CreatedAt: record.CreatedAt,
+			Before:    previous,
+			After:     &record.Event,
+		}
+		if record.Operation == OperationDeleted {
+			entry.After = nil
+		}
+
+		changes, err := diff(entry.Before, entry.After)
+		if err != nil {
+			return nil, err
+		}
+		entry.Changes = changes
+		entries = append(entries, entry)
+		previous = entry.After
+	}
+	return entries, nil
+}
+
+// diff -- returns the fields which differ in the events, either of them may be nil.
+func diff(before, after *Event) ([]Change, error) {
+	beforeFields, err := fieldsOf(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fieldsOf(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var names = make([]string, 0, len(afterFields))
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var changes = make([]Change, 0, len(names))
+	for _, name := range names {
+		if slices.Contains(unversioned, name) || bytes.Equal(beforeFields[name], afterFields[name]) {
+			continue
+		}
+		changes = append(changes, Change{Field: name, Before: beforeFields[name], After: afterFields[name]})
+	}
+	return changes, nil
+}
+
+func fieldsOf(event *Event) (map[string]json.RawMessage, error) {
+	if event == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	return fields, json.Unmarshal(data, &fields)
+}
+
+type actorKey struct{}
+
+// WithActor -- marks ctx with the user changes made with it are recorded in the event history on behalf of.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorOf -- returns the user ctx is marked with by WithActor, empty if it isn't.
+func ActorOf(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
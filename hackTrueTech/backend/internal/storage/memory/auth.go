@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+)
+
+func (s *Storage) GetPassword(ctx context.Context, username string) (string, error) {
+	const op = "storage.memory.auth.GetPassword"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	usr, ok := s.users[username]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return usr.password, nil
+}
+
+func (s *Storage) RegisterUser(ctx context.Context, usr *auth.User) error {
+	const op = "storage.memory.auth.RegisterUser"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[usr.Username]; ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAlreadyExists)
+	}
+	s.users[usr.Username] = user{password: usr.Password, gender: usr.Gender, age: usr.Age}
+	return nil
+}
+
+func (s *Storage) IsAdmin(ctx context.Context, username string) (bool, error) {
+	const op = "storage.memory.auth.IsAdmin"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	usr, ok := s.users[username]
+	if !ok {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return usr.isAdmin, nil
+}
+
+func (s *Storage) DeleteUser(ctx context.Context, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.users, username)
+	return nil
+}
@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+)
+
+func (s *Storage) Book(ctx context.Context, eventId uint64, username string) error {
+	const op = "storage.memory.bookings.Book"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[eventId]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if s.bookings[eventId] == nil {
+		s.bookings[eventId] = make(map[string]bool)
+	}
+	if _, ok := s.bookings[eventId][username]; !ok {
+		s.bookings[eventId][username] = false
+	}
+	return nil
+}
+
+func (s *Storage) CheckIn(ctx context.Context, eventId uint64, username string) error {
+	const op = "storage.memory.bookings.CheckIn"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[eventId]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if s.bookings[eventId] == nil {
+		s.bookings[eventId] = make(map[string]bool)
+	}
+	s.bookings[eventId][username] = true
+	return nil
+}
+
+// IsBooked -- reports whether user has booked or checked in to the event.
+func (s *Storage) IsBooked(ctx context.Context, eventId uint64, username string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.bookings[eventId][username]
+	return ok, nil
+}
+
+func (s *Storage) AddFavorite(ctx context.Context, eventId uint64, username string) error {
+	const op = "storage.memory.bookings.AddFavorite"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[eventId]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if s.favorites[eventId] == nil {
+		s.favorites[eventId] = make(map[string]struct{})
+	}
+	s.favorites[eventId][username] = struct{}{}
+	return nil
+}
+
+func (s *Storage) RemoveFavorite(ctx context.Context, eventId uint64, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.favorites[eventId], username)
+	return nil
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+)
+
+func (s *Storage) RestoreCache() ([]storage.Event, error) {
+	const op = "storage.memory.RestoreCache"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []storage.Event
+	for _, id := range sortedIds(s.cache) {
+		if event, ok := s.events[id]; ok {
+			events = append(events, s.listed(event))
+		}
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%s: no ids cached: %w", op, storage.ErrNotFound)
+	}
+	return events, nil
+}
+
+func (s *Storage) SaveCache(id uint) error {
+	const op = "storage.memory.cacher.SaveCache"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[uint64(id)]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	if _, ok := s.cache[uint64(id)]; ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAlreadyExists)
+	}
+	s.cache[uint64(id)] = struct{}{}
+	return nil
+}
+
+func (s *Storage) DeleteCache(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.cache, uint64(id))
+	return nil
+}
+
+func (s *Storage) IsAlreadyCached(id uint) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.cache[uint64(id)]
+	return ok
+}
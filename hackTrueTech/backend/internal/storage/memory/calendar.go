@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"slices"
+)
+
+func (s *Storage) GetCalendarToken(ctx context.Context, username string) (string, error) {
+	const op = "storage.memory.calendar.GetCalendarToken"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[username]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return token, nil
+}
+
+// SetCalendarToken -- sets user's calendar feed token replacing the previous one, so old feed links stop working.
+func (s *Storage) SetCalendarToken(ctx context.Context, username, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[username] = token
+	return nil
+}
+
+func (s *Storage) GetCalendarUsername(ctx context.Context, token string) (string, error) {
+	const op = "storage.memory.calendar.GetCalendarUsername"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for username, userToken := range s.tokens {
+		if userToken == token {
+			return username, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+}
+
+// GetUserEvents -- returns events user has booked or added to favorites ordered by date.
+func (s *Storage) GetUserEvents(ctx context.Context, username string) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []storage.Event
+	for _, id := range sortedIds(s.events) {
+		event := s.events[id]
+		if event.Status == storage.StatusDraft {
+			continue
+		}
+		_, booked := s.bookings[id][username]
+		_, favorite := s.favorites[id][username]
+		if booked || favorite {
+			events = append(events, s.listed(event))
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b storage.Event) int {
+		return a.Date.Compare(b.Date)
+	})
+	return events, nil
+}
@@ -0,0 +1,286 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"maps"
+	"slices"
+	"time"
+)
+
+// defaultListLimit -- is the number of events listed when no features are requested.
+const defaultListLimit = 30
+
+func (s *Storage) GetEvent(ctx context.Context, id uint64) (*storage.Event, error) {
+	const op = "storage.memory.events.GetEvent"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	res := s.listed(event)
+	return &res, nil
+}
+
+func (s *Storage) CreateEvent(ctx context.Context, event *storage.Event) (uint64, error) {
+	const op = "storage.memory.events.CreateEvent"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.createEvent(ctx, event)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// createEvent -- saves event, storage.SubjectEventCreated outbox message and the first history record, mu must
+// be held.
+func (s *Storage) createEvent(ctx context.Context, event *storage.Event) (uint64, error) {
+	if event.Status == "" {
+		event.Status = storage.StatusPublished
+	}
+
+	s.lastEventId++
+	saved := *event
+	saved.Id = s.lastEventId
+	saved.Date = event.Date.UTC()
+	saved.Feature = knownFeatures(event.Feature)
+	saved.Rating, saved.Ratings, saved.ReviewsCount = 0, nil, 0
+	saved.Version = 1
+	s.events[saved.Id] = &saved
+
+	if saved.Status == storage.StatusPendingReview {
+		s.decide(storage.Decision{EventId: saved.Id, Actor: saved.Owner, Decision: storage.DecisionSubmitted})
+	}
+
+	if err := s.enqueueEvents(ctx, storage.SubjectEventCreated, storage.OperationCreated, []uint64{saved.Id}); err != nil {
+		return 0, err
+	}
+	return saved.Id, nil
+}
+
+func (s *Storage) GetEventsByFeature(ctx context.Context, filter *storage.Filter) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var features []string
+	var limit = defaultListLimit
+	if filter.Features != nil {
+		features = knownFeatures(filter.Features)
+		if len(features) == 0 {
+			features = slices.Clone(storage.Features)
+		}
+		limit = -1
+	}
+
+	statuses := filter.ListedStatuses()
+	var events = make([]storage.Event, 0, 8)
+	for _, id := range sortedIds(s.events) {
+		if limit >= 0 && len(events) == limit {
+			break
+		}
+		event := s.events[id]
+		if features != nil && !slices.Equal(event.Feature, features) {
+			continue
+		}
+		if !slices.Contains(statuses, event.Status) {
+			continue
+		}
+		events = append(events, s.listed(event))
+	}
+
+	events = slices.DeleteFunc(events, func(event storage.Event) bool {
+		return !filter.Allows(&event)
+	})
+	storage.SortEvents(events, filter)
+
+	return events, nil
+}
+
+// DeleteEvent -- deletes the event and everything referencing it.
+func (s *Storage) DeleteEvent(ctx context.Context, id uint64) error {
+	const op = "storage.memory.events.DeleteEvent"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	s.record(ctx, storage.OperationDeleted, event)
+
+	delete(s.events, id)
+	delete(s.bookings, id)
+	delete(s.favorites, id)
+	delete(s.cache, id)
+	maps.DeleteFunc(s.reviews, func(_ uint64, review *storage.Review) bool {
+		return review.EventId == id
+	})
+	s.decisions = slices.DeleteFunc(s.decisions, func(decision storage.Decision) bool {
+		return decision.EventId == id
+	})
+
+	if err := s.enqueue(storage.SubjectEventDeleted, storage.EventDeletion{Id: id}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// PatchEvent -- applies the patch and returns the new version of the event. storage.ErrVersionConflict is returned
+// if patch.Version is set and the event has another one.
+func (s *Storage) PatchEvent(ctx context.Context, patch *storage.EventPatch) (uint64, error) {
+	const op = "storage.memory.events.PatchEvent"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, err := s.checkVersion(patch.Id, patch.Version)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	patch.Apply(saved)
+	saved.Date = saved.Date.UTC()
+	saved.Version++
+
+	if err = s.enqueueEvents(ctx, storage.SubjectEventUpdated, storage.OperationUpdated, []uint64{patch.Id}); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return saved.Version, nil
+}
+
+// checkVersion -- returns the event if it has the version, any one matches zero version, mu must be held.
+func (s *Storage) checkVersion(id, version uint64) (*storage.Event, error) {
+	event, ok := s.events[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	if version != 0 && version != event.Version {
+		return nil, storage.ErrVersionConflict
+	}
+	return event, nil
+}
+
+// SetEventStatus -- moves event to the status. storage.ErrInvalidTransition is returned if event's current status
+// doesn't allow it or there is no such event.
+func (s *Storage) SetEventStatus(ctx context.Context, id uint64, status string) error {
+	const op = "storage.memory.events.SetEventStatus"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[id]
+	if !ok || !storage.CanTransition(event.Status, status) {
+		return fmt.Errorf("%s: %w", op, storage.ErrInvalidTransition)
+	}
+	event.Status = status
+	event.Version++
+
+	if err := s.enqueueEvents(ctx, storage.SubjectEventUpdated, storage.OperationStatusChanged, []uint64{id}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ArchivePastEvents -- archives every event which date is before the given time and returns the number of them.
+func (s *Storage) ArchivePastEvents(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.memory.events.ArchivePastEvents"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []uint64
+	for _, id := range sortedIds(s.events) {
+		event := s.events[id]
+		if event.Date.Before(before) && storage.CanTransition(event.Status, storage.StatusArchived) {
+			event.Status = storage.StatusArchived
+			event.Version++
+			ids = append(ids, id)
+		}
+	}
+
+	if err := s.enqueueEvents(ctx, storage.SubjectEventUpdated, storage.OperationStatusChanged, ids); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return int64(len(ids)), nil
+}
+
+func (s *Storage) GetBookers(ctx context.Context, id uint64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var bookers = make([]string, 0, 4)
+	for username := range s.bookings[id] {
+		bookers = append(bookers, username)
+	}
+	slices.Sort(bookers)
+	return bookers, nil
+}
+
+// listed -- returns a copy of event with the rating, reviews count and aspect ratings of its visible reviews,
+// mu must be held.
+func (s *Storage) listed(event *storage.Event) storage.Event {
+	res := *event
+	res.Feature = slices.Clone(event.Feature)
+	res.Rating, res.ReviewsCount, res.Ratings = s.aggregate(func(review *storage.Review) bool {
+		return review.EventId == event.Id
+	})
+	return res
+}
+
+// aggregate -- returns the average score, the number of reviews and the average score of every aspect of visible
+// rated reviews matching keep, aspect scores are nil if there are none. mu must be held.
+func (s *Storage) aggregate(keep func(review *storage.Review) bool) (float64, uint64, map[string]float64) {
+	var total, count float64
+	var reviews uint64
+	var aspects = make(map[string][2]float64)
+	for _, review := range s.reviews {
+		if review.Hidden || len(review.Ratings) == 0 || !keep(review) {
+			continue
+		}
+		reviews++
+		for aspect, score := range review.Ratings {
+			total += float64(score)
+			count++
+			aspects[aspect] = [2]float64{aspects[aspect][0] + float64(score), aspects[aspect][1] + 1}
+		}
+	}
+	if count == 0 {
+		return 0, 0, nil
+	}
+
+	var ratings = make(map[string]float64, len(aspects))
+	for aspect, sum := range aspects {
+		ratings[aspect] = sum[0] / sum[1]
+	}
+	return total / count, reviews, ratings
+}
+
+// knownFeatures -- returns the known features of the given ones sorted, never nil.
+func knownFeatures(features []string) []string {
+	var res = make([]string, 0, len(features))
+	for _, feature := range features {
+		if slices.Contains(storage.Features, feature) && !slices.Contains(res, feature) {
+			res = append(res, feature)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+// sortedIds -- returns keys of m in ascending order.
+func sortedIds[V any](m map[uint64]V) []uint64 {
+	var ids = make([]uint64, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"slices"
+	"time"
+)
+
+// GetEventHistory -- returns every change of the event ordered by the time it was made, deleted events included.
+func (s *Storage) GetEventHistory(ctx context.Context, id uint64) ([]storage.HistoryRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var records = make([]storage.HistoryRecord, 0, 4)
+	for _, record := range s.history {
+		if record.EventId == id {
+			record.Event.Feature = slices.Clone(record.Event.Feature)
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// RestoreEvent -- brings the event back to the state it had at the version, recreating it if it has been deleted,
+// and returns the new version. Bookings, favorites and reviews of a deleted event are gone for good.
+func (s *Storage) RestoreEvent(ctx context.Context, id, version uint64) (uint64, error) {
+	const op = "storage.memory.history.RestoreEvent"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var restored *storage.Event
+	var last uint64
+	for i := range s.history {
+		record := &s.history[i]
+		if record.EventId != id {
+			continue
+		}
+		if record.Version == version {
+			restored = &record.Event
+		}
+		last = max(last, record.Version)
+	}
+	if restored == nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	event := *restored
+	event.Id = id
+	event.Feature = knownFeatures(restored.Feature)
+	subject := storage.SubjectEventUpdated
+	if current, ok := s.events[id]; ok {
+		event.Version = current.Version + 1
+	} else {
+		event.Version = last + 1
+		subject = storage.SubjectEventCreated
+	}
+	s.events[id] = &event
+
+	if err := s.enqueueEvents(ctx, subject, storage.OperationRestored, []uint64{id}); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return event.Version, nil
+}
+
+// record -- appends the state of the event to its history on behalf of the actor ctx is marked with, mu must
+// be held.
+func (s *Storage) record(ctx context.Context, operation string, event *storage.Event) {
+	snapshot := storage.Snapshot(*event)
+	snapshot.Feature = slices.Clone(event.Feature)
+
+	s.lastHistoryId++
+	s.history = append(s.history, storage.HistoryRecord{
+		Id:        s.lastHistoryId,
+		EventId:   event.Id,
+		Version:   event.Version,
+		Operation: operation,
+		Actor:     storage.ActorOf(ctx),
+		CreatedAt: time.Now().UTC(),
+		Event:     snapshot,
+	})
+}
@@ -0,0 +1,81 @@
+// Package memory keeps everything in process memory. It's meant for tests and the demo mode: nothing survives
+// a restart, but the semantics are the same as of the SQL backends.
+package memory
+
+import (
+	"context"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"sync"
+	"time"
+)
+
+// Storage -- is safe for concurrent use. Every method holds mu for its whole run, so changes are as atomic as
+// transactions of the SQL backends.
+type Storage struct {
+	mu sync.RWMutex
+
+	users      map[string]user
+	events     map[uint64]*storage.Event
+	series     map[uint64]*storage.Series
+	bookings   map[uint64]map[string]bool
+	favorites  map[uint64]map[string]struct{}
+	cache      map[uint64]struct{}
+	tokens     map[string]string
+	reviews    map[uint64]*storage.Review
+	decisions  []storage.Decision
+	outbox     []outboxEntry
+	history    []storage.HistoryRecord
+	webhooks   []storage.Webhook
+	deliveries []storage.Delivery
+	operations map[string]storage.Operation
+
+	// publishMu -- serializes PublishOutbox, so a message isn't handed to two publishers at once.
+	publishMu sync.Mutex
+
+	lastEventId    uint64
+	lastSeriesId   uint64
+	lastReviewId   uint64
+	lastDecisionId uint64
+	lastOutboxId   uint64
+	lastHistoryId  uint64
+	lastWebhookId  uint64
+	lastDeliveryId uint64
+}
+
+type user struct {
+	password string
+	isAdmin  bool
+	gender   bool
+	age      string
+}
+
+type outboxEntry struct {
+	message     storage.OutboxMessage
+	publishedAt time.Time
+}
+
+// New -- returns an empty storage with the same users the initial migration of the SQL backends creates.
+func New() *Storage {
+	return &Storage{
+		users: map[string]user{
+			"idkidkidk": {password: "idkidkidk", isAdmin: true, gender: true, age: "20"},
+			"idkidk":    {password: "idkidk", age: "18"},
+		},
+		events:     make(map[uint64]*storage.Event),
+		series:     make(map[uint64]*storage.Series),
+		bookings:   make(map[uint64]map[string]bool),
+		favorites:  make(map[uint64]map[string]struct{}),
+		cache:      make(map[uint64]struct{}),
+		tokens:     make(map[string]string),
+		reviews:    make(map[uint64]*storage.Review),
+		operations: make(map[string]storage.Operation),
+	}
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/conformance"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) conformance.Storage {
+		return New()
+	})
+}
+
+func TestConcurrentUse(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	const writers = 16
+	var wg sync.WaitGroup
+	var ids = make([]uint64, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id, err := s.CreateEvent(ctx, &storage.Event{Name: "concurrent", Feature: []string{"deaf"}})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids[i] = id
+			if err = s.Book(ctx, id, "booker"); err != nil {
+				t.Error(err)
+			}
+			if _, err = s.GetEventsByFeature(ctx, &storage.Filter{Features: []string{"deaf"}}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	slices.Sort(ids)
+	if ids = slices.Compact(ids); len(ids) != writers {
+		t.Fatalf("concurrent creates returned %d distinct ids, want %d", len(ids), writers)
+	}
+	events, err := s.GetEventsByFeature(ctx, &storage.Filter{Features: []string{"deaf"}})
+	if err != nil || len(events) != writers {
+		t.Fatalf("listed %d events, %v; want %d", len(events), err, writers)
+	}
+}
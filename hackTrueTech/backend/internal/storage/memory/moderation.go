@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"slices"
+	"time"
+)
+
+// GetModerationQueue -- returns events waiting for review in the order they were created.
+func (s *Storage) GetModerationQueue(ctx context.Context) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []storage.Event
+	for _, id := range sortedIds(s.events) {
+		if event := s.events[id]; event.Status == storage.StatusPendingReview {
+			events = append(events, s.listed(event))
+		}
+	}
+	return events, nil
+}
+
+// Moderate -- moves the event and the rest of its series occurrences in the same status to the status of
+// the decision and records the decision. storage.ErrInvalidTransition is returned if the event's status doesn't
+// allow the decision.
+func (s *Storage) Moderate(ctx context.Context, decision *storage.Decision) error {
+	const op = "storage.memory.moderation.Moderate"
+
+	status := decision.Status()
+	if status == "" {
+		return fmt.Errorf("%s: unknown decision %q", op, decision.Decision)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[decision.EventId]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	current := event.Status
+	if !storage.CanTransition(current, status) {
+		return fmt.Errorf("%s: %w", op, storage.ErrInvalidTransition)
+	}
+
+	var ids []uint64
+	for _, id := range sortedIds(s.events) {
+		occurrence := s.events[id]
+		if occurrence.Status != current {
+			continue
+		}
+		if id == event.Id || (event.SeriesId != 0 && occurrence.SeriesId == event.SeriesId) {
+			occurrence.Status = status
+			occurrence.Version++
+			ids = append(ids, id)
+		}
+	}
+	if err := s.enqueueEvents(storage.WithActor(ctx, decision.Actor), storage.SubjectEventUpdated,
+		storage.OperationStatusChanged, ids); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	*decision = s.decide(*decision)
+	return nil
+}
+
+func (s *Storage) GetDecisions(ctx context.Context, eventId uint64) ([]storage.Decision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var decisions = make([]storage.Decision, 0, 2)
+	for _, decision := range s.decisions {
+		if decision.EventId == eventId {
+			decisions = append(decisions, decision)
+		}
+	}
+
+	slices.SortStableFunc(decisions, func(a, b storage.Decision) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return decisions, nil
+}
+
+// decide -- records the decision and returns it with the id and the creation time set, mu must be held.
+func (s *Storage) decide(decision storage.Decision) storage.Decision {
+	s.lastDecisionId++
+	decision.Id = s.lastDecisionId
+	decision.CreatedAt = time.Now().UTC()
+	s.decisions = append(s.decisions, decision)
+	return decision
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"slices"
+	"time"
+)
+
+// CreateOperation -- saves the pending operation. storage.ErrAlreadyExists is returned if there is one with its id.
+func (s *Storage) CreateOperation(ctx context.Context, operation *storage.Operation) error {
+	const op = "storage.memory.operations.CreateOperation"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.operations[operation.Id]; ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAlreadyExists)
+	}
+	saved := *operation
+	saved.Result = slices.Clone(operation.Result)
+	s.operations[saved.Id] = saved
+	return nil
+}
+
+// FinishOperation -- saves the status, the code, the message, the result and the time the operation finished at.
+func (s *Storage) FinishOperation(ctx context.Context, operation *storage.Operation) error {
+	const op = "storage.memory.operations.FinishOperation"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, ok := s.operations[operation.Id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	saved.Status, saved.Code, saved.Message = operation.Status, operation.Code, operation.Message
+	saved.Result = slices.Clone(operation.Result)
+	if operation.FinishedAt != nil {
+		finishedAt := operation.FinishedAt.UTC()
+		saved.FinishedAt = &finishedAt
+	}
+	s.operations[saved.Id] = saved
+	return nil
+}
+
+// GetOperation -- returns the operation, storage.ErrNotFound is returned if there is no such one.
+func (s *Storage) GetOperation(ctx context.Context, id string) (*storage.Operation, error) {
+	const op = "storage.memory.operations.GetOperation"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	operation, ok := s.operations[id]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	operation.Result = slices.Clone(operation.Result)
+	return &operation, nil
+}
+
+// PurgeOperations -- deletes operations started before the given time and returns the number of them.
+func (s *Storage) PurgeOperations(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for id, operation := range s.operations {
+		if operation.CreatedAt.Before(before) {
+			delete(s.operations, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
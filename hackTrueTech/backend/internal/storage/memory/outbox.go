@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"slices"
+	"time"
+)
+
+// PublishOutbox -- hands up to limit pending outbox messages to publish in the order they were written and marks
+// them published if publish succeeds. Messages publish failed on are retried next time, so they are delivered at
+// least once. Returns the number of published messages.
+func (s *Storage) PublishOutbox(ctx context.Context, limit int, publish func([]storage.OutboxMessage) error) (int, error) {
+	const op = "storage.memory.outbox.PublishOutbox"
+
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+
+	s.mu.RLock()
+	var messages = make([]storage.OutboxMessage, 0, limit)
+	for _, entry := range s.outbox {
+		if len(messages) == limit {
+			break
+		}
+		if entry.publishedAt.IsZero() {
+			messages = append(messages, entry.message)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(messages) == 0 {
+		return 0, nil
+	}
+	if err := publish(messages); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i := range s.outbox {
+		if slices.ContainsFunc(messages, func(message storage.OutboxMessage) bool {
+			return message.Id == s.outbox[i].message.Id
+		}) {
+			s.outbox[i].publishedAt = now
+		}
+	}
+	return len(messages), nil
+}
+
+// PurgeOutbox -- deletes messages published before the given time and returns the number of them.
+func (s *Storage) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.outbox)
+	s.outbox = slices.DeleteFunc(s.outbox, func(entry outboxEntry) bool {
+		return !entry.publishedAt.IsZero() && entry.publishedAt.Before(before)
+	})
+	return int64(count - len(s.outbox)), nil
+}
+
+// enqueue -- writes payload marshalled to JSON to the outbox, mu must be held.
+func (s *Storage) enqueue(subject string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	s.lastOutboxId++
+	s.outbox = append(s.outbox, outboxEntry{message: storage.OutboxMessage{
+		Id:        s.lastOutboxId,
+		Subject:   subject,
+		Payload:   data,
+		CreatedAt: time.Now().UTC(),
+	}})
+	return nil
+}
+
+// enqueueEvents -- writes the current state of events with the ids to the outbox and appends it to their history
+// as the operation, mu must be held.
+func (s *Storage) enqueueEvents(ctx context.Context, subject, operation string, ids []uint64) error {
+	for _, id := range ids {
+		event, ok := s.events[id]
+		if !ok {
+			continue
+		}
+		listed := s.listed(event)
+		if err := s.enqueue(subject, &listed); err != nil {
+			return err
+		}
+		s.record(ctx, operation, event)
+	}
+	return nil
+}
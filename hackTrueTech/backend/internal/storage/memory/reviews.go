@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"maps"
+	"slices"
+	"time"
+)
+
+func (s *Storage) CreateReview(ctx context.Context, review *storage.Review) (uint64, error) {
+	const op = "storage.memory.reviews.CreateReview"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[review.EventId]; !ok {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	for _, saved := range s.reviews {
+		if saved.EventId == review.EventId && saved.Username == review.Username {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrAlreadyExists)
+		}
+	}
+
+	s.lastReviewId++
+	review.Id = s.lastReviewId
+	review.CreatedAt = time.Now().UTC()
+
+	saved := *review
+	saved.Ratings = maps.Clone(review.Ratings)
+	s.reviews[saved.Id] = &saved
+
+	return review.Id, nil
+}
+
+// GetReviews -- returns reviews left on the event, hidden ones are included only if withHidden is set.
+func (s *Storage) GetReviews(ctx context.Context, eventId uint64, withHidden bool) ([]storage.Review, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var reviews = make([]storage.Review, 0, 4)
+	for _, review := range s.reviews {
+		if review.EventId != eventId || (review.Hidden && !withHidden) {
+			continue
+		}
+		res := *review
+		res.Ratings = maps.Clone(review.Ratings)
+		if res.Ratings == nil {
+			res.Ratings = make(map[string]uint8)
+		}
+		reviews = append(reviews, res)
+	}
+
+	slices.SortFunc(reviews, func(a, b storage.Review) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Id, a.Id)
+	})
+	return reviews, nil
+}
+
+func (s *Storage) SetReviewHidden(ctx context.Context, id uint64, hidden bool) error {
+	const op = "storage.memory.reviews.SetReviewHidden"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	review, ok := s.reviews[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	review.Hidden = hidden
+	return nil
+}
+
+func (s *Storage) GetVenueRating(ctx context.Context, city, address string) (*storage.VenueRating, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	venue := storage.VenueRating{City: city, Address: address}
+	venue.Rating, venue.ReviewsCount, venue.Ratings = s.aggregate(func(review *storage.Review) bool {
+		event, ok := s.events[review.EventId]
+		return ok && event.City == city && event.Address == address
+	})
+	if venue.Ratings == nil {
+		venue.Ratings = make(map[string]float64)
+	}
+	return &venue, nil
+}
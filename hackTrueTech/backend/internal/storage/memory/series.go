@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"slices"
+)
+
+// CreateSeries -- saves the series and materialises every its occurrence as an individual event. Ids of created
+// occurrences are set to series.Occurrences.
+func (s *Storage) CreateSeries(ctx context.Context, series *storage.Series) (uint64, error) {
+	const op = "storage.memory.series.CreateSeries"
+
+	dates, err := series.Dates()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(dates) == 0 {
+		return 0, fmt.Errorf("%s: series has no occurrences: %w", op, storage.ErrInvalidInput)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSeriesId++
+	series.Id = s.lastSeriesId
+	series.Occurrences = make([]uint64, 0, len(dates))
+	for _, date := range dates {
+		occurrence := series.Event
+		occurrence.Date = date
+		occurrence.SeriesId = series.Id
+
+		id, err := s.createEvent(ctx, &occurrence)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		series.Occurrences = append(series.Occurrences, id)
+	}
+
+	saved := *series
+	saved.Exceptions = slices.Clone(series.Exceptions)
+	saved.Occurrences = slices.Clone(series.Occurrences)
+	s.series[saved.Id] = &saved
+
+	return series.Id, nil
+}
+
+// PatchSeries -- applies the patch except the date to every occurrence of the series the event patch.Id belongs to
+// and returns the new version of that event. patch.Version is checked against the version of that event.
+func (s *Storage) PatchSeries(ctx context.Context, patch *storage.EventPatch) (uint64, error) {
+	const op = "storage.memory.series.PatchSeries"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	patched, err := s.checkVersion(patch.Id, patch.Version)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if patched.SeriesId == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	seriesPatch := *patch
+	seriesPatch.Date = nil
+
+	var ids []uint64
+	for _, id := range sortedIds(s.events) {
+		occurrence := s.events[id]
+		if occurrence.SeriesId != patched.SeriesId {
+			continue
+		}
+		seriesPatch.Apply(occurrence)
+		occurrence.Version++
+		ids = append(ids, id)
+	}
+
+	if err = s.enqueueEvents(ctx, storage.SubjectEventUpdated, storage.OperationUpdated, ids); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return patched.Version, nil
+}
@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"slices"
+	"sort"
+	"time"
+)
+
+// CreateWebhook -- saves the webhook and returns its id.
+func (s *Storage) CreateWebhook(ctx context.Context, webhook *storage.Webhook) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastWebhookId++
+	saved := *webhook
+	saved.Id = s.lastWebhookId
+	saved.Subjects = slices.Clone(webhook.Subjects)
+	if saved.Subjects == nil {
+		saved.Subjects = []string{}
+	}
+	saved.CreatedAt = time.Now().UTC()
+	s.webhooks = append(s.webhooks, saved)
+	return saved.Id, nil
+}
+
+// GetWebhooks -- returns every webhook with its secret ordered by id.
+func (s *Storage) GetWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var webhooks = make([]storage.Webhook, 0, len(s.webhooks))
+	for _, webhook := range s.webhooks {
+		webhook.Subjects = slices.Clone(webhook.Subjects)
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook -- deletes the webhook with its deliveries.
+func (s *Storage) DeleteWebhook(ctx context.Context, id uint64) error {
+	const op = "storage.memory.webhooks.DeleteWebhook"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.webhooks, func(webhook storage.Webhook) bool { return webhook.Id == id })
+	if i == -1 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	s.webhooks = slices.Delete(s.webhooks, i, i+1)
+	s.deliveries = slices.DeleteFunc(s.deliveries, func(delivery storage.Delivery) bool {
+		return delivery.WebhookId == id
+	})
+	return nil
+}
+
+// EnqueueDeliveries -- schedules the domain event for every webhook subscribed to the subject and returns
+// the number of deliveries scheduled. The event with the key is scheduled once, enqueueing it again is a no-op.
+func (s *Storage) EnqueueDeliveries(ctx context.Context, subject, key string, payload []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	var created int
+	for i := range s.webhooks {
+		webhook := &s.webhooks[i]
+		if !webhook.Subscribed(subject) || slices.ContainsFunc(s.deliveries, func(delivery storage.Delivery) bool {
+			return delivery.WebhookId == webhook.Id && delivery.Key == key
+		}) {
+			continue
+		}
+
+		s.lastDeliveryId++
+		s.deliveries = append(s.deliveries, storage.Delivery{
+			Id:            s.lastDeliveryId,
+			WebhookId:     webhook.Id,
+			Key:           key,
+			Subject:       subject,
+			Payload:       slices.Clone(payload),
+			Status:        storage.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		created++
+	}
+	return created, nil
+}
+
+// ClaimDeliveries -- leases up to limit pending deliveries which are due, so they aren't claimed again for lease.
+// Ones whose lease runs out before an attempt is recorded are claimed again, so they are delivered at least once.
+func (s *Storage) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storage.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	var due []*storage.Delivery
+	for i := range s.deliveries {
+		delivery := &s.deliveries[i]
+		if delivery.Status == storage.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+
+	var claimed = make([]storage.Delivery, 0, min(limit, len(due)))
+	for _, delivery := range due[:min(limit, len(due))] {
+		delivery.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, cloneDelivery(*delivery))
+	}
+	return claimed, nil
+}
+
+// RecordAttempt -- records the outcome of an attempt to deliver.
+func (s *Storage) RecordAttempt(ctx context.Context, id uint64, attempt *storage.Attempt) error {
+	const op = "storage.memory.webhooks.RecordAttempt"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.deliveries, func(delivery storage.Delivery) bool { return delivery.Id == id })
+	if i == -1 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	delivery := &s.deliveries[i]
+	delivery.Status = attempt.Status
+	delivery.Attempts++
+	delivery.ResponseCode = attempt.ResponseCode
+	delivery.Error = attempt.Error
+	delivery.NextAttemptAt = attempt.NextAttemptAt.UTC()
+	return nil
+}
+
+// GetDeliveries -- returns up to limit last deliveries to the webhook, the latest first.
+func (s *Storage) GetDeliveries(ctx context.Context, webhookId uint64, limit int) ([]storage.Delivery, error) {
+	const op = "storage.memory.webhooks.GetDeliveries"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !slices.ContainsFunc(s.webhooks, func(webhook storage.Webhook) bool { return webhook.Id == webhookId }) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	var deliveries = make([]storage.Delivery, 0, 8)
+	for i := len(s.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if s.deliveries[i].WebhookId == webhookId {
+			deliveries = append(deliveries, cloneDelivery(s.deliveries[i]))
+		}
+	}
+	return deliveries, nil
+}
+
+func cloneDelivery(delivery storage.Delivery) storage.Delivery {
+	delivery.Payload = slices.Clone(delivery.Payload)
+	return delivery
+}
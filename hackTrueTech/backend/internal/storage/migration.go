@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Migration -- is a pair of up and down scripts named <version>_<name>.up.sql and <version>_<name>.down.sql.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// ParseMigrations -- reads every migration from dir of fsys and orders them by version.
+func ParseMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	const op = "storage.migration.ParseMigrations"
+
+	files, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var byVersion = make(map[int64]*Migration)
+	for _, file := range files {
+		base := path.Base(file)
+		rawVersion, rest, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("%s: malformed migration name %q", op, base)
+		}
+		version, err := strconv.ParseInt(rawVersion, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: malformed migration version %q: %w", op, base, err)
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version}
+			byVersion[version] = migration
+		}
+		switch {
+		case strings.HasSuffix(rest, ".up.sql"):
+			migration.Name = strings.TrimSuffix(rest, ".up.sql")
+			migration.Up = string(data)
+		case strings.HasSuffix(rest, ".down.sql"):
+			migration.Down = string(data)
+		default:
+			return nil, fmt.Errorf("%s: migration %q is neither up nor down", op, base)
+		}
+	}
+
+	var migrations = make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("%s: migration %d has no up script", op, migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
@@ -0,0 +1,32 @@
+package storage
+
+import "time"
+
+const (
+	DecisionSubmitted = "submitted"
+	DecisionApproved  = "approved"
+	DecisionRejected  = "rejected"
+)
+
+// decisionStatuses -- maps moderation decisions to the statuses they move events to.
+var decisionStatuses = map[string]string{
+	DecisionSubmitted: StatusPendingReview,
+	DecisionApproved:  StatusPublished,
+	DecisionRejected:  StatusRejected,
+}
+
+// Decision -- is an audit record of the moderation queue: submission of the event by its owner, approval or
+// rejection of it by an admin. Reason is required for rejections.
+type Decision struct {
+	Id        uint64    `json:"id,omitempty"`
+	EventId   uint64    `json:"event_id"`
+	Actor     string    `json:"actor"`
+	Decision  string    `json:"decision"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Status -- returns the status the decision moves event to, empty if decision is unknown.
+func (d *Decision) Status() string {
+	return decisionStatuses[d.Decision]
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Operation -- is a change of the event requested in async mode. Once it's finished Code and Message are
+// the status code and the status of the response the change would get in sync mode, Result is its JSON body if any.
+type Operation struct {
+	Id         string          `json:"id"`
+	Kind       string          `json:"kind"`
+	EventId    uint64          `json:"event_id"`
+	Actor      string          `json:"-"`
+	Status     string          `json:"status"`
+	Code       int             `json:"code,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
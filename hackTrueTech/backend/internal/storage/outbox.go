@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"strconv"
+	"time"
+)
+
+// Domain events published from the outbox. Payload of created and updated events is the event itself, the payload
+// of deleted one is EventDeletion.
+const (
+	SubjectEventCreated = "events.created"
+	SubjectEventUpdated = "events.updated"
+	SubjectEventDeleted = "events.deleted"
+)
+
+// OutboxMessage -- is a domain event written to the outbox in the same transaction as the change it describes and
+// published to the broker afterwards.
+type OutboxMessage struct {
+	Id        uint64
+	Subject   string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// Key -- is the idempotency key of the message. It's the same for every delivery attempt, so consumers and the broker
+// can drop duplicates.
+func (m *OutboxMessage) Key() string {
+	return "outbox-" + strconv.FormatUint(m.Id, 10)
+}
+
+// EventDeletion -- is the payload of SubjectEventDeleted.
+type EventDeletion struct {
+	Id uint64 `json:"id"`
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrVersionConflict -- is returned when the event has been changed since the version a change was made against.
+var ErrVersionConflict = errors.New("version conflict")
+
+// EventPatch -- is a partial update of the event Id. Only the fields that are set are changed. Version is the version
+// of the event the patch was made against, the patch is applied to that version only unless it's zero.
+type EventPatch struct {
+	Id           uint64     `json:"id"`
+	Version      uint64     `json:"version,omitempty"`
+	Price        *uint64    `json:"price,omitempty"`
+	Restrictions *uint64    `json:"restrictions,omitempty"`
+	Date         *time.Time `json:"date,omitempty"`
+	City         *string    `json:"city,omitempty"`
+	Address      *string    `json:"address,omitempty"`
+	Name         *string    `json:"name,omitempty"`
+	Description  *string    `json:"description,omitempty"`
+}
+
+// ParseEventPatch -- parses a JSON Merge Patch (RFC 7396) of an event: members that are present replace the fields,
+// null resets a nullable field, see nullValues, and absent fields are kept. Null for any other field is
+// ErrInvalidInput, the event can't go without it. "id" identifies the event, the rest of the members, e.g. status or
+// features, aren't patchable and are ignored.
+func ParseEventPatch(data []byte) (*EventPatch, error) {
+	const op = "storage.patch.ParseEventPatch"
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if members == nil {
+		return nil, fmt.Errorf("%s: patch must be an object", op)
+	}
+
+	var patch EventPatch
+	if raw, ok := members["id"]; ok {
+		if err := json.Unmarshal(raw, &patch.Id); err != nil {
+			return nil, fmt.Errorf("%s: id: %w", op, err)
+		}
+	}
+
+	fields := map[string]any{
+		"price":        &patch.Price,
+		"restrictions": &patch.Restrictions,
+		"date":         &patch.Date,
+		"city":         &patch.City,
+		"address":      &patch.Address,
+		"name":         &patch.Name,
+		"description":  &patch.Description,
+	}
+	for name, field := range fields {
+		raw, ok := members[name]
+		if !ok {
+			continue
+		}
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			if raw, ok = nullValues[name]; !ok {
+				return nil, fmt.Errorf("%s: %w: %s can't be null", op, ErrInvalidInput, name)
+			}
+		}
+		if err := json.Unmarshal(raw, field); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, name, err)
+		}
+	}
+
+	return &patch, nil
+}
+
+// nullValues -- are the values nullable fields are reset to with null. Price and restrictions must be positive,
+// and an event has to have a date, a place and a name, so they're not among them.
+var nullValues = map[string]json.RawMessage{
+	"description": json.RawMessage(`""`),
+}
+
+// Apply -- changes the fields of event set in the patch.
+func (p *EventPatch) Apply(event *Event) {
+	if p.Price != nil {
+		event.Price = *p.Price
+	}
+	if p.Restrictions != nil {
+		event.Restrictions = *p.Restrictions
+	}
+	if p.Date != nil {
+		event.Date = *p.Date
+	}
+	if p.City != nil {
+		event.City = *p.City
+	}
+	if p.Address != nil {
+		event.Address = *p.Address
+	}
+	if p.Name != nil {
+		event.Name = *p.Name
+	}
+	if p.Description != nil {
+		event.Description = *p.Description
+	}
+}
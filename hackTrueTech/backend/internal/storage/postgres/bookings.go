@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+)
+
+const foreignKeyViolation = "23503"
+
+func (s *Storage) Book(ctx context.Context, eventId uint64, username string) error {
+	const op = "storage.postgres.bookings.Book"
+
+	if _, err := s.driver.ExecContext(ctx, createBooking, eventId, username); err != nil {
+		return fmt.Errorf("%s: %w", op, missingEvent(err))
+	}
+	return nil
+}
+
+func (s *Storage) CheckIn(ctx context.Context, eventId uint64, username string) error {
+	const op = "storage.postgres.bookings.CheckIn"
+
+	if _, err := s.driver.ExecContext(ctx, checkIn, eventId, username); err != nil {
+		return fmt.Errorf("%s: %w", op, missingEvent(err))
+	}
+	return nil
+}
+
+// IsBooked -- reports whether user has booked or checked in to the event.
+func (s *Storage) IsBooked(ctx context.Context, eventId uint64, username string) (bool, error) {
+	const op = "storage.postgres.bookings.IsBooked"
+
+	var booked bool
+	if err := s.reader(ctx).QueryRowContext(ctx, isBooked, eventId, username).Scan(&booked); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return booked, nil
+}
+
+func (s *Storage) AddFavorite(ctx context.Context, eventId uint64, username string) error {
+	const op = "storage.postgres.bookings.AddFavorite"
+
+	if _, err := s.driver.ExecContext(ctx, addFavorite, eventId, username); err != nil {
+		return fmt.Errorf("%s: %w", op, missingEvent(err))
+	}
+	return nil
+}
+
+func (s *Storage) RemoveFavorite(ctx context.Context, eventId uint64, username string) error {
+	const op = "storage.postgres.bookings.RemoveFavorite"
+
+	if _, err := s.driver.ExecContext(ctx, removeFavorite, eventId, username); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// missingEvent -- returns storage.ErrNotFound if err is the violation of the foreign key to the event, err otherwise.
+func missingEvent(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return storage.ErrNotFound
+	}
+	return err
+}
@@ -1,8 +1,7 @@
 package postgres
 
 import (
-	"database/sql"
-	"errors"
+	"context"
 	"fmt"
 	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
 	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
@@ -12,34 +11,17 @@ import (
 func (s *Storage) RestoreCache() ([]storage.Event, error) {
 	const op = "storage.postgres.RestoreCache"
 
-	rows, err := s.driver.Query(getCachedIds)
+	rows, err := s.reader(context.Background()).Query(getCachedEvents)
 	if err != nil {
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
 
-	var ids = make([]uint, 0, 5)
-	for rows.Next() {
-		var id uint
-		if err = rows.Scan(&id); err != nil {
-			slog.Error("couldn't scan id from cacher table", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			continue
-		}
-		ids = append(ids, id)
-	}
-	if len(ids) == 0 {
-		return nil, fmt.Errorf("%s: %s", op, "no ids cached")
+	events, err := scanListedEvents(rows, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-
-	var events = make([]storage.Event, 0, 3)
-	for _, id := range ids {
-		var event storage.Event
-		if err = s.driver.QueryRow(getEvent, id).Scan(&event.Id, &event.Price, &event.Restrictions, &event.Date,
-			&event.Feature, &event.City, &event.Address, &event.Name,
-			&event.ImgPath, &event.Description); err != nil {
-			slog.Error("couldn't query row id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-			continue
-		}
-		events = append(events, event)
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%s: no ids cached: %w", op, storage.ErrNotFound)
 	}
 
 	return events, nil
@@ -64,10 +46,12 @@ func (s *Storage) DeleteCache(id uint) error {
 }
 
 func (s *Storage) IsAlreadyCached(id uint) bool {
+	const op = "storage.postgres.cacher.IsAlreadyCached"
 
-	row := s.driver.QueryRow(isAlreadyCached, id)
-	if errors.Is(row.Err(), sql.ErrNoRows) {
-		return true
+	var cached bool
+	if err := s.driver.QueryRow(isAlreadyCached, id).Scan(&cached); err != nil {
+		slog.Error("couldn't check cached id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		return false
 	}
-	return false
+	return cached
 }
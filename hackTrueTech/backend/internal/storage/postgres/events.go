@@ -3,14 +3,14 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/lib/pq"
 	"github.com/wlcmtunknwndth/hackBPA/internal/lib/compareStrings"
-	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
 	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
-	"log/slog"
 	"slices"
-	"sync"
+	"time"
 )
 
 var featuresToId = map[string]int{
@@ -30,149 +30,302 @@ var idToFeature = map[int64]string{
 func (s *Storage) GetEvent(ctx context.Context, id uint64) (*storage.Event, error) {
 	const op = "storage.postgres.events.GetEvent"
 
-	var index Index
-	err := s.driver.QueryRowContext(ctx, getIndex, &id).Scan(&index.EventId, &index.FeatureId)
+	rows, err := s.reader(ctx).QueryContext(ctx, getEventById, id)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	var event storage.Event
-	err = s.driver.QueryRowContext(ctx, getEvent, index.EventId).Scan(
-		&event.Id, &event.Price, &event.Restrictions, &event.Date,
-		&event.City, &event.Address, &event.Name,
-		&event.ImgPath, &event.Description,
-	)
+	events, err := scanListedEvents(rows, nil)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-
-	features := make([]string, 0, len(index.FeatureId))
-	for i, _ := range index.FeatureId {
-		//var idd int64
-		//err = index.FeatureId
-		//if err != nil {
-		//	slog.Error("couldn't scan id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-		//	continue
-		//}
-		ftr, ok := idToFeature[index.FeatureId[i]]
-		if !ok {
-			continue
-		}
-		features = append(features, ftr)
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
 	}
-	event.Feature = features
 
-	return &event, nil
+	return &events[0], nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanEvent -- scans a row selected with eventColumns to the event.
+func scanEvent(row scanner, event *storage.Event) error {
+	return row.Scan(&event.Id, &event.Price, &event.Restrictions, &event.Date,
+		&event.City, &event.Address, &event.Name,
+		&event.ImgPath, &event.Description, &event.SeriesId,
+		&event.Status, &event.Owner, &event.Version,
+	)
 }
 
 func (s *Storage) CreateEvent(ctx context.Context, event *storage.Event) (uint64, error) {
 	const op = "storage.postgres.events.CreateEvent"
 
 	var id uint64
-	err := s.driver.QueryRowContext(ctx, createEvent, &event.Price,
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		id, err = createEventWith(ctx, tx, event)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// createEventWith -- inserts event, its features index and storage.SubjectEventCreated outbox message within
+// the transaction and returns the event id.
+func createEventWith(ctx context.Context, q *sql.Tx, event *storage.Event) (uint64, error) {
+	if event.Status == "" {
+		event.Status = storage.StatusPublished
+	}
+
+	var id uint64
+	err := q.QueryRowContext(ctx, createEvent, &event.Price,
 		&event.Restrictions, &event.Date, &event.City,
-		&event.Address, &event.Name, &event.ImgPath, &event.Description,
+		&event.Address, &event.Name, &event.ImgPath, &event.Description, &event.SeriesId,
+		&event.Status, &event.Owner,
 	).Scan(&id)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, err
 	}
 
-	var features = make([]int, 0, 2)
 	if event.Feature != nil {
 		slices.SortFunc(event.Feature, compareStrings.CmpStr)
-		for _, val := range event.Feature {
-			featureId, ok := featuresToId[val]
-			if ok {
-				features = append(features, featureId)
-			}
+	}
+	if _, err = q.ExecContext(ctx, createIndex, &id, pq.Array(featureIds(event.Feature))); err != nil {
+		return 0, err
+	}
+
+	if event.Status == storage.StatusPendingReview {
+		decision := storage.Decision{EventId: id, Actor: event.Owner, Decision: storage.DecisionSubmitted}
+		if err = q.QueryRowContext(ctx, createDecision, decision.EventId, decision.Actor, decision.Decision, nil).
+			Scan(&decision.Id, &decision.CreatedAt); err != nil {
+			return 0, err
 		}
 	}
 
-	var indId uint64
-	if err = s.driver.QueryRowContext(ctx, createIndex, &id, pq.Array(features)).Scan(&indId); err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+	if err = enqueueEvents(ctx, q, storage.SubjectEventCreated, storage.OperationCreated, []int64{int64(id)}); err != nil {
+		return 0, err
 	}
 
-	return indId, nil
+	return id, nil
 }
 
-func (s *Storage) GetEventsByFeature(ctx context.Context, features []string) ([]storage.Event, error) {
+// featureIds -- returns ids of the known features in the order they are given.
+func featureIds(features []string) []int {
+	var ids = make([]int, 0, 2)
+	for _, val := range features {
+		if featureId, ok := featuresToId[val]; ok {
+			ids = append(ids, featureId)
+		}
+	}
+	return ids
+}
+
+// defaultListLimit -- is the number of events listed when no features are requested.
+const defaultListLimit = 30
+
+func (s *Storage) GetEventsByFeature(ctx context.Context, filter *storage.Filter) ([]storage.Event, error) {
 	const op = "storage.postgres.events.GetEventsByFeature"
 
-	var rows *sql.Rows
-	if features != nil {
-		var ids = make([]int, 0, len(features))
-		for i, _ := range features {
-			id, ok := featuresToId[features[i]]
-			if ok {
-				ids = append(ids, id)
+	var ids []int64
+	var limit = sql.NullInt64{Int64: defaultListLimit, Valid: true}
+	if filter.Features != nil {
+		ids = make([]int64, 0, len(filter.Features))
+		for i := range filter.Features {
+			if id, ok := featuresToId[filter.Features[i]]; ok {
+				ids = append(ids, int64(id))
 			}
 		}
 		if len(ids) == 0 {
-			ids = []int{1, 2, 3, 4}
-		}
-		var err error
-		rows, err = s.driver.QueryContext(ctx, getIndexesByFeature, pq.Array(ids))
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+			ids = []int64{1, 2, 3, 4}
 		}
+		// index stores features sorted, see createEventWith
+		slices.Sort(ids)
+		limit.Valid = false
+	}
 
-	} else {
-		var err error
-		rows, err = s.driver.QueryContext(ctx, getAllIndexes)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+	rows, err := s.reader(ctx).QueryContext(ctx, getEventsByFeature, pq.Array(ids), pq.Array(filter.ListedStatuses()), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-		}
+	events, err := scanListedEvents(rows, filter.Allows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	storage.SortEvents(events, filter)
+
+	return events, nil
+}
+
+// scanListedEvents -- scans rows selected with listedEventColumns, keeping the events allowed by keep, and closes rows.
+func scanListedEvents(rows *sql.Rows, keep func(event *storage.Event) bool) ([]storage.Event, error) {
+	defer rows.Close()
+
 	var events []storage.Event
-	var wg sync.WaitGroup
 	for rows.Next() {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			var index Index
-			err := rows.Scan(&index.Id, &index.EventId, &index.FeatureId)
-			if err != nil {
-				slog.Error("couldn't scan index row", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-				return
-			}
+		var event storage.Event
+		var features pq.Int64Array
+		var ratings []byte
+		err := rows.Scan(&event.Id, &event.Price, &event.Restrictions, &event.Date,
+			&event.City, &event.Address, &event.Name,
+			&event.ImgPath, &event.Description, &event.SeriesId,
+			&event.Status, &event.Owner, &event.Version,
+			&features, &event.Rating, &event.ReviewsCount, &ratings,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if keep != nil && !keep(&event) {
+			continue
+		}
 
-			var event storage.Event
-			if err := s.driver.QueryRow(getEvent, index.Id).Scan(&event.Id, &event.Price,
-				&event.Restrictions, &event.Date, &event.City, &event.Address, &event.Name,
-				&event.ImgPath, &event.Description,
-			); err != nil {
-				slog.Error("couldn't get event by feature", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
-				return
-			}
-			events = append(events, event)
-		}()
-		wg.Wait()
+		event.Feature = featureNames(features)
+		if err = json.Unmarshal(ratings, &event.Ratings); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
 	}
+	return events, rows.Err()
+}
 
-	return events, nil
+// featureNames -- maps features' ids to their names skipping unknown ones.
+func featureNames(ids []int64) []string {
+	features := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if ftr, ok := idToFeature[id]; ok {
+			features = append(features, ftr)
+		}
+	}
+	return features
 }
 
+// DeleteEvent -- deletes the event, its features index and everything referencing it.
 func (s *Storage) DeleteEvent(ctx context.Context, id uint64) error {
 	const op = "storage.postgres.events.DeleteEvent"
 
-	if _, err := s.driver.ExecContext(ctx, deleteEvent, id); err != nil {
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkVersion(ctx, tx, id, 0); err != nil {
+			return err
+		}
+		events, err := getEventsWith(ctx, tx, []int64{int64(id)})
+		if err != nil {
+			return err
+		}
+		if err = recordHistory(ctx, tx, storage.OperationDeleted, events); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, deleteEvent, id); err != nil {
+			return err
+		}
+		return enqueue(ctx, tx, storage.SubjectEventDeleted, storage.EventDeletion{Id: id})
+	})
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	return nil
 }
 
-func (s *Storage) PatchEvent(ctx context.Context, event *storage.Event) error {
+// PatchEvent -- applies the patch and returns the new version of the event. storage.ErrVersionConflict is returned
+// if patch.Version is set and the event has another one.
+func (s *Storage) PatchEvent(ctx context.Context, patch *storage.EventPatch) (uint64, error) {
 	const op = "storage.postgres.events.PatchEvent"
 
-	_, err := s.driver.ExecContext(ctx, patchEvent, &event.Price,
-		&event.Restrictions, &event.Date, &event.Feature, &event.City,
-		&event.Address, &event.Name, &event.Description, &event.Id)
+	var version uint64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkVersion(ctx, tx, patch.Id, patch.Version); err != nil {
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, patchEvent, patch.Price, patch.Restrictions, patch.Date, patch.City,
+			patch.Address, patch.Name, patch.Description, patch.Id).Scan(&version); err != nil {
+			return err
+		}
+		return enqueueEvents(ctx, tx, storage.SubjectEventUpdated, storage.OperationUpdated, []int64{int64(patch.Id)})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return version, nil
+}
+
+// checkVersion -- locks the event until the end of the transaction and checks that it has the version, any one
+// matches zero version.
+func checkVersion(ctx context.Context, tx *sql.Tx, id, version uint64) error {
+	var current uint64
+	if err := tx.QueryRowContext(ctx, lockEventVersion, id).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+		return err
+	}
+	if version != 0 && version != current {
+		return storage.ErrVersionConflict
+	}
+	return nil
+}
+
+// SetEventStatus -- moves event to the status. storage.ErrInvalidTransition is returned if event's current status
+// doesn't allow it or there is no such event.
+func (s *Storage) SetEventStatus(ctx context.Context, id uint64, status string) error {
+	const op = "storage.postgres.events.SetEventStatus"
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		ids, err := collectIds(tx.QueryContext(ctx, setEventStatus, status, id,
+			pq.Array(storage.TransitionsTo(status))))
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return storage.ErrInvalidTransition
+		}
+		return enqueueEvents(ctx, tx, storage.SubjectEventUpdated, storage.OperationStatusChanged, ids)
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	return nil
 }
+
+// ArchivePastEvents -- archives every event which date is before the given time and returns the number of them.
+func (s *Storage) ArchivePastEvents(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.postgres.events.ArchivePastEvents"
+
+	var archived int64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		ids, err := collectIds(tx.QueryContext(ctx, archiveEvents,
+			pq.Array(storage.TransitionsTo(storage.StatusArchived)), before))
+		if err != nil {
+			return err
+		}
+		archived = int64(len(ids))
+		return enqueueEvents(ctx, tx, storage.SubjectEventUpdated, storage.OperationStatusChanged, ids)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return archived, nil
+}
+
+func (s *Storage) GetBookers(ctx context.Context, id uint64) ([]string, error) {
+	const op = "storage.postgres.events.GetBookers"
+
+	rows, err := s.reader(ctx).QueryContext(ctx, getBookers, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var bookers = make([]string, 0, 4)
+	for rows.Next() {
+		var username string
+		if err = rows.Scan(&username); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		bookers = append(bookers, username)
+	}
+	return bookers, rows.Err()
+}
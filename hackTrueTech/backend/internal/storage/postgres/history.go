@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+)
+
+// GetEventHistory -- returns every change of the event ordered by the time it was made, deleted events included.
+func (s *Storage) GetEventHistory(ctx context.Context, id uint64) ([]storage.HistoryRecord, error) {
+	const op = "storage.postgres.history.GetEventHistory"
+
+	rows, err := s.reader(ctx).QueryContext(ctx, getEventHistory, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var records = make([]storage.HistoryRecord, 0, 4)
+	for rows.Next() {
+		var record storage.HistoryRecord
+		var event []byte
+		if err = rows.Scan(&record.Id, &record.EventId, &record.Version, &record.Operation, &record.Actor,
+			&event, &record.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err = json.Unmarshal(event, &record.Event); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		records = append(records, record)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return records, nil
+}
+
+// RestoreEvent -- brings the event back to the state it had at the version, recreating it if it has been deleted,
+// and returns the new version. Bookings, favorites and reviews of a deleted event are gone for good.
+func (s *Storage) RestoreEvent(ctx context.Context, id, version uint64) (uint64, error) {
+	const op = "storage.postgres.history.RestoreEvent"
+
+	var restored uint64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var data []byte
+		if err := tx.QueryRowContext(ctx, getHistoricalEvent, id, version).Scan(&data); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return storage.ErrNotFound
+			}
+			return err
+		}
+		var event storage.Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+
+		subject := storage.SubjectEventUpdated
+		if err := checkVersion(ctx, tx, id, 0); errors.Is(err, storage.ErrNotFound) {
+			subject = storage.SubjectEventCreated
+		} else if err != nil {
+			return err
+		}
+
+		var last uint64
+		if err := tx.QueryRowContext(ctx, getLastHistoryVersion, id).Scan(&last); err != nil {
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, restoreEvent, id, event.Price, event.Restrictions, event.Date,
+			event.City, event.Address, event.Name, event.ImgPath, event.Description, event.SeriesId,
+			event.Status, event.Owner, last+1).Scan(&restored); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, restoreIndex, id, pq.Array(featureIds(event.Feature))); err != nil {
+			return err
+		}
+		return enqueueEvents(ctx, tx, subject, storage.OperationRestored, []int64{int64(id)})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return restored, nil
+}
+
+// recordHistory -- appends the state of events to their history within the transaction, on behalf of the actor
+// ctx is marked with.
+func recordHistory(ctx context.Context, tx *sql.Tx, operation string, events []storage.Event) error {
+	for i := range events {
+		data, err := json.Marshal(storage.Snapshot(events[i]))
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, createHistoryRecord, events[i].Id, events[i].Version, operation,
+			storage.ActorOf(ctx), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"slices"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLock -- is the key of the advisory lock held while migrations run, so concurrently started instances
+// don't apply the same migration twice.
+const migrationLock = 8_452_617_301
+
+// Migrations -- returns every embedded migration ordered by version.
+func Migrations() ([]storage.Migration, error) {
+	return storage.ParseMigrations(migrationsFS, "migrations")
+}
+
+// MigrateUp -- applies every pending migration, each one in its own transaction. A database initialized by
+// the former db/init.sql is detected and marked as migrated to version 1 without running it.
+func (s *Storage) MigrateUp(ctx context.Context) error {
+	const op = "storage.postgres.migrate.MigrateUp"
+
+	return s.withMigrationLock(ctx, func(conn *sql.Conn, applied []int64) error {
+		migrations, err := Migrations()
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if len(applied) == 0 {
+			var initialized bool
+			if err = conn.QueryRowContext(ctx, isInitialized).Scan(&initialized); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			if initialized && len(migrations) != 0 {
+				if _, err = conn.ExecContext(ctx, addMigration, migrations[0].Version, migrations[0].Name); err != nil {
+					return fmt.Errorf("%s: %w", op, err)
+				}
+				applied = append(applied, migrations[0].Version)
+				slog.Info("existing schema marked as migrated", slogResponse.SlogOp(op),
+					slog.Int64("version", migrations[0].Version))
+			}
+		}
+
+		for _, migration := range migrations {
+			if slices.Contains(applied, migration.Version) {
+				continue
+			}
+			if err = runMigration(ctx, conn, migration.Up, addMigration, migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("%s: %d_%s: %w", op, migration.Version, migration.Name, err)
+			}
+			slog.Info("applied migration", slogResponse.SlogOp(op),
+				slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+		}
+		return nil
+	})
+}
+
+// MigrateDown -- reverts the given number of the latest applied migrations.
+func (s *Storage) MigrateDown(ctx context.Context, steps int) error {
+	const op = "storage.postgres.migrate.MigrateDown"
+
+	return s.withMigrationLock(ctx, func(conn *sql.Conn, applied []int64) error {
+		migrations, err := Migrations()
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for i := len(applied) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+			idx := slices.IndexFunc(migrations, func(m storage.Migration) bool { return m.Version == applied[i] })
+			if idx == -1 {
+				return fmt.Errorf("%s: applied migration %d is unknown to this binary", op, applied[i])
+			}
+			migration := migrations[idx]
+			if migration.Down == "" {
+				return fmt.Errorf("%s: migration %d has no down script", op, migration.Version)
+			}
+			if err = runMigration(ctx, conn, migration.Down, removeMigration, migration.Version); err != nil {
+				return fmt.Errorf("%s: %d_%s: %w", op, migration.Version, migration.Name, err)
+			}
+			slog.Info("reverted migration", slogResponse.SlogOp(op),
+				slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+		}
+		return nil
+	})
+}
+
+// MigrationVersion -- returns the latest applied migration version, 0 if none is applied.
+func (s *Storage) MigrationVersion(ctx context.Context) (int64, error) {
+	const op = "storage.postgres.migrate.MigrationVersion"
+
+	var version int64
+	err := s.withMigrationLock(ctx, func(_ *sql.Conn, applied []int64) error {
+		if len(applied) != 0 {
+			version = applied[len(applied)-1]
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return version, nil
+}
+
+// withMigrationLock -- runs fn on a single connection holding the migration advisory lock, passing it the versions
+// of applied migrations.
+func (s *Storage) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn, applied []int64) error) error {
+	const op = "storage.postgres.migrate.withMigrationLock"
+
+	conn, err := s.driver.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, lockMigrations, migrationLock); err != nil {
+		return fmt.Errorf("%s: lock: %w", op, err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), unlockMigrations, migrationLock); err != nil {
+			slog.Error("couldn't release migration lock", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := conn.QueryContext(ctx, getAppliedMigrations)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	var applied []int64
+	for rows.Next() {
+		var version int64
+		if err = rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		applied = append(applied, version)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fn(conn, applied)
+}
+
+// runMigration -- executes script and the bookkeeping query in one transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, script string, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.Error("couldn't rollback migration", slogResponse.SlogErr(err))
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS public.cache;
+DROP TABLE IF EXISTS public.index;
+DROP TABLE IF EXISTS public.features;
+DROP TABLE IF EXISTS public.events;
+DROP TABLE IF EXISTS public.auth;
@@ -44,4 +44,4 @@ CREATE TABLE public.index(
 CREATE TABLE public.cache
 (
     id BIGINT CHECK (id > 0) PRIMARY KEY
-);
\ No newline at end of file
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.review_ratings;
+DROP TABLE IF EXISTS public.reviews;
+DROP TABLE IF EXISTS public.bookings;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS public.bookings(
+    id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL CHECK (event_id > 0),
+    username VARCHAR(64) NOT NULL,
+    checked_in BOOLEAN DEFAULT false,
+    created_at timestamptz DEFAULT now(),
+    UNIQUE (event_id, username)
+);
+
+CREATE TABLE IF NOT EXISTS public.reviews(
+    id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL CHECK (event_id > 0),
+    username VARCHAR(64) NOT NULL,
+    comment VARCHAR(2048),
+    hidden BOOLEAN DEFAULT false,
+    created_at timestamptz DEFAULT now(),
+    UNIQUE (event_id, username)
+);
+
+CREATE INDEX IF NOT EXISTS reviews_event_id_idx ON public.reviews(event_id);
+
+CREATE TABLE IF NOT EXISTS public.review_ratings(
+    review_id BIGINT REFERENCES public.reviews(id) ON DELETE CASCADE,
+    aspect VARCHAR(64) NOT NULL,
+    score SMALLINT CHECK (score >= 1 AND score <= 5),
+    PRIMARY KEY (review_id, aspect)
+);
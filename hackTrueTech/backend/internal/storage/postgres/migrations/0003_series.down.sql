@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS public.events_series_id_idx;
+
+ALTER TABLE public.events DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS public.series;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS public.series(
+    id BIGSERIAL CHECK (id > 0) PRIMARY KEY,
+    recurrence VARCHAR(256) NOT NULL,
+    start timestamptz NOT NULL,
+    exceptions timestamptz[]
+);
+
+ALTER TABLE public.events ADD COLUMN IF NOT EXISTS series_id BIGINT REFERENCES public.series(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS events_series_id_idx ON public.events(series_id);
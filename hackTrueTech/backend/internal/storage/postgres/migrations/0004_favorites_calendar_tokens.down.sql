@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.calendar_tokens;
+DROP TABLE IF EXISTS public.favorites;
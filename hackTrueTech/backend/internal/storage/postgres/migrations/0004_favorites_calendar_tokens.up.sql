@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS public.favorites(
+    event_id BIGINT NOT NULL CHECK (event_id > 0),
+    username VARCHAR(64) NOT NULL,
+    created_at timestamptz DEFAULT now(),
+    PRIMARY KEY (event_id, username)
+);
+
+CREATE TABLE IF NOT EXISTS public.calendar_tokens(
+    username VARCHAR(64) PRIMARY KEY,
+    token VARCHAR(64) NOT NULL UNIQUE
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS public.events_status_date_idx;
+
+ALTER TABLE public.events
+    DROP COLUMN IF EXISTS owner,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,7 @@
+-- Events created before statuses were introduced are published ones.
+ALTER TABLE public.events
+    ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'published'
+        CONSTRAINT events_status_check CHECK (status IN ('draft', 'published', 'cancelled', 'archived')),
+    ADD COLUMN IF NOT EXISTS owner VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS events_status_date_idx ON public.events(status, date);
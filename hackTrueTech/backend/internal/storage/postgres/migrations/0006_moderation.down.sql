@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS public.moderation_decisions;
+
+-- Events waiting for moderation or rejected by it go back to drafts of their owners.
+UPDATE public.events SET status = 'draft' WHERE status IN ('pending_review', 'rejected');
+
+ALTER TABLE public.events
+    DROP CONSTRAINT IF EXISTS events_status_check,
+    ADD CONSTRAINT events_status_check CHECK (status IN ('draft', 'published', 'cancelled', 'archived'));
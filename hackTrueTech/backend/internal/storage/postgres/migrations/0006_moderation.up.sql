@@ -0,0 +1,15 @@
+ALTER TABLE public.events
+    DROP CONSTRAINT IF EXISTS events_status_check,
+    ADD CONSTRAINT events_status_check
+        CHECK (status IN ('draft', 'pending_review', 'rejected', 'published', 'cancelled', 'archived'));
+
+CREATE TABLE IF NOT EXISTS public.moderation_decisions(
+    id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL CHECK (event_id > 0),
+    actor VARCHAR(64) NOT NULL,
+    decision VARCHAR(16) NOT NULL CHECK (decision IN ('submitted', 'approved', 'rejected')),
+    reason VARCHAR(1024),
+    created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS moderation_decisions_event_id_idx ON public.moderation_decisions(event_id);
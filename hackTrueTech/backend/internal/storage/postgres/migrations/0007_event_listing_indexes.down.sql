@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public.index_features_idx;
+
+DROP INDEX IF EXISTS public.index_event_id_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX index_event_id_idx ON public.index(event_id);
+
+CREATE INDEX index_features_idx ON public.index(features);
@@ -0,0 +1,16 @@
+ALTER TABLE public.moderation_decisions DROP CONSTRAINT IF EXISTS moderation_decisions_event_id_fkey;
+
+ALTER TABLE public.reviews DROP CONSTRAINT IF EXISTS reviews_event_id_fkey;
+
+ALTER TABLE public.favorites DROP CONSTRAINT IF EXISTS favorites_event_id_fkey;
+
+ALTER TABLE public.bookings DROP CONSTRAINT IF EXISTS bookings_event_id_fkey;
+
+ALTER TABLE public.cache DROP CONSTRAINT IF EXISTS cache_id_fkey;
+
+ALTER TABLE public.index
+    DROP CONSTRAINT IF EXISTS index_event_id_fkey,
+    DROP CONSTRAINT IF EXISTS index_event_id_key,
+    ALTER COLUMN event_id DROP NOT NULL;
+
+CREATE INDEX index_event_id_idx ON public.index(event_id);
@@ -0,0 +1,41 @@
+-- Event ids used to be index ids in some endpoints, events.id is the only identifier from now on:
+-- every event gets exactly one index row, rows of deleted events are dropped and referenced with cascading keys.
+
+DELETE FROM public.index i WHERE NOT EXISTS (SELECT 1 FROM public.events e WHERE e.id = i.event_id);
+
+DELETE FROM public.index i USING public.index newer WHERE newer.event_id = i.event_id AND newer.id > i.id;
+
+INSERT INTO public.index(event_id, features)
+    SELECT e.id, '{}' FROM public.events e WHERE NOT EXISTS (SELECT 1 FROM public.index i WHERE i.event_id = e.id);
+
+DROP INDEX IF EXISTS public.index_event_id_idx;
+
+ALTER TABLE public.index
+    ALTER COLUMN event_id SET NOT NULL,
+    ADD CONSTRAINT index_event_id_key UNIQUE (event_id),
+    ADD CONSTRAINT index_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+DELETE FROM public.cache c WHERE NOT EXISTS (SELECT 1 FROM public.events e WHERE e.id = c.id);
+
+ALTER TABLE public.cache
+    ADD CONSTRAINT cache_id_fkey FOREIGN KEY (id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+DELETE FROM public.bookings b WHERE NOT EXISTS (SELECT 1 FROM public.events e WHERE e.id = b.event_id);
+
+ALTER TABLE public.bookings
+    ADD CONSTRAINT bookings_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+DELETE FROM public.favorites f WHERE NOT EXISTS (SELECT 1 FROM public.events e WHERE e.id = f.event_id);
+
+ALTER TABLE public.favorites
+    ADD CONSTRAINT favorites_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+DELETE FROM public.reviews r WHERE NOT EXISTS (SELECT 1 FROM public.events e WHERE e.id = r.event_id);
+
+ALTER TABLE public.reviews
+    ADD CONSTRAINT reviews_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
+
+DELETE FROM public.moderation_decisions d WHERE NOT EXISTS (SELECT 1 FROM public.events e WHERE e.id = d.event_id);
+
+ALTER TABLE public.moderation_decisions
+    ADD CONSTRAINT moderation_decisions_event_id_fkey FOREIGN KEY (event_id) REFERENCES public.events(id) ON DELETE CASCADE;
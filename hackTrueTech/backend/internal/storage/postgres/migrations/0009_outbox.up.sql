@@ -0,0 +1,9 @@
+CREATE TABLE public.outbox(
+    id BIGSERIAL PRIMARY KEY,
+    subject VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at timestamptz DEFAULT now(),
+    published_at timestamptz
+);
+
+CREATE INDEX outbox_pending_idx ON public.outbox(id) WHERE published_at IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE public.events ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
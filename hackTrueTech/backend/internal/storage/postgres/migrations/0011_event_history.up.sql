@@ -0,0 +1,35 @@
+CREATE TABLE public.event_history(
+    id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL CHECK (event_id > 0),
+    version BIGINT NOT NULL,
+    operation VARCHAR(16) NOT NULL
+        CHECK (operation IN ('created', 'updated', 'status_changed', 'deleted', 'restored')),
+    actor VARCHAR(64),
+    event JSONB NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX event_history_event_id_idx ON public.event_history(event_id, id);
+
+-- History outlives events, so there is no foreign key. Events created before it start their history
+-- with their current state.
+INSERT INTO public.event_history(event_id, version, operation, event)
+    SELECT e.id, e.version, 'created', json_build_object(
+        'id', e.id,
+        'price', COALESCE(e.price, 0),
+        'restrictions', COALESCE(e.restrictions, 0),
+        'date', COALESCE(e.date, '0001-01-01T00:00:00Z'),
+        'feature', COALESCE((SELECT array_agg(f.tag ORDER BY f.tag) FROM public.features f
+                                WHERE f.id = ANY(i.features)), '{}'),
+        'city', e.city,
+        'address', e.address,
+        'name', e.name,
+        'img_path', COALESCE(e.img_path, ''),
+        'description', COALESCE(e.description, ''),
+        'series_id', COALESCE(e.series_id, 0),
+        'status', e.status,
+        'owner', COALESCE(e.owner, ''),
+        'version', e.version
+    )
+    FROM public.events e LEFT JOIN public.index i ON i.event_id = e.id
+    ORDER BY e.id;
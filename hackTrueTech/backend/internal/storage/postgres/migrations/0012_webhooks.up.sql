@@ -0,0 +1,26 @@
+CREATE TABLE public.webhooks(
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    subjects TEXT[] NOT NULL DEFAULT '{}',
+    owner VARCHAR(64),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE public.webhook_deliveries(
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id BIGINT NOT NULL REFERENCES public.webhooks(id) ON DELETE CASCADE,
+    event_key VARCHAR(64) NOT NULL,
+    subject VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    response_code INT,
+    error TEXT,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (webhook_id, event_key)
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON public.webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_webhook_id_idx ON public.webhook_deliveries(webhook_id, id);
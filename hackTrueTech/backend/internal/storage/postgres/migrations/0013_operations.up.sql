@@ -0,0 +1,14 @@
+CREATE TABLE public.operations(
+    id VARCHAR(36) PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    event_id BIGINT NOT NULL,
+    actor VARCHAR(64),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    code INT,
+    message TEXT,
+    result JSONB,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    finished_at timestamptz
+);
+
+CREATE INDEX operations_created_at_idx ON public.operations(created_at);
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+)
+
+// GetModerationQueue -- returns events waiting for review in the order they were created.
+func (s *Storage) GetModerationQueue(ctx context.Context) ([]storage.Event, error) {
+	const op = "storage.postgres.moderation.GetModerationQueue"
+
+	rows, err := s.reader(ctx).QueryContext(ctx, getModerationQueue)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events = make([]storage.Event, 0, 4)
+	for rows.Next() {
+		var event storage.Event
+		if err = scanEvent(rows, &event); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// Moderate -- moves the event and the rest of its series occurrences in the same status to the status of
+// the decision and records the decision. storage.ErrInvalidTransition is returned if the event's status doesn't
+// allow the decision.
+func (s *Storage) Moderate(ctx context.Context, decision *storage.Decision) error {
+	const op = "storage.postgres.moderation.Moderate"
+
+	status := decision.Status()
+	if status == "" {
+		return fmt.Errorf("%s: unknown decision %q", op, decision.Decision)
+	}
+
+	tx, err := s.driver.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.Error("couldn't rollback transaction", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		}
+	}()
+
+	var current string
+	if err = tx.QueryRowContext(ctx, lockEventStatus, decision.EventId).
+		Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !storage.CanTransition(current, status) {
+		return fmt.Errorf("%s: %w", op, storage.ErrInvalidTransition)
+	}
+
+	ids, err := collectIds(tx.QueryContext(ctx, moderateEvents, status, decision.EventId, pq.Array([]string{current})))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = enqueueEvents(storage.WithActor(ctx, decision.Actor), tx, storage.SubjectEventUpdated,
+		storage.OperationStatusChanged, ids); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = tx.QueryRowContext(ctx, createDecision, decision.EventId, decision.Actor, decision.Decision,
+		sql.NullString{String: decision.Reason, Valid: decision.Reason != ""}).
+		Scan(&decision.Id, &decision.CreatedAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) GetDecisions(ctx context.Context, eventId uint64) ([]storage.Decision, error) {
+	const op = "storage.postgres.moderation.GetDecisions"
+
+	rows, err := s.reader(ctx).QueryContext(ctx, getDecisions, eventId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var decisions = make([]storage.Decision, 0, 2)
+	for rows.Next() {
+		var decision storage.Decision
+		if err = rows.Scan(&decision.Id, &decision.EventId, &decision.Actor, &decision.Decision,
+			&decision.Reason, &decision.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		decisions = append(decisions, decision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return decisions, nil
+}
@@ -1,44 +1,174 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"github.com/lib/pq"
 	_ "github.com/lib/pq"
 	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Storage -- writes to the primary and reads from replicas if any are configured. Replicas are health-checked in
+// the background and read from in round-robin, the primary serves reads if none is healthy or ctx is marked with
+// storage.WithReadYourWrites.
 type Storage struct {
-	driver *sql.DB
+	driver   *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
-type Index struct {
-	Id        uint64
-	EventId   uint64
-	FeatureId pq.Int64Array
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
 }
 
+// replicaPingTimeout -- is how long a replica health check waits for the reply.
+const replicaPingTimeout = 2 * time.Second
+
 func New(config *config.Database) (*Storage, error) {
 	const op = "storage.postgres.New"
 
-	connStr := fmt.Sprintf("postgres://%s:%s@postgres:%s/%s?sslmode=%s", config.DbUser, config.DbPass, config.Port, config.DbName, config.SslMode)
+	if config.DSN == "" && (config.DbUser == "" || config.DbName == "") {
+		return nil, fmt.Errorf("%s: %w", op, errors.New("db_user and db_name are required unless dsn is set"))
+	}
 
-	db, err := sql.Open("postgres", connStr)
+	db, err := open(config.ConnString(), config)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err = db.Ping(); err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	s := &Storage{driver: db, stop: make(chan struct{})}
+	for _, dsn := range config.Replicas {
+		replicaDb, err := sql.Open("postgres", dsn)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("%s: replica: %w", op, err)
+		}
+		replicaDb.SetMaxOpenConns(config.MaxOpenConns)
+		replicaDb.SetMaxIdleConns(config.MaxIdleConns)
+		replicaDb.SetConnMaxLifetime(config.ConnMaxLifetime)
+		s.replicas = append(s.replicas, &replica{db: replicaDb})
+	}
+
+	if len(s.replicas) != 0 {
+		s.checkReplicas()
+		s.wg.Add(1)
+		go s.watchReplicas(config.ReplicaCheckInterval)
+	}
+
+	return s, nil
+}
+
+// open -- opens the pool configured by config and pings it, retrying with exponential backoff while it's unavailable.
+func open(connStr string, config *config.Database) (*sql.DB, error) {
+	const op = "storage.postgres.open"
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, err
 	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
 
-	return &Storage{driver: db}, nil
+	backoff := config.ConnectBackoff
+	for attempt := 0; ; attempt++ {
+		if err = db.Ping(); err == nil {
+			return db, nil
+		}
+		if attempt >= config.ConnectRetries {
+			break
+		}
+
+		slog.Warn("storage is unavailable, retrying", slogResponse.SlogOp(op), slogResponse.SlogErr(err),
+			slog.Int("attempt", attempt+1), slog.Duration("backoff", backoff))
+		time.Sleep(backoff)
+		backoff = min(backoff*2, config.MaxConnectBackoff)
+	}
+
+	if closeErr := db.Close(); closeErr != nil {
+		slog.Error("couldn't close storage", slogResponse.SlogOp(op), slogResponse.SlogErr(closeErr))
+	}
+	return nil, err
+}
+
+// reader -- returns the pool a read made with ctx goes to.
+func (s *Storage) reader(ctx context.Context) *sql.DB {
+	if len(s.replicas) == 0 || storage.ReadsYourWrites(ctx) {
+		return s.driver
+	}
+
+	start := s.next.Add(1)
+	for i := range s.replicas {
+		r := s.replicas[(start+uint64(i))%uint64(len(s.replicas))]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return s.driver
+}
+
+// watchReplicas -- checks replicas every interval until the storage is closed.
+func (s *Storage) watchReplicas(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.checkReplicas()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// checkReplicas -- pings every replica and marks it healthy if it replies in time.
+func (s *Storage) checkReplicas() {
+	const op = "storage.postgres.checkReplicas"
+
+	for i, r := range s.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), replicaPingTimeout)
+		err := r.db.PingContext(ctx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				slog.Info("replica is healthy", slogResponse.SlogOp(op), slog.Int("replica", i))
+			} else {
+				slog.Warn("replica is unhealthy", slogResponse.SlogOp(op), slog.Int("replica", i),
+					slogResponse.SlogErr(err))
+			}
+		}
+	}
 }
 
 func (s *Storage) Close() error {
-	return s.driver.Close()
+	if s.stop != nil {
+		close(s.stop)
+		s.wg.Wait()
+	}
+
+	errs := make([]error, 0, len(s.replicas)+1)
+	for _, r := range s.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	errs = append(errs, s.driver.Close())
+	return errors.Join(errs...)
 }
 
-func (s *Storage) Ping() error {
-	return s.driver.Ping()
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.driver.PingContext(ctx)
 }
@@ -0,0 +1,341 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/conformance"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// testDSNEnv -- names the environment variable with the DSN of a disposable database the conformance suite runs
+// against. Its public schema is dropped before every test, the suite is skipped without it.
+const testDSNEnv = "POSTGRES_TEST_DSN"
+
+const resetSchema = `DROP SCHEMA public CASCADE; CREATE SCHEMA public`
+
+// testDB -- returns the test database with an empty public schema, skips the test without one.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err = db.ExecContext(context.Background(), resetSchema); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) conformance.Storage {
+		s := &Storage{driver: testDB(t)}
+		if err := s.MigrateUp(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+// schemaQueries -- describe the public schema: columns, constraints and indexes, each row as a single string.
+var schemaQueries = []string{
+	`SELECT table_name || '.' || column_name || ' ' || data_type || ' ' || is_nullable || ' ' ||
+		COALESCE(column_default, '') FROM information_schema.columns WHERE table_schema = 'public'`,
+	`SELECT conrelid::regclass || ' ' || conname || ' ' || pg_get_constraintdef(oid) FROM pg_constraint
+		WHERE connamespace = 'public'::regnamespace`,
+	`SELECT indexdef FROM pg_indexes WHERE schemaname = 'public'`,
+}
+
+// schema -- returns the sorted description of the public schema of db, migration bookkeeping aside.
+func schema(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	var rows []string
+	for _, query := range schemaQueries {
+		res, err := db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for res.Next() {
+			var row string
+			if err = res.Scan(&row); err != nil {
+				t.Fatal(err)
+			}
+			rows = append(rows, row)
+		}
+		if err = res.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows = slices.DeleteFunc(rows, func(row string) bool {
+		return strings.Contains(row, "schema_migrations")
+	})
+	slices.Sort(rows)
+	return rows
+}
+
+// migratedSchema -- returns the schema every migration creates from scratch.
+func migratedSchema(t *testing.T) []string {
+	t.Helper()
+
+	db := testDB(t)
+	if err := (&Storage{driver: db}).MigrateUp(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return schema(t, db)
+}
+
+// checkMigrated -- fails the test unless every migration is applied to db and its schema is want.
+func checkMigrated(t *testing.T, db *sql.DB, want []string) {
+	t.Helper()
+
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query(`SELECT version, name FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var applied []storage.Migration
+	for rows.Next() {
+		var migration storage.Migration
+		if err = rows.Scan(&migration.Version, &migration.Name); err != nil {
+			t.Fatal(err)
+		}
+		applied = append(applied, migration)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("%d migrations applied, want %d: %v", len(applied), len(migrations), applied)
+	}
+	for i := range applied {
+		if applied[i].Version != migrations[i].Version || applied[i].Name != migrations[i].Name {
+			t.Errorf("applied %d_%s, want %d_%s", applied[i].Version, applied[i].Name,
+				migrations[i].Version, migrations[i].Name)
+		}
+	}
+
+	got := schema(t, db)
+	for _, row := range want {
+		if !slices.Contains(got, row) {
+			t.Errorf("missing %s", row)
+		}
+	}
+	for _, row := range got {
+		if !slices.Contains(want, row) {
+			t.Errorf("unexpected %s", row)
+		}
+	}
+}
+
+func TestMigrateUp_Baseline(t *testing.T) {
+	want := migratedSchema(t)
+
+	db := testDB(t)
+	ctx := context.Background()
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The database is initialized by the former db/init.sql and has an event already.
+	if _, err = db.ExecContext(ctx, migrations[0].Up); err != nil {
+		t.Fatal(err)
+	}
+	var id uint64
+	if err = db.QueryRowContext(ctx, `INSERT INTO events(price, restrictions, date, city, address, name)
+		VALUES (100, 12, now(), 'Москва', 'Тверская, 1', 'Лекция') RETURNING id`).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.ExecContext(ctx, `INSERT INTO index(event_id, features) VALUES ($1, '{1}')`, id); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Storage{driver: db}
+	if err = s.MigrateUp(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, db, want)
+
+	event, err := s.GetEvent(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Status != storage.StatusPublished || event.SeriesId != 0 || event.Version != 1 ||
+		!slices.Equal(event.Feature, []string{"blind"}) {
+		t.Errorf("event after migrations = %+v, want published without series at version 1 for the blind", event)
+	}
+	history, err := s.GetEventHistory(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 {
+		t.Errorf("history after migrations = %+v, want the event's current state", history)
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	want := migratedSchema(t)
+
+	db := testDB(t)
+	ctx := context.Background()
+	s := &Storage{driver: db}
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.MigrateUp(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.MigrateDown(ctx, len(migrations)); err != nil {
+		t.Fatal(err)
+	}
+	if got := schema(t, db); len(got) != 0 {
+		t.Errorf("schema after every migration is reverted = %v, want empty", got)
+	}
+	if err = s.MigrateUp(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, db, want)
+}
+
+// fakeConnector -- opens connections which fail every query and answer pings unless the connector is down.
+type fakeConnector struct {
+	down atomic.Bool
+}
+
+var errDown = errors.New("connection refused")
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	if c.down.Load() {
+		return nil, errDown
+	}
+	return fakeConn{c}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	connector *fakeConnector
+}
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c fakeConn) Ping(context.Context) error {
+	if c.connector.down.Load() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// fakeDB -- returns the pool of a fake database and the connector to take it down with.
+func fakeDB(t *testing.T) (*sql.DB, *fakeConnector) {
+	t.Helper()
+
+	connector := &fakeConnector{}
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { db.Close() })
+	return db, connector
+}
+
+func TestStorage_reader(t *testing.T) {
+	primary, _ := fakeDB(t)
+	first, _ := fakeDB(t)
+	second, _ := fakeDB(t)
+	names := map[*sql.DB]string{primary: "primary", first: "first", second: "second"}
+	ryw := storage.WithReadYourWrites(context.Background())
+
+	tests := []struct {
+		name     string
+		replicas []*sql.DB
+		healthy  []bool
+		ctx      context.Context
+		want     []*sql.DB
+	}{
+		{name: "no replicas", ctx: context.Background(), want: []*sql.DB{primary, primary}},
+		{name: "round robin", replicas: []*sql.DB{first, second}, healthy: []bool{true, true},
+			ctx: context.Background(), want: []*sql.DB{second, first, second, first}},
+		{name: "unhealthy skipped", replicas: []*sql.DB{first, second}, healthy: []bool{false, true},
+			ctx: context.Background(), want: []*sql.DB{second, second, second}},
+		{name: "none healthy", replicas: []*sql.DB{first, second}, healthy: []bool{false, false},
+			ctx: context.Background(), want: []*sql.DB{primary, primary}},
+		{name: "read your writes", replicas: []*sql.DB{first, second}, healthy: []bool{true, true}, ctx: ryw,
+			want: []*sql.DB{primary, primary}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Storage{driver: primary}
+			for i, db := range tt.replicas {
+				r := &replica{db: db}
+				r.healthy.Store(tt.healthy[i])
+				s.replicas = append(s.replicas, r)
+			}
+
+			for i, want := range tt.want {
+				if got := s.reader(tt.ctx); got != want {
+					t.Errorf("read %d goes to %s, want %s", i, names[got], names[want])
+				}
+			}
+		})
+	}
+}
+
+func TestStorage_checkReplicas(t *testing.T) {
+	primary, _ := fakeDB(t)
+	up, upConnector := fakeDB(t)
+	down, downConnector := fakeDB(t)
+	downConnector.down.Store(true)
+	s := &Storage{driver: primary, replicas: []*replica{{db: up}, {db: down}}}
+
+	s.checkReplicas()
+	if !s.replicas[0].healthy.Load() || s.replicas[1].healthy.Load() {
+		t.Fatalf("replicas healthy = %v, %v; want true, false", s.replicas[0].healthy.Load(),
+			s.replicas[1].healthy.Load())
+	}
+	if got := s.reader(context.Background()); got != up {
+		t.Error("read doesn't go to the healthy replica")
+	}
+
+	upConnector.down.Store(true)
+	downConnector.down.Store(false)
+	s.checkReplicas()
+	if s.replicas[0].healthy.Load() || !s.replicas[1].healthy.Load() {
+		t.Fatalf("replicas healthy after switch = %v, %v; want false, true", s.replicas[0].healthy.Load(),
+			s.replicas[1].healthy.Load())
+	}
+	if got := s.reader(context.Background()); got != down {
+		t.Error("read doesn't go to the replica back up")
+	}
+
+	downConnector.down.Store(true)
+	s.checkReplicas()
+	if got := s.reader(context.Background()); got != primary {
+		t.Error("read doesn't fall back to the primary with every replica down")
+	}
+}
@@ -8,7 +8,8 @@ const (
 	deleteUser   = "DELETE FROM auth WHERE username = $1"
 
 	//Event
-	getEvent    = "SELECT * FROM events WHERE id = $1"
+	eventColumns = `id, price, restrictions, date, city, address, name, COALESCE(img_path, ''),
+							COALESCE(description, ''), COALESCE(series_id, 0), status, COALESCE(owner, ''), version`
 	createEvent = `INSERT INTO events(
 							price,
 							restrictions,
@@ -17,32 +18,208 @@ const (
                    			address,
 							name,
 							img_path,
-							description
-                   			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id
+							description,
+							series_id,
+							status,
+							owner
+                   			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, 0), $10, NULLIF($11, '')) RETURNING id
 	`
 	changeImgPath = "UPDATE events SET img_path=$1"
 
-	patchEvent = `UPDATE events SET price = $1,
-											restrictions = $2,
-											date = $3,
-											city = $4,
-											address = $5,
-											name = $6,
-											description = $7
-									WHERE id = $8
+	// patchEvent -- sets the fields given, NULL keeps a field as is.
+	patchEvent = `UPDATE events SET price = COALESCE($1, price),
+											restrictions = COALESCE($2, restrictions),
+											date = COALESCE($3, date),
+											city = COALESCE($4, city),
+											address = COALESCE($5, address),
+											name = COALESCE($6, name),
+											description = COALESCE($7, description),
+											version = version + 1
+									WHERE id = $8 RETURNING version
 											`
+	lockEventVersion = "SELECT version FROM events WHERE id = $1 FOR UPDATE"
 
-	deleteEvent = "DELETE FROM events WHERE id = $1"
+	deleteEvent = "DELETE FROM events WHERE id = $1 RETURNING id"
 
-	createIndex = `INSERT INTO index(event_id, features) VALUES ($1, $2) RETURNING id`
+	setEventStatus = `UPDATE events SET status = $1, version = version + 1 WHERE id = $2 AND status = ANY($3)
+							RETURNING id`
+	archiveEvents = `UPDATE events SET status = 'archived', version = version + 1 WHERE status = ANY($1) AND date < $2
+							RETURNING id`
+	getBookers = `SELECT username FROM bookings WHERE event_id = $1`
 
-	getIndex = `SELECT event_id, features FROM index WHERE id = $1`
-	//getFeatures        = `SELECT features FROM idnex WHERE id = $1`
-	getIndexesByFeature = "SELECT * FROM index WHERE features = $1"
-	getAllIndexes       = "SELECT * FROM index LIMIT 30"
+	// Moderation
+	getModerationQueue = "SELECT " + eventColumns + " FROM events WHERE status = 'pending_review' ORDER BY id"
+	lockEventStatus    = "SELECT status FROM events WHERE id = $1 FOR UPDATE"
+	moderateEvents     = `UPDATE events SET status = $1, version = version + 1 WHERE status = ANY($3) AND (id = $2 OR
+							series_id = (SELECT series_id FROM events WHERE id = $2)) RETURNING id`
+	createDecision = `INSERT INTO moderation_decisions(event_id, actor, decision, reason) VALUES ($1, $2, $3, $4)
+							RETURNING id, created_at`
+	getDecisions = `SELECT id, event_id, actor, decision, COALESCE(reason, ''), created_at FROM moderation_decisions
+							WHERE event_id = $1 ORDER BY created_at`
+
+	// Series
+	createSeries = `INSERT INTO series(recurrence, start, exceptions) VALUES ($1, $2, $3) RETURNING id`
+	patchSeries  = `UPDATE events SET price = COALESCE($1, price),
+											restrictions = COALESCE($2, restrictions),
+											city = COALESCE($3, city),
+											address = COALESCE($4, address),
+											name = COALESCE($5, name),
+											description = COALESCE($6, description),
+											version = version + 1
+									WHERE series_id = (SELECT series_id FROM events WHERE id = $7) RETURNING id, version
+											`
+
+	createIndex = `INSERT INTO index(event_id, features) VALUES ($1, $2)`
+
+	// listedEventColumns -- are eventColumns followed by features, rating, reviews count and aspect ratings as JSON,
+	// selected from listEvents below.
+	listedEventColumns = `e.id, e.price, e.restrictions, e.date, e.city, e.address, e.name,
+							COALESCE(e.img_path, ''), COALESCE(e.description, ''), COALESCE(e.series_id, 0), e.status, COALESCE(e.owner, ''), e.version,
+							COALESCE(i.features, '{}'), rt.rating, rt.reviews, COALESCE(ra.ratings, '{}'::json)`
+	listEvents = "SELECT " + listedEventColumns + ` FROM events e
+							LEFT JOIN index i ON i.event_id = e.id
+							LEFT JOIN LATERAL (
+								SELECT COALESCE(AVG(rr.score), 0) AS rating, COUNT(DISTINCT r.id) AS reviews
+								FROM reviews r JOIN review_ratings rr ON rr.review_id = r.id
+								WHERE r.event_id = e.id AND NOT r.hidden
+							) rt ON true
+							LEFT JOIN LATERAL (
+								SELECT json_object_agg(aspect, score) AS ratings FROM (
+									SELECT rr.aspect, AVG(rr.score) AS score
+									FROM reviews r JOIN review_ratings rr ON rr.review_id = r.id
+									WHERE r.event_id = e.id AND NOT r.hidden GROUP BY rr.aspect
+								) aspects
+							) ra ON true
+`
+	// getEventsByFeature -- lists events of given statuses having exactly the given features or any features if
+	// they are NULL, at most $3 of them or all if it's NULL.
+	getEventsByFeature = listEvents + `WHERE ($1::bigint[] IS NULL OR i.features = $1) AND e.status = ANY($2)
+							ORDER BY e.id LIMIT $3`
+	getEventById    = listEvents + `WHERE e.id = $1`
+	getCachedEvents = listEvents + `WHERE e.id IN (SELECT id FROM cache) ORDER BY e.id`
+	getEventsByIds  = listEvents + `WHERE e.id = ANY($1) ORDER BY e.id`
 
-	getCachedIds    = `SELECT * FROM cache`
 	saveCache       = `INSERT INTO cache(id) VALUES($1)`
 	deleteCache     = `DELETE FROM cache WHERE id = $1`
-	isAlreadyCached = `SELECT * FROM cache WHERE id = $1`
+	isAlreadyCached = `SELECT EXISTS(SELECT 1 FROM cache WHERE id = $1)`
+
+	// Bookings
+	createBooking = `INSERT INTO bookings(event_id, username) VALUES ($1, $2) ON CONFLICT (event_id, username) DO NOTHING`
+	checkIn       = `INSERT INTO bookings(event_id, username, checked_in) VALUES ($1, $2, true)
+						ON CONFLICT (event_id, username) DO UPDATE SET checked_in = true`
+	isBooked = `SELECT EXISTS(SELECT 1 FROM bookings WHERE event_id = $1 AND username = $2)`
+
+	// Favorites
+	addFavorite    = `INSERT INTO favorites(event_id, username) VALUES ($1, $2) ON CONFLICT (event_id, username) DO NOTHING`
+	removeFavorite = `DELETE FROM favorites WHERE event_id = $1 AND username = $2`
+
+	// Calendar
+	getCalendarToken = `SELECT token FROM calendar_tokens WHERE username = $1`
+	setCalendarToken = `INSERT INTO calendar_tokens(username, token) VALUES ($1, $2)
+							ON CONFLICT (username) DO UPDATE SET token = EXCLUDED.token`
+	getCalendarUsername = `SELECT username FROM calendar_tokens WHERE token = $1`
+	getUserEvents       = "SELECT " + eventColumns + ` FROM events WHERE status <> 'draft' AND id IN (
+								SELECT event_id FROM bookings WHERE username = $1
+								UNION
+								SELECT event_id FROM favorites WHERE username = $1
+							) ORDER BY date`
+
+	// Reviews
+	createReview       = `INSERT INTO reviews(event_id, username, comment) VALUES ($1, $2, $3) RETURNING id, created_at`
+	createReviewRating = `INSERT INTO review_ratings(review_id, aspect, score) VALUES ($1, $2, $3)`
+	getReviews         = `SELECT id, event_id, username, comment, hidden, created_at FROM reviews
+							WHERE event_id = $1 AND (NOT hidden OR $2) ORDER BY created_at DESC`
+	getReviewRatings = `SELECT rr.review_id, rr.aspect, rr.score FROM review_ratings rr
+							JOIN reviews r ON r.id = rr.review_id WHERE r.event_id = $1`
+	setReviewHidden = `UPDATE reviews SET hidden = $1 WHERE id = $2`
+
+	getVenueRating = `SELECT COALESCE(AVG(rr.score), 0), COUNT(DISTINCT r.id) FROM reviews r
+							JOIN review_ratings rr ON rr.review_id = r.id JOIN events e ON e.id = r.event_id
+							WHERE e.city = $1 AND e.address = $2 AND NOT r.hidden`
+	getVenueAspectRatings = `SELECT rr.aspect, AVG(rr.score) FROM reviews r
+							JOIN review_ratings rr ON rr.review_id = r.id JOIN events e ON e.id = r.event_id
+							WHERE e.city = $1 AND e.address = $2 AND NOT r.hidden GROUP BY rr.aspect`
+
+	// Outbox
+	createOutboxMessage = `INSERT INTO outbox(subject, payload) VALUES ($1, $2)`
+	getPendingOutbox    = `SELECT id, subject, payload, created_at FROM outbox WHERE published_at IS NULL
+								ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`
+	markOutboxPublished = `UPDATE outbox SET published_at = now() WHERE id = ANY($1)`
+	purgeOutbox         = `DELETE FROM outbox WHERE published_at < $1`
+
+	// History
+	createHistoryRecord = `INSERT INTO event_history(event_id, version, operation, actor, event)
+								VALUES ($1, $2, $3, NULLIF($4, ''), $5)`
+	getEventHistory = `SELECT id, event_id, version, operation, COALESCE(actor, ''), event, created_at
+								FROM event_history WHERE event_id = $1 ORDER BY id`
+	getHistoricalEvent = `SELECT event FROM event_history WHERE event_id = $1 AND version = $2
+								ORDER BY id DESC LIMIT 1`
+	getLastHistoryVersion = `SELECT COALESCE(MAX(version), 0) FROM event_history WHERE event_id = $1`
+	// restoreEvent -- brings back the deleted event with version $13 or overwrites the existing one.
+	restoreEvent = `INSERT INTO events(id, price, restrictions, date, city, address, name, img_path, description,
+								series_id, status, owner, version)
+							VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, 0), $11, NULLIF($12, ''), $13)
+							ON CONFLICT (id) DO UPDATE SET price = excluded.price,
+								restrictions = excluded.restrictions,
+								date = excluded.date,
+								city = excluded.city,
+								address = excluded.address,
+								name = excluded.name,
+								img_path = excluded.img_path,
+								description = excluded.description,
+								series_id = excluded.series_id,
+								status = excluded.status,
+								owner = excluded.owner,
+								version = events.version + 1
+							RETURNING version`
+	restoreIndex = `INSERT INTO index(event_id, features) VALUES ($1, $2)
+							ON CONFLICT (event_id) DO UPDATE SET features = excluded.features`
+
+	// Webhooks
+	createWebhook = `INSERT INTO webhooks(url, secret, subjects, owner) VALUES ($1, $2, $3, NULLIF($4, ''))
+								RETURNING id`
+	getWebhooks   = `SELECT id, url, secret, subjects, COALESCE(owner, ''), created_at FROM webhooks ORDER BY id`
+	deleteWebhook = `DELETE FROM webhooks WHERE id = $1`
+	checkWebhook  = `SELECT 1 FROM webhooks WHERE id = $1`
+	// createDeliveries -- schedules the domain event for every webhook subscribed to its subject, once per key.
+	createDeliveries = `INSERT INTO webhook_deliveries(webhook_id, event_key, subject, payload)
+								SELECT id, $2, $1, $3 FROM webhooks
+								WHERE cardinality(subjects) = 0 OR $1 = ANY(subjects)
+								ON CONFLICT (webhook_id, event_key) DO NOTHING`
+	// claimDeliveries -- leases up to $1 due deliveries for $2 milliseconds, deliveries leased by another
+	// dispatcher are skipped.
+	claimDeliveries = `UPDATE webhook_deliveries SET next_attempt_at = now() + $2 * interval '1 millisecond'
+								WHERE id IN (SELECT id FROM webhook_deliveries
+									WHERE status = 'pending' AND next_attempt_at <= now()
+									ORDER BY next_attempt_at, id LIMIT $1 FOR UPDATE SKIP LOCKED)
+								RETURNING id, webhook_id, event_key, subject, payload, status, attempts,
+									COALESCE(response_code, 0), COALESCE(error, ''), next_attempt_at, created_at`
+	recordAttempt = `UPDATE webhook_deliveries SET status = $2, attempts = attempts + 1,
+								response_code = NULLIF($3, 0), error = NULLIF($4, ''), next_attempt_at = $5
+								WHERE id = $1`
+	getDeliveries = `SELECT id, webhook_id, event_key, subject, payload, status, attempts,
+								COALESCE(response_code, 0), COALESCE(error, ''), next_attempt_at, created_at
+								FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2`
+
+	// Operations
+	createOperation = `INSERT INTO operations(id, kind, event_id, actor, status, created_at)
+								VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)`
+	finishOperation = `UPDATE operations SET status = $2, code = $3, message = $4, result = $5, finished_at = $6
+								WHERE id = $1`
+	getOperation = `SELECT id, kind, event_id, COALESCE(actor, ''), status, COALESCE(code, 0), COALESCE(message, ''),
+								result, created_at, finished_at FROM operations WHERE id = $1`
+	purgeOperations = `DELETE FROM operations WHERE created_at < $1`
+
+	// Migrations
+	createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations(
+								version BIGINT PRIMARY KEY,
+								name VARCHAR(256) NOT NULL,
+								applied_at timestamptz DEFAULT now()
+							)`
+	getAppliedMigrations = `SELECT version FROM schema_migrations ORDER BY version`
+	addMigration         = `INSERT INTO schema_migrations(version, name) VALUES ($1, $2)`
+	removeMigration      = `DELETE FROM schema_migrations WHERE version = $1`
+	isInitialized        = `SELECT to_regclass('public.events') IS NOT NULL`
+	lockMigrations       = `SELECT pg_advisory_lock($1)`
+	unlockMigrations     = `SELECT pg_advisory_unlock($1)`
 )
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"time"
+)
+
+// CreateWebhook -- saves the webhook and returns its id.
+func (s *Storage) CreateWebhook(ctx context.Context, webhook *storage.Webhook) (uint64, error) {
+	const op = "storage.postgres.webhooks.CreateWebhook"
+
+	subjects := webhook.Subjects
+	if subjects == nil {
+		subjects = []string{}
+	}
+
+	var id uint64
+	if err := s.driver.QueryRowContext(ctx, createWebhook, webhook.URL, webhook.Secret, pq.Array(subjects),
+		webhook.Owner).Scan(&id); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// GetWebhooks -- returns every webhook with its secret ordered by id.
+func (s *Storage) GetWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	const op = "storage.postgres.webhooks.GetWebhooks"
+
+	rows, err := s.reader(ctx).QueryContext(ctx, getWebhooks)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var webhooks = make([]storage.Webhook, 0, 4)
+	for rows.Next() {
+		var webhook storage.Webhook
+		if err = rows.Scan(&webhook.Id, &webhook.URL, &webhook.Secret, pq.Array(&webhook.Subjects), &webhook.Owner,
+			&webhook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook -- deletes the webhook with its deliveries.
+func (s *Storage) DeleteWebhook(ctx context.Context, id uint64) error {
+	const op = "storage.postgres.webhooks.DeleteWebhook"
+
+	res, err := s.driver.ExecContext(ctx, deleteWebhook, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// EnqueueDeliveries -- schedules the domain event for every webhook subscribed to the subject and returns
+// the number of deliveries scheduled. The event with the key is scheduled once, enqueueing it again is a no-op.
+func (s *Storage) EnqueueDeliveries(ctx context.Context, subject, key string, payload []byte) (int, error) {
+	const op = "storage.postgres.webhooks.EnqueueDeliveries"
+
+	res, err := s.driver.ExecContext(ctx, createDeliveries, subject, key, payload)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	created, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return int(created), nil
+}
+
+// ClaimDeliveries -- leases up to limit pending deliveries which are due, so they aren't claimed again for lease.
+// Deliveries leased by another dispatcher are skipped, ones whose lease runs out before an attempt is recorded are
+// claimed again, so they are delivered at least once.
+func (s *Storage) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storage.Delivery, error) {
+	const op = "storage.postgres.webhooks.ClaimDeliveries"
+
+	deliveries, err := scanDeliveries(s.driver.QueryContext(ctx, claimDeliveries, limit, lease.Milliseconds()))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt -- records the outcome of an attempt to deliver.
+func (s *Storage) RecordAttempt(ctx context.Context, id uint64, attempt *storage.Attempt) error {
+	const op = "storage.postgres.webhooks.RecordAttempt"
+
+	res, err := s.driver.ExecContext(ctx, recordAttempt, id, attempt.Status, attempt.ResponseCode, attempt.Error,
+		attempt.NextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	recorded, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if recorded == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// GetDeliveries -- returns up to limit last deliveries to the webhook, the latest first.
+func (s *Storage) GetDeliveries(ctx context.Context, webhookId uint64, limit int) ([]storage.Delivery, error) {
+	const op = "storage.postgres.webhooks.GetDeliveries"
+
+	var found int
+	if err := s.reader(ctx).QueryRowContext(ctx, checkWebhook, webhookId).Scan(&found); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries, err := scanDeliveries(s.reader(ctx).QueryContext(ctx, getDeliveries, webhookId, limit))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return deliveries, nil
+}
+
+// scanDeliveries -- reads deliveries returned by a query and closes rows.
+func scanDeliveries(rows *sql.Rows, err error) ([]storage.Delivery, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries = make([]storage.Delivery, 0, 8)
+	for rows.Next() {
+		var delivery storage.Delivery
+		var payload []byte
+		if err = rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.Key, &delivery.Subject, &payload,
+			&delivery.Status, &delivery.Attempts, &delivery.ResponseCode, &delivery.Error, &delivery.NextAttemptAt,
+			&delivery.CreatedAt); err != nil {
+			return nil, err
+		}
+		delivery.Payload = payload
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,59 @@
+package storage
+
+import "time"
+
+// RatingAspects -- are the accessibility aspects a visitor can rate in a review, mapped to their human-readable names.
+var RatingAspects = map[string]string{
+	"sign_language":     "Качество сурдоперевода",
+	"wheelchair":        "Доступность для колясок",
+	"audio_description": "Качество тифлокомментирования",
+	"quiet_space":       "Тихая зона",
+	"staff":             "Помощь персонала",
+}
+
+const (
+	MinScore = 1
+	MaxScore = 5
+)
+
+type Review struct {
+	Id        uint64           `json:"id,omitempty"`
+	EventId   uint64           `json:"event_id"`
+	Username  string           `json:"username,omitempty"`
+	Comment   string           `json:"comment"`
+	Ratings   map[string]uint8 `json:"ratings"`
+	Hidden    bool             `json:"hidden,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// VenueRating -- is the aggregate of visible reviews left on every event held at the same city and address.
+type VenueRating struct {
+	City         string             `json:"city"`
+	Address      string             `json:"address"`
+	Rating       float64            `json:"rating"`
+	Ratings      map[string]float64 `json:"ratings,omitempty"`
+	ReviewsCount uint64             `json:"reviews_count"`
+}
+
+type Booking struct {
+	EventId   uint64    `json:"event_id"`
+	Username  string    `json:"username"`
+	CheckedIn bool      `json:"checked_in"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidateRatings -- checks that every rated aspect is known and every score is within [MinScore, MaxScore].
+func ValidateRatings(ratings map[string]uint8) bool {
+	if len(ratings) == 0 {
+		return false
+	}
+	for aspect, score := range ratings {
+		if _, ok := RatingAspects[aspect]; !ok {
+			return false
+		}
+		if score < MinScore || score > MaxScore {
+			return false
+		}
+	}
+	return true
+}
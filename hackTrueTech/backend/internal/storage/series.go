@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/recurrence"
+	"time"
+)
+
+// Series -- is a recurring event. Event is the template every occurrence is materialised from, Recurrence is
+// an RRULE (see recurrence.Parse) expanded from Start, Exceptions are the dates the series skips.
+type Series struct {
+	Id          uint64      `json:"id,omitempty"`
+	Recurrence  string      `json:"recurrence"`
+	Start       time.Time   `json:"start"`
+	Exceptions  []time.Time `json:"exceptions,omitempty"`
+	Event       Event       `json:"event"`
+	Occurrences []uint64    `json:"occurrences,omitempty"`
+}
+
+// Dates -- returns dates of every occurrence of the series.
+func (s *Series) Dates() ([]time.Time, error) {
+	const op = "storage.series.Dates"
+
+	rule, err := recurrence.Parse(s.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrInvalidInput, err)
+	}
+	return rule.Occurrences(s.Start, s.Exceptions), nil
+}
@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/auth"
+)
+
+func (s *Storage) GetPassword(ctx context.Context, username string) (string, error) {
+	const op = "storage.sqlite.auth.GetPassword"
+
+	var password string
+	err := s.driver.QueryRowContext(ctx, getPassword, username).Scan(&password)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: no rows found: %w", op, err)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return password, nil
+}
+
+func (s *Storage) RegisterUser(ctx context.Context, usr *auth.User) error {
+	const op = "storage.sqlite.auth.RegisterUser"
+
+	if _, err := s.driver.ExecContext(ctx, registerUser, usr.Username, usr.Password, usr.Gender, usr.Age); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) IsAdmin(ctx context.Context, username string) (bool, error) {
+	const op = "storage.sqlite.auth.IsAdmin"
+
+	var ans bool
+	if err := s.driver.QueryRowContext(ctx, isAdmin, username).Scan(&ans); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return ans, nil
+}
+
+func (s *Storage) DeleteUser(ctx context.Context, username string) error {
+	const op = "storage.sqlite.auth.DeleteUser"
+
+	if _, err := s.driver.ExecContext(ctx, deleteUser, username); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+)
+
+func (s *Storage) RestoreCache() ([]storage.Event, error) {
+	const op = "storage.sqlite.RestoreCache"
+
+	rows, err := s.driver.Query(getCachedEvents)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events, err := scanListedEvents(rows, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%s: no ids cached: %w", op, storage.ErrNotFound)
+	}
+
+	return events, nil
+}
+
+func (s *Storage) SaveCache(id uint) error {
+	const op = "storage.sqlite.cacher.SaveCache"
+
+	if _, err := s.driver.Exec(saveCache, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteCache(id uint) error {
+	const op = "storage.sqlite.cacher.DeleteCache"
+
+	if _, err := s.driver.Exec(deleteCache, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) IsAlreadyCached(id uint) bool {
+	const op = "storage.sqlite.cacher.IsAlreadyCached"
+
+	var cached bool
+	if err := s.driver.QueryRow(isAlreadyCached, id).Scan(&cached); err != nil {
+		slog.Error("couldn't check cached id", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		return false
+	}
+	return cached
+}
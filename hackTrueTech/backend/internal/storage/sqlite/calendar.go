@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+)
+
+func (s *Storage) GetCalendarToken(ctx context.Context, username string) (string, error) {
+	const op = "storage.sqlite.calendar.GetCalendarToken"
+
+	var token string
+	if err := s.driver.QueryRowContext(ctx, getCalendarToken, username).Scan(&token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return token, nil
+}
+
+// SetCalendarToken -- sets user's calendar feed token replacing the previous one, so old feed links stop working.
+func (s *Storage) SetCalendarToken(ctx context.Context, username, token string) error {
+	const op = "storage.sqlite.calendar.SetCalendarToken"
+
+	if _, err := s.driver.ExecContext(ctx, setCalendarToken, username, token); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) GetCalendarUsername(ctx context.Context, token string) (string, error) {
+	const op = "storage.sqlite.calendar.GetCalendarUsername"
+
+	var username string
+	if err := s.driver.QueryRowContext(ctx, getCalendarUsername, token).Scan(&username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return username, nil
+}
+
+// GetUserEvents -- returns events user has booked or added to favorites ordered by date.
+func (s *Storage) GetUserEvents(ctx context.Context, username string) ([]storage.Event, error) {
+	const op = "storage.sqlite.calendar.GetUserEvents"
+
+	rows, err := s.driver.QueryContext(ctx, getUserEvents, username)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events, err := scanListedEvents(rows, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
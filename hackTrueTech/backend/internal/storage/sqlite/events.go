@@ -0,0 +1,306 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/compareStrings"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultListLimit -- is the number of events listed when no features are requested.
+const defaultListLimit = 30
+
+func (s *Storage) GetEvent(ctx context.Context, id uint64) (*storage.Event, error) {
+	const op = "storage.sqlite.events.GetEvent"
+
+	rows, err := s.driver.QueryContext(ctx, getEventById, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events, err := scanListedEvents(rows, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+
+	return &events[0], nil
+}
+
+func (s *Storage) CreateEvent(ctx context.Context, event *storage.Event) (uint64, error) {
+	const op = "storage.sqlite.events.CreateEvent"
+
+	var id uint64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		id, err = createEventWith(ctx, tx, event)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// createEventWith -- inserts event and storage.SubjectEventCreated outbox message within the transaction and
+// returns the event id.
+func createEventWith(ctx context.Context, tx *sql.Tx, event *storage.Event) (uint64, error) {
+	if event.Status == "" {
+		event.Status = storage.StatusPublished
+	}
+
+	var features []string
+	if event.Feature != nil {
+		slices.SortFunc(event.Feature, compareStrings.CmpStr)
+		for _, val := range event.Feature {
+			if slices.Contains(storage.Features, val) {
+				features = append(features, val)
+			}
+		}
+	}
+
+	var id uint64
+	err := tx.QueryRowContext(ctx, createEvent, event.Price,
+		event.Restrictions, event.Date.UTC(), event.City,
+		event.Address, event.Name, event.ImgPath, event.Description, strings.Join(features, ","),
+		event.SeriesId, event.Status, event.Owner,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if event.Status == storage.StatusPendingReview {
+		decision := storage.Decision{EventId: id, Actor: event.Owner, Decision: storage.DecisionSubmitted}
+		if err = tx.QueryRowContext(ctx, createDecision, decision.EventId, decision.Actor, decision.Decision, nil).
+			Scan(&decision.Id, &decision.CreatedAt); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = enqueueEvents(ctx, tx, storage.SubjectEventCreated, storage.OperationCreated, []uint64{id}); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetEventsByFeature(ctx context.Context, filter *storage.Filter) ([]storage.Event, error) {
+	const op = "storage.sqlite.events.GetEventsByFeature"
+
+	var features sql.NullString
+	var limit = defaultListLimit
+	if filter.Features != nil {
+		var known = make([]string, 0, len(filter.Features))
+		for _, feature := range filter.Features {
+			if slices.Contains(storage.Features, feature) {
+				known = append(known, feature)
+			}
+		}
+		if len(known) == 0 {
+			known = slices.Clone(storage.Features)
+		}
+		slices.Sort(known)
+		features = sql.NullString{String: strings.Join(known, ","), Valid: true}
+		limit = -1
+	}
+
+	statuses, err := json.Marshal(filter.ListedStatuses())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.driver.QueryContext(ctx, getEventsByFeature, features, string(statuses), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events, err := scanListedEvents(rows, filter.Allows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	storage.SortEvents(events, filter)
+
+	return events, nil
+}
+
+// scanListedEvents -- scans rows selected with listEvents, keeping the events allowed by keep, and closes rows.
+func scanListedEvents(rows *sql.Rows, keep func(event *storage.Event) bool) ([]storage.Event, error) {
+	defer rows.Close()
+
+	var events []storage.Event
+	for rows.Next() {
+		var event storage.Event
+		var features string
+		var ratings []byte
+		err := rows.Scan(&event.Id, &event.Price, &event.Restrictions, &event.Date,
+			&event.City, &event.Address, &event.Name,
+			&event.ImgPath, &event.Description, &event.SeriesId,
+			&event.Status, &event.Owner, &event.Version,
+			&features, &event.Rating, &event.ReviewsCount, &ratings,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if keep != nil && !keep(&event) {
+			continue
+		}
+
+		event.Feature = make([]string, 0, 2)
+		if features != "" {
+			event.Feature = strings.Split(features, ",")
+		}
+		if err = json.Unmarshal(ratings, &event.Ratings); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// DeleteEvent -- deletes the event and everything referencing it.
+func (s *Storage) DeleteEvent(ctx context.Context, id uint64) error {
+	const op = "storage.sqlite.events.DeleteEvent"
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		events, err := getEventsWith(ctx, tx, []uint64{id})
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return storage.ErrNotFound
+		}
+		if err = recordHistory(ctx, tx, storage.OperationDeleted, events); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, deleteEvent, id); err != nil {
+			return err
+		}
+		return enqueue(ctx, tx, storage.SubjectEventDeleted, storage.EventDeletion{Id: id})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// PatchEvent -- applies the patch and returns the new version of the event. storage.ErrVersionConflict is returned
+// if patch.Version is set and the event has another one.
+func (s *Storage) PatchEvent(ctx context.Context, patch *storage.EventPatch) (uint64, error) {
+	const op = "storage.sqlite.events.PatchEvent"
+
+	var date any
+	if patch.Date != nil {
+		date = patch.Date.UTC()
+	}
+
+	var version uint64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkVersion(ctx, tx, patch.Id, patch.Version); err != nil {
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, patchEvent, patch.Price, patch.Restrictions, date, patch.City,
+			patch.Address, patch.Name, patch.Description, patch.Id).Scan(&version); err != nil {
+			return err
+		}
+		return enqueueEvents(ctx, tx, storage.SubjectEventUpdated, storage.OperationUpdated, []uint64{patch.Id})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return version, nil
+}
+
+// checkVersion -- checks that the event has the version, any one matches zero version. The transaction holds
+// the only connection, so the version can't change until it ends.
+func checkVersion(ctx context.Context, tx *sql.Tx, id, version uint64) error {
+	var current uint64
+	if err := tx.QueryRowContext(ctx, getEventVersion, id).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+		return err
+	}
+	if version != 0 && version != current {
+		return storage.ErrVersionConflict
+	}
+	return nil
+}
+
+// SetEventStatus -- moves event to the status. storage.ErrInvalidTransition is returned if event's current status
+// doesn't allow it or there is no such event.
+func (s *Storage) SetEventStatus(ctx context.Context, id uint64, status string) error {
+	const op = "storage.sqlite.events.SetEventStatus"
+
+	from, err := json.Marshal(storage.TransitionsTo(status))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		ids, err := collectIds(tx.QueryContext(ctx, setEventStatus, status, id, string(from)))
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return storage.ErrInvalidTransition
+		}
+		return enqueueEvents(ctx, tx, storage.SubjectEventUpdated, storage.OperationStatusChanged, ids)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ArchivePastEvents -- archives every event which date is before the given time and returns the number of them.
+func (s *Storage) ArchivePastEvents(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.sqlite.events.ArchivePastEvents"
+
+	from, err := json.Marshal(storage.TransitionsTo(storage.StatusArchived))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var archived int64
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		ids, err := collectIds(tx.QueryContext(ctx, archiveEvents, string(from), before.UTC()))
+		if err != nil {
+			return err
+		}
+		archived = int64(len(ids))
+		return enqueueEvents(ctx, tx, storage.SubjectEventUpdated, storage.OperationStatusChanged, ids)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return archived, nil
+}
+
+func (s *Storage) GetBookers(ctx context.Context, id uint64) ([]string, error) {
+	const op = "storage.sqlite.events.GetBookers"
+
+	rows, err := s.driver.QueryContext(ctx, getBookers, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var bookers = make([]string, 0, 4)
+	for rows.Next() {
+		var username string
+		if err = rows.Scan(&username); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		bookers = append(bookers, username)
+	}
+	return bookers, rows.Err()
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS moderation_decisions;
+DROP TABLE IF EXISTS calendar_tokens;
+DROP TABLE IF EXISTS favorites;
+DROP TABLE IF EXISTS review_ratings;
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS bookings;
+DROP TABLE IF EXISTS cache;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS series;
+DROP TABLE IF EXISTS auth;
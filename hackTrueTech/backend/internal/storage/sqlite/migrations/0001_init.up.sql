@@ -0,0 +1,100 @@
+CREATE TABLE auth(
+    username VARCHAR(64) NOT NULL,
+    password VARCHAR(64),
+    isadmin BOOLEAN DEFAULT false,
+    gender BOOLEAN DEFAULT false,
+    age INTEGER CHECK (age > 0 AND age < 130)
+);
+
+INSERT INTO auth(username, password, isadmin, gender, age) VALUES ('idkidkidk', 'idkidkidk', true, true, 20);
+INSERT INTO auth(username, password, isadmin, gender, age) VALUES ('idkidk', 'idkidk', false, false, 18);
+
+CREATE TABLE series(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    recurrence VARCHAR(256) NOT NULL,
+    start DATETIME NOT NULL,
+    exceptions TEXT NOT NULL DEFAULT '[]'
+);
+
+-- features are names of storage.Features the event is tagged with, sorted and comma separated.
+CREATE TABLE events(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    price INTEGER CHECK (price > 0 AND price < 100000000),
+    restrictions INTEGER CHECK (restrictions > 0 AND restrictions < 120),
+    date DATETIME,
+    city VARCHAR(32) NOT NULL,
+    address VARCHAR(128) NOT NULL,
+    name VARCHAR(128) NOT NULL,
+    img_path VARCHAR(256),
+    description VARCHAR(2048),
+    features TEXT NOT NULL DEFAULT '',
+    series_id INTEGER REFERENCES series(id) ON DELETE SET NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'published'
+        CHECK (status IN ('draft', 'pending_review', 'rejected', 'published', 'cancelled', 'archived')),
+    owner VARCHAR(64)
+);
+
+CREATE INDEX events_status_date_idx ON events(status, date);
+
+CREATE INDEX events_features_idx ON events(features);
+
+CREATE INDEX events_series_id_idx ON events(series_id);
+
+CREATE TABLE cache(
+    id INTEGER PRIMARY KEY REFERENCES events(id) ON DELETE CASCADE
+);
+
+CREATE TABLE bookings(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    username VARCHAR(64) NOT NULL,
+    checked_in BOOLEAN DEFAULT false,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, username)
+);
+
+CREATE TABLE reviews(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    username VARCHAR(64) NOT NULL,
+    comment VARCHAR(2048),
+    hidden BOOLEAN DEFAULT false,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, username)
+);
+
+CREATE TABLE review_ratings(
+    review_id INTEGER REFERENCES reviews(id) ON DELETE CASCADE,
+    aspect VARCHAR(64) NOT NULL,
+    score INTEGER CHECK (score >= 1 AND score <= 5),
+    PRIMARY KEY (review_id, aspect)
+);
+
+CREATE TABLE favorites(
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    username VARCHAR(64) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event_id, username)
+);
+
+CREATE TABLE calendar_tokens(
+    username VARCHAR(64) PRIMARY KEY,
+    token VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE TABLE moderation_decisions(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    actor VARCHAR(64) NOT NULL,
+    decision VARCHAR(16) NOT NULL CHECK (decision IN ('submitted', 'approved', 'rejected')),
+    reason VARCHAR(1024),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE outbox(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    subject VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    published_at DATETIME
+);
@@ -0,0 +1,33 @@
+CREATE TABLE event_history(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL CHECK (event_id > 0),
+    version INTEGER NOT NULL,
+    operation VARCHAR(16) NOT NULL
+        CHECK (operation IN ('created', 'updated', 'status_changed', 'deleted', 'restored')),
+    actor VARCHAR(64),
+    event TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX event_history_event_id_idx ON event_history(event_id, id);
+
+-- History outlives events, so there is no foreign key. Events created before it start their history
+-- with their current state.
+INSERT INTO event_history(event_id, version, operation, event)
+    SELECT id, version, 'created', json_object(
+        'id', id,
+        'price', COALESCE(price, 0),
+        'restrictions', COALESCE(restrictions, 0),
+        'date', strftime('%Y-%m-%dT%H:%M:%SZ', COALESCE(date, '0001-01-01 00:00:00')),
+        'feature', json(CASE WHEN features = '' THEN '[]' ELSE '["' || replace(features, ',', '","') || '"]' END),
+        'city', city,
+        'address', address,
+        'name', name,
+        'img_path', COALESCE(img_path, ''),
+        'description', COALESCE(description, ''),
+        'series_id', COALESCE(series_id, 0),
+        'status', status,
+        'owner', COALESCE(owner, ''),
+        'version', version
+    )
+    FROM events ORDER BY id;
@@ -0,0 +1,14 @@
+CREATE TABLE operations(
+    id VARCHAR(36) PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    event_id INTEGER NOT NULL,
+    actor VARCHAR(64),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    code INTEGER,
+    message TEXT,
+    result TEXT,
+    created_at DATETIME NOT NULL,
+    finished_at DATETIME
+);
+
+CREATE INDEX operations_created_at_idx ON operations(created_at);
@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+)
+
+// GetModerationQueue -- returns events waiting for review in the order they were created.
+func (s *Storage) GetModerationQueue(ctx context.Context) ([]storage.Event, error) {
+	const op = "storage.sqlite.moderation.GetModerationQueue"
+
+	rows, err := s.driver.QueryContext(ctx, getModerationQueue)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events, err := scanListedEvents(rows, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// Moderate -- moves the event and the rest of its series occurrences in the same status to the status of
+// the decision and records the decision. storage.ErrInvalidTransition is returned if the event's status doesn't
+// allow the decision.
+func (s *Storage) Moderate(ctx context.Context, decision *storage.Decision) error {
+	const op = "storage.sqlite.moderation.Moderate"
+
+	status := decision.Status()
+	if status == "" {
+		return fmt.Errorf("%s: unknown decision %q", op, decision.Decision)
+	}
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var current string
+		if err := tx.QueryRowContext(ctx, getEventStatus, decision.EventId).Scan(&current); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return storage.ErrNotFound
+			}
+			return err
+		}
+		if !storage.CanTransition(current, status) {
+			return storage.ErrInvalidTransition
+		}
+
+		ids, err := collectIds(tx.QueryContext(ctx, moderateEvents, status, decision.EventId, current))
+		if err != nil {
+			return err
+		}
+		if err = enqueueEvents(storage.WithActor(ctx, decision.Actor), tx, storage.SubjectEventUpdated,
+			storage.OperationStatusChanged, ids); err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, createDecision, decision.EventId, decision.Actor, decision.Decision,
+			sql.NullString{String: decision.Reason, Valid: decision.Reason != ""}).
+			Scan(&decision.Id, &decision.CreatedAt)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) GetDecisions(ctx context.Context, eventId uint64) ([]storage.Decision, error) {
+	const op = "storage.sqlite.moderation.GetDecisions"
+
+	rows, err := s.driver.QueryContext(ctx, getDecisions, eventId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var decisions = make([]storage.Decision, 0, 2)
+	for rows.Next() {
+		var decision storage.Decision
+		if err = rows.Scan(&decision.Id, &decision.EventId, &decision.Actor, &decision.Decision,
+			&decision.Reason, &decision.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		decisions = append(decisions, decision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return decisions, nil
+}
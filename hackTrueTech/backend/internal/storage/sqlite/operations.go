@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	driver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"time"
+)
+
+// CreateOperation -- saves the pending operation. storage.ErrAlreadyExists is returned if there is one with its id.
+func (s *Storage) CreateOperation(ctx context.Context, operation *storage.Operation) error {
+	const op = "storage.sqlite.operations.CreateOperation"
+
+	if _, err := s.driver.ExecContext(ctx, createOperation, operation.Id, operation.Kind, operation.EventId,
+		operation.Actor, operation.Status, operation.CreatedAt.UTC()); err != nil {
+		var sqliteErr *driver.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return fmt.Errorf("%s: %w", op, storage.ErrAlreadyExists)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// FinishOperation -- saves the status, the code, the message, the result and the time the operation finished at.
+func (s *Storage) FinishOperation(ctx context.Context, operation *storage.Operation) error {
+	const op = "storage.sqlite.operations.FinishOperation"
+
+	var result, finishedAt any
+	if len(operation.Result) > 0 {
+		result = string(operation.Result)
+	}
+	if operation.FinishedAt != nil {
+		finishedAt = operation.FinishedAt.UTC()
+	}
+
+	res, err := s.driver.ExecContext(ctx, finishOperation, operation.Id, operation.Status, operation.Code,
+		operation.Message, result, finishedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	finished, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if finished == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+// GetOperation -- returns the operation, storage.ErrNotFound is returned if there is no such one.
+func (s *Storage) GetOperation(ctx context.Context, id string) (*storage.Operation, error) {
+	const op = "storage.sqlite.operations.GetOperation"
+
+	var operation storage.Operation
+	var result sql.NullString
+	var finishedAt sql.NullTime
+	if err := s.driver.QueryRowContext(ctx, getOperation, id).Scan(&operation.Id, &operation.Kind, &operation.EventId,
+		&operation.Actor, &operation.Status, &operation.Code, &operation.Message, &result, &operation.CreatedAt,
+		&finishedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if result.Valid {
+		operation.Result = json.RawMessage(result.String)
+	}
+	operation.CreatedAt = operation.CreatedAt.UTC()
+	if finishedAt.Valid {
+		finished := finishedAt.Time.UTC()
+		operation.FinishedAt = &finished
+	}
+	return &operation, nil
+}
+
+// PurgeOperations -- deletes operations started before the given time and returns the number of them.
+func (s *Storage) PurgeOperations(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.sqlite.operations.PurgeOperations"
+
+	res, err := s.driver.ExecContext(ctx, purgeOperations, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return purged, nil
+}
@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"time"
+)
+
+// PublishOutbox -- hands up to limit pending outbox messages to publish in the order they were written and marks
+// them published if publish succeeds. Messages publish failed on are retried next time, so they are delivered at
+// least once. Returns the number of published messages.
+func (s *Storage) PublishOutbox(ctx context.Context, limit int, publish func([]storage.OutboxMessage) error) (int, error) {
+	const op = "storage.sqlite.outbox.PublishOutbox"
+
+	var published int
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, getPendingOutbox, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var messages = make([]storage.OutboxMessage, 0, limit)
+		var ids = make([]uint64, 0, limit)
+		for rows.Next() {
+			var message storage.OutboxMessage
+			if err = rows.Scan(&message.Id, &message.Subject, &message.Payload, &message.CreatedAt); err != nil {
+				return err
+			}
+			messages = append(messages, message)
+			ids = append(ids, message.Id)
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		if err = publish(messages); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(ids)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, markOutboxPublished, string(data)); err != nil {
+			return err
+		}
+		published = len(messages)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return published, nil
+}
+
+// PurgeOutbox -- deletes messages published before the given time and returns the number of them.
+func (s *Storage) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.sqlite.outbox.PurgeOutbox"
+
+	res, err := s.driver.ExecContext(ctx, purgeOutbox, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return purged, nil
+}
+
+// enqueue -- writes payload marshalled to JSON to the outbox within the transaction.
+func enqueue(ctx context.Context, tx *sql.Tx, subject string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, createOutboxMessage, subject, string(data))
+	return err
+}
+
+// enqueueEvents -- writes the current state of events with the ids to the outbox and appends it to their history
+// as the operation within the transaction.
+func enqueueEvents(ctx context.Context, tx *sql.Tx, subject, operation string, ids []uint64) error {
+	events, err := getEventsWith(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range events {
+		if err = enqueue(ctx, tx, subject, &events[i]); err != nil {
+			return err
+		}
+	}
+	return recordHistory(ctx, tx, operation, events)
+}
+
+// getEventsWith -- reads events with the ids within the transaction.
+func getEventsWith(ctx context.Context, tx *sql.Tx, ids []uint64) ([]storage.Event, error) {
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, getEventsByIds, string(data))
+	if err != nil {
+		return nil, err
+	}
+	return scanListedEvents(rows, nil)
+}
+
+// collectIds -- reads ids returned by a query and closes rows.
+func collectIds(rows *sql.Rows, err error) ([]uint64, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
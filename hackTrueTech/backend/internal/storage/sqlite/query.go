@@ -0,0 +1,195 @@
+package sqlite
+
+const (
+	// AUTH
+	getPassword  = "SELECT password FROM auth WHERE username = ?1"
+	registerUser = "INSERT INTO auth(username, password, gender, age) VALUES(?1, ?2, ?3, ?4)"
+	isAdmin      = "SELECT isadmin FROM auth WHERE username = ?1"
+	deleteUser   = "DELETE FROM auth WHERE username = ?1"
+
+	//Event
+	createEvent = `INSERT INTO events(price, restrictions, date, city, address, name, img_path, description, features,
+							series_id, status, owner)
+						VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, NULLIF(?10, 0), ?11, NULLIF(?12, '')) RETURNING id`
+
+	// patchEvent -- sets the fields given, NULL keeps the field as it is.
+	patchEvent = `UPDATE events SET price = COALESCE(?1, price),
+										restrictions = COALESCE(?2, restrictions),
+										date = COALESCE(?3, date),
+										city = COALESCE(?4, city),
+										address = COALESCE(?5, address),
+										name = COALESCE(?6, name),
+										description = COALESCE(?7, description),
+										version = version + 1
+								WHERE id = ?8 RETURNING version`
+	getEventVersion = "SELECT version FROM events WHERE id = ?1"
+
+	deleteEvent = "DELETE FROM events WHERE id = ?1 RETURNING id"
+
+	// Lists in ?N placeholders below are JSON arrays.
+	setEventStatus = `UPDATE events SET status = ?1, version = version + 1
+							WHERE id = ?2 AND status IN (SELECT value FROM json_each(?3)) RETURNING id`
+	archiveEvents = `UPDATE events SET status = 'archived', version = version + 1
+							WHERE status IN (SELECT value FROM json_each(?1)) AND date < ?2 RETURNING id`
+	getBookers = `SELECT username FROM bookings WHERE event_id = ?1`
+
+	// listEvents -- selects events with features, rating, reviews count and aspect ratings as JSON, filtered by
+	// the WHERE clause appended.
+	listEvents = `WITH totals AS (
+							SELECT r.event_id, AVG(rr.score) AS rating, COUNT(DISTINCT r.id) AS reviews
+							FROM reviews r JOIN review_ratings rr ON rr.review_id = r.id
+							WHERE NOT r.hidden GROUP BY r.event_id
+						), aspects AS (
+							SELECT event_id, json_group_object(aspect, score) AS ratings FROM (
+								SELECT r.event_id, rr.aspect, AVG(rr.score) AS score
+								FROM reviews r JOIN review_ratings rr ON rr.review_id = r.id
+								WHERE NOT r.hidden GROUP BY r.event_id, rr.aspect
+							) GROUP BY event_id
+						)
+						SELECT e.id, e.price, e.restrictions, e.date, e.city, e.address, e.name,
+							COALESCE(e.img_path, ''), COALESCE(e.description, ''), COALESCE(e.series_id, 0), e.status,
+							COALESCE(e.owner, ''), e.version, e.features, COALESCE(t.rating, 0), COALESCE(t.reviews, 0),
+							COALESCE(a.ratings, '{}')
+						FROM events e
+						LEFT JOIN totals t ON t.event_id = e.id
+						LEFT JOIN aspects a ON a.event_id = e.id
+`
+	// getEventsByFeature -- lists events of given statuses having exactly the given features or any features if
+	// they are NULL, at most ?3 of them or all if it's -1.
+	getEventsByFeature = listEvents + `WHERE (?1 IS NULL OR e.features = ?1)
+							AND e.status IN (SELECT value FROM json_each(?2)) ORDER BY e.id LIMIT ?3`
+	getEventById    = listEvents + `WHERE e.id = ?1`
+	getCachedEvents = listEvents + `WHERE e.id IN (SELECT id FROM cache) ORDER BY e.id`
+	getEventsByIds  = listEvents + `WHERE e.id IN (SELECT value FROM json_each(?1)) ORDER BY e.id`
+
+	// Moderation
+	getModerationQueue = listEvents + `WHERE e.status = 'pending_review' ORDER BY e.id`
+	getEventStatus     = "SELECT status FROM events WHERE id = ?1"
+	moderateEvents     = `UPDATE events SET status = ?1, version = version + 1 WHERE status = ?3 AND (id = ?2 OR
+							series_id = (SELECT series_id FROM events WHERE id = ?2)) RETURNING id`
+	createDecision = `INSERT INTO moderation_decisions(event_id, actor, decision, reason) VALUES (?1, ?2, ?3, ?4)
+							RETURNING id, created_at`
+	getDecisions = `SELECT id, event_id, actor, decision, COALESCE(reason, ''), created_at FROM moderation_decisions
+							WHERE event_id = ?1 ORDER BY created_at, id`
+
+	// Series
+	createSeries = `INSERT INTO series(recurrence, start, exceptions) VALUES (?1, ?2, ?3) RETURNING id`
+	patchSeries  = `UPDATE events SET price = COALESCE(?1, price),
+										restrictions = COALESCE(?2, restrictions),
+										city = COALESCE(?3, city),
+										address = COALESCE(?4, address),
+										name = COALESCE(?5, name),
+										description = COALESCE(?6, description),
+										version = version + 1
+								WHERE series_id = (SELECT series_id FROM events WHERE id = ?7) RETURNING id, version`
+
+	saveCache       = `INSERT INTO cache(id) VALUES(?1)`
+	deleteCache     = `DELETE FROM cache WHERE id = ?1`
+	isAlreadyCached = `SELECT EXISTS(SELECT 1 FROM cache WHERE id = ?1)`
+
+	// Bookings
+	createBooking = `INSERT INTO bookings(event_id, username) VALUES (?1, ?2) ON CONFLICT (event_id, username) DO NOTHING`
+	checkIn       = `INSERT INTO bookings(event_id, username, checked_in) VALUES (?1, ?2, true)
+						ON CONFLICT (event_id, username) DO UPDATE SET checked_in = true`
+	isBooked = `SELECT EXISTS(SELECT 1 FROM bookings WHERE event_id = ?1 AND username = ?2)`
+
+	// Favorites
+	addFavorite    = `INSERT INTO favorites(event_id, username) VALUES (?1, ?2) ON CONFLICT (event_id, username) DO NOTHING`
+	removeFavorite = `DELETE FROM favorites WHERE event_id = ?1 AND username = ?2`
+
+	// Calendar
+	getCalendarToken = `SELECT token FROM calendar_tokens WHERE username = ?1`
+	setCalendarToken = `INSERT INTO calendar_tokens(username, token) VALUES (?1, ?2)
+							ON CONFLICT (username) DO UPDATE SET token = excluded.token`
+	getCalendarUsername = `SELECT username FROM calendar_tokens WHERE token = ?1`
+	getUserEvents       = listEvents + `WHERE e.status <> 'draft' AND e.id IN (
+								SELECT event_id FROM bookings WHERE username = ?1
+								UNION
+								SELECT event_id FROM favorites WHERE username = ?1
+							) ORDER BY e.date`
+
+	// Reviews
+	createReview       = `INSERT INTO reviews(event_id, username, comment) VALUES (?1, ?2, ?3) RETURNING id, created_at`
+	createReviewRating = `INSERT INTO review_ratings(review_id, aspect, score) VALUES (?1, ?2, ?3)`
+	getReviews         = `SELECT id, event_id, username, comment, hidden, created_at FROM reviews
+							WHERE event_id = ?1 AND (NOT hidden OR ?2) ORDER BY created_at DESC, id DESC`
+	getReviewRatings = `SELECT rr.review_id, rr.aspect, rr.score FROM review_ratings rr
+							JOIN reviews r ON r.id = rr.review_id WHERE r.event_id = ?1`
+	setReviewHidden = `UPDATE reviews SET hidden = ?1 WHERE id = ?2`
+
+	getVenueRating = `SELECT COALESCE(AVG(rr.score), 0), COUNT(DISTINCT r.id) FROM reviews r
+							JOIN review_ratings rr ON rr.review_id = r.id JOIN events e ON e.id = r.event_id
+							WHERE e.city = ?1 AND e.address = ?2 AND NOT r.hidden`
+	getVenueAspectRatings = `SELECT rr.aspect, AVG(rr.score) FROM reviews r
+							JOIN review_ratings rr ON rr.review_id = r.id JOIN events e ON e.id = r.event_id
+							WHERE e.city = ?1 AND e.address = ?2 AND NOT r.hidden GROUP BY rr.aspect`
+
+	// History
+	createHistoryRecord = `INSERT INTO event_history(event_id, version, operation, actor, event)
+								VALUES (?1, ?2, ?3, NULLIF(?4, ''), ?5)`
+	getEventHistory = `SELECT id, event_id, version, operation, COALESCE(actor, ''), event, created_at
+								FROM event_history WHERE event_id = ?1 ORDER BY id`
+	getHistoricalEvent = `SELECT event FROM event_history WHERE event_id = ?1 AND version = ?2
+								ORDER BY id DESC LIMIT 1`
+	getLastHistoryVersion = `SELECT COALESCE(MAX(version), 0) FROM event_history WHERE event_id = ?1`
+	// restoreEvent -- brings back the deleted event with version ?14 or overwrites the existing one.
+	restoreEvent = `INSERT INTO events(id, price, restrictions, date, city, address, name, img_path, description,
+								features, series_id, status, owner, version)
+							VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, NULLIF(?11, 0), ?12, NULLIF(?13, ''), ?14)
+							ON CONFLICT (id) DO UPDATE SET price = excluded.price,
+								restrictions = excluded.restrictions,
+								date = excluded.date,
+								city = excluded.city,
+								address = excluded.address,
+								name = excluded.name,
+								img_path = excluded.img_path,
+								description = excluded.description,
+								features = excluded.features,
+								series_id = excluded.series_id,
+								status = excluded.status,
+								owner = excluded.owner,
+								version = events.version + 1
+							RETURNING version`
+
+	// Outbox
+	createOutboxMessage = `INSERT INTO outbox(subject, payload) VALUES (?1, ?2)`
+	getPendingOutbox    = `SELECT id, subject, payload, created_at FROM outbox WHERE published_at IS NULL
+								ORDER BY id LIMIT ?1`
+	markOutboxPublished = `UPDATE outbox SET published_at = CURRENT_TIMESTAMP
+								WHERE id IN (SELECT value FROM json_each(?1))`
+	purgeOutbox = `DELETE FROM outbox WHERE published_at < ?1`
+
+	// Webhooks
+	createWebhook = `INSERT INTO webhooks(url, secret, subjects, owner) VALUES (?1, ?2, ?3, NULLIF(?4, ''))
+								RETURNING id`
+	getWebhooks   = `SELECT id, url, secret, subjects, COALESCE(owner, ''), created_at FROM webhooks ORDER BY id`
+	deleteWebhook = `DELETE FROM webhooks WHERE id = ?1`
+	checkWebhook  = `SELECT 1 FROM webhooks WHERE id = ?1`
+	// createDeliveries -- schedules the domain event for every webhook subscribed to its subject, once per key.
+	createDeliveries = `INSERT OR IGNORE INTO webhook_deliveries(webhook_id, event_key, subject, payload,
+									next_attempt_at)
+								SELECT id, ?2, ?1, ?3, ?4 FROM webhooks WHERE subjects = '[]'
+									OR EXISTS (SELECT 1 FROM json_each(webhooks.subjects) WHERE value = ?1)`
+	// claimDeliveries -- leases up to ?1 deliveries due at ?2 until ?3.
+	claimDeliveries = `UPDATE webhook_deliveries SET next_attempt_at = ?3
+								WHERE id IN (SELECT id FROM webhook_deliveries
+									WHERE status = 'pending' AND next_attempt_at <= ?2
+									ORDER BY next_attempt_at, id LIMIT ?1)
+								RETURNING id, webhook_id, event_key, subject, payload, status, attempts,
+									COALESCE(response_code, 0), COALESCE(error, ''), next_attempt_at, created_at`
+	recordAttempt = `UPDATE webhook_deliveries SET status = ?2, attempts = attempts + 1,
+								response_code = NULLIF(?3, 0), error = NULLIF(?4, ''), next_attempt_at = ?5
+								WHERE id = ?1`
+	getDeliveries = `SELECT id, webhook_id, event_key, subject, payload, status, attempts,
+								COALESCE(response_code, 0), COALESCE(error, ''), next_attempt_at, created_at
+								FROM webhook_deliveries WHERE webhook_id = ?1 ORDER BY id DESC LIMIT ?2`
+
+	// Operations
+	createOperation = `INSERT INTO operations(id, kind, event_id, actor, status, created_at)
+								VALUES (?1, ?2, ?3, NULLIF(?4, ''), ?5, ?6)`
+	finishOperation = `UPDATE operations SET status = ?2, code = ?3, message = ?4, result = ?5, finished_at = ?6
+								WHERE id = ?1`
+	getOperation = `SELECT id, kind, event_id, COALESCE(actor, ''), status, COALESCE(code, 0), COALESCE(message, ''),
+								result, created_at, finished_at FROM operations WHERE id = ?1`
+	purgeOperations = `DELETE FROM operations WHERE created_at < ?1`
+)
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	driver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func (s *Storage) CreateReview(ctx context.Context, review *storage.Review) (uint64, error) {
+	const op = "storage.sqlite.reviews.CreateReview"
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, createReview, review.EventId, review.Username, review.Comment).
+			Scan(&review.Id, &review.CreatedAt); err != nil {
+			var sqliteErr *driver.Error
+			if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+				return storage.ErrAlreadyExists
+			}
+			return err
+		}
+
+		for aspect, score := range review.Ratings {
+			if _, err := tx.ExecContext(ctx, createReviewRating, review.Id, aspect, score); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return review.Id, nil
+}
+
+// GetReviews -- returns reviews left on the event, hidden ones are included only if withHidden is set.
+func (s *Storage) GetReviews(ctx context.Context, eventId uint64, withHidden bool) ([]storage.Review, error) {
+	const op = "storage.sqlite.reviews.GetReviews"
+
+	rows, err := s.driver.QueryContext(ctx, getReviews, eventId, withHidden)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var reviews = make([]storage.Review, 0, 4)
+	var positions = make(map[uint64]int)
+	for rows.Next() {
+		var review storage.Review
+		var comment sql.NullString
+		if err = rows.Scan(&review.Id, &review.EventId, &review.Username, &comment,
+			&review.Hidden, &review.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		review.Comment = comment.String
+		review.Ratings = make(map[string]uint8)
+		positions[review.Id] = len(reviews)
+		reviews = append(reviews, review)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ratings, err := s.driver.QueryContext(ctx, getReviewRatings, eventId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer ratings.Close()
+
+	for ratings.Next() {
+		var reviewId uint64
+		var aspect string
+		var score uint8
+		if err = ratings.Scan(&reviewId, &aspect, &score); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if i, ok := positions[reviewId]; ok {
+			reviews[i].Ratings[aspect] = score
+		}
+	}
+	if err = ratings.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reviews, nil
+}
+
+func (s *Storage) SetReviewHidden(ctx context.Context, id uint64, hidden bool) error {
+	const op = "storage.sqlite.reviews.SetReviewHidden"
+
+	res, err := s.driver.ExecContext(ctx, setReviewHidden, hidden, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Storage) GetVenueRating(ctx context.Context, city, address string) (*storage.VenueRating, error) {
+	const op = "storage.sqlite.reviews.GetVenueRating"
+
+	venue := storage.VenueRating{City: city, Address: address}
+	if err := s.driver.QueryRowContext(ctx, getVenueRating, city, address).
+		Scan(&venue.Rating, &venue.ReviewsCount); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.driver.QueryContext(ctx, getVenueAspectRatings, city, address)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	venue.Ratings = make(map[string]float64)
+	for rows.Next() {
+		var aspect string
+		var score float64
+		if err = rows.Scan(&aspect, &score); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		venue.Ratings[aspect] = score
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &venue, nil
+}
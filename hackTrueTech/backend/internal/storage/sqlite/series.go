@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+)
+
+// CreateSeries -- saves the series and materialises every its occurrence as an individual event. Ids of created
+// occurrences are set to series.Occurrences.
+func (s *Storage) CreateSeries(ctx context.Context, series *storage.Series) (uint64, error) {
+	const op = "storage.sqlite.series.CreateSeries"
+
+	dates, err := series.Dates()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(dates) == 0 {
+		return 0, fmt.Errorf("%s: series has no occurrences: %w", op, storage.ErrInvalidInput)
+	}
+
+	exceptions, err := json.Marshal(series.Exceptions)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, createSeries, series.Recurrence, series.Start.UTC(),
+			string(exceptions)).Scan(&series.Id); err != nil {
+			return err
+		}
+
+		series.Occurrences = make([]uint64, 0, len(dates))
+		for _, date := range dates {
+			occurrence := series.Event
+			occurrence.Date = date
+			occurrence.SeriesId = series.Id
+
+			id, err := createEventWith(ctx, tx, &occurrence)
+			if err != nil {
+				return err
+			}
+			series.Occurrences = append(series.Occurrences, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return series.Id, nil
+}
+
+// PatchSeries -- applies the patch except the date to every occurrence of the series the event patch.Id belongs to
+// and returns the new version of that event. patch.Version is checked against the version of that event.
+func (s *Storage) PatchSeries(ctx context.Context, patch *storage.EventPatch) (uint64, error) {
+	const op = "storage.sqlite.series.PatchSeries"
+
+	var version uint64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkVersion(ctx, tx, patch.Id, patch.Version); err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx, patchSeries, patch.Price, patch.Restrictions, patch.City, patch.Address,
+			patch.Name, patch.Description, patch.Id)
+		if err != nil {
+			return err
+		}
+		var ids []uint64
+		ids, version, err = collectPatched(rows, patch.Id)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return storage.ErrNotFound
+		}
+		return enqueueEvents(ctx, tx, storage.SubjectEventUpdated, storage.OperationUpdated, ids)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return version, nil
+}
+
+// collectPatched -- returns the ids of the occurrences the series patch returns with their versions and the version
+// of the event id among them.
+func collectPatched(rows *sql.Rows, id uint64) ([]uint64, uint64, error) {
+	defer rows.Close()
+
+	var ids []uint64
+	var patched uint64
+	for rows.Next() {
+		var occurrence uint64
+		var version uint64
+		if err := rows.Scan(&occurrence, &version); err != nil {
+			return nil, 0, err
+		}
+		if occurrence == id {
+			patched = version
+		}
+		ids = append(ids, occurrence)
+	}
+	return ids, patched, rows.Err()
+}
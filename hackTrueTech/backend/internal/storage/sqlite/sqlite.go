@@ -0,0 +1,167 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"log/slog"
+	_ "modernc.org/sqlite"
+	"net/url"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Storage -- keeps everything in a single SQLite file. SQLite allows one writer at a time, so the storage uses
+// a single connection, which also serializes transactions the way row locks do in postgres.Storage.
+type Storage struct {
+	driver *sql.DB
+}
+
+// New -- opens the database file at path creating it if needed, ":memory:" opens a private in-memory database.
+func New(path string) (*Storage, error) {
+	const op = "storage.sqlite.New"
+
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	query.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	if err = db.Ping(); err != nil {
+		return nil, errors.Join(fmt.Errorf("%s: %w", op, err), db.Close())
+	}
+
+	return &Storage{driver: db}, nil
+}
+
+func (s *Storage) Close() error {
+	return s.driver.Close()
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.driver.PingContext(ctx)
+}
+
+// Migrations -- returns every embedded migration ordered by version.
+func Migrations() ([]storage.Migration, error) {
+	return storage.ParseMigrations(migrationsFS, "migrations")
+}
+
+// MigrateUp -- applies every pending migration, each one in its own transaction. The applied version is kept
+// in user_version pragma.
+func (s *Storage) MigrateUp(ctx context.Context) error {
+	const op = "storage.sqlite.MigrateUp"
+
+	migrations, err := Migrations()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	version, err := s.MigrationVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= version {
+			continue
+		}
+		if err = s.runMigration(ctx, migration.Up, migration.Version); err != nil {
+			return fmt.Errorf("%s: %d_%s: %w", op, migration.Version, migration.Name, err)
+		}
+		slog.Info("applied migration", slogResponse.SlogOp(op),
+			slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+	}
+	return nil
+}
+
+// MigrateDown -- reverts the given number of the latest applied migrations.
+func (s *Storage) MigrateDown(ctx context.Context, steps int) error {
+	const op = "storage.sqlite.MigrateDown"
+
+	migrations, err := Migrations()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	version, err := s.MigrationVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		migration := migrations[i]
+		if migration.Version > version {
+			continue
+		}
+		if migration.Down == "" {
+			return fmt.Errorf("%s: migration %d has no down script", op, migration.Version)
+		}
+
+		var previous int64
+		if i > 0 {
+			previous = migrations[i-1].Version
+		}
+		if err = s.runMigration(ctx, migration.Down, previous); err != nil {
+			return fmt.Errorf("%s: %d_%s: %w", op, migration.Version, migration.Name, err)
+		}
+		slog.Info("reverted migration", slogResponse.SlogOp(op),
+			slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+		steps--
+	}
+	return nil
+}
+
+// MigrationVersion -- returns the latest applied migration version, 0 if none is applied.
+func (s *Storage) MigrationVersion(ctx context.Context) (int64, error) {
+	const op = "storage.sqlite.MigrationVersion"
+
+	var version int64
+	if err := s.driver.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return version, nil
+}
+
+// runMigration -- executes script and sets the schema version in one transaction.
+func (s *Storage) runMigration(ctx context.Context, script string, version int64) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version))
+		return err
+	})
+}
+
+// withTx -- runs fn in a transaction, which is committed if fn succeeds and rolled back otherwise.
+func (s *Storage) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	const op = "storage.sqlite.withTx"
+
+	tx, err := s.driver.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.Error("couldn't rollback transaction", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,22 @@
+package sqlite
+
+import (
+	"context"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/conformance"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) conformance.Storage {
+		s, err := New(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+
+		if err = s.MigrateUp(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+const (
+	StatusDraft         = "draft"
+	StatusPendingReview = "pending_review"
+	StatusRejected      = "rejected"
+	StatusPublished     = "published"
+	StatusCancelled     = "cancelled"
+	StatusArchived      = "archived"
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// transitions -- maps every status to the statuses an event can be moved to from it. Archived is final. An event
+// gets published only through moderation, see IsModerated.
+var transitions = map[string][]string{
+	StatusDraft:         {StatusPendingReview, StatusCancelled},
+	StatusPendingReview: {StatusPublished, StatusRejected, StatusDraft},
+	StatusRejected:      {StatusPendingReview, StatusDraft},
+	StatusPublished:     {StatusDraft, StatusCancelled, StatusArchived},
+	StatusCancelled:     {StatusArchived},
+	StatusArchived:      {},
+}
+
+// hidden -- are the statuses of events that are visible to their owner and admins only.
+var hidden = []string{StatusDraft, StatusPendingReview, StatusRejected}
+
+func IsStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+func CanTransition(from, to string) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+// TransitionsTo -- returns statuses event can be moved to the given status from.
+func TransitionsTo(to string) []string {
+	var from = make([]string, 0, len(transitions))
+	for status, next := range transitions {
+		if slices.Contains(next, to) {
+			from = append(from, status)
+		}
+	}
+	slices.Sort(from)
+	return from
+}
+
+// IsModerated -- reports whether moving an event to the status is a step of moderation, which must go through
+// the moderation queue to be recorded, see Decision.
+func IsModerated(status string) bool {
+	return status == StatusPendingReview || status == StatusPublished || status == StatusRejected
+}
+
+// VisibleTo -- reports whether the event can be shown to the user. Drafts and events that haven't passed moderation
+// are visible to their owner and admins only.
+func (e *Event) VisibleTo(username string, isAdmin bool) bool {
+	if !slices.Contains(hidden, e.Status) {
+		return true
+	}
+	return isAdmin || (username != "" && username == e.Owner)
+}
+
+// Cancellation -- is the notification published when event is cancelled, Bookers are usernames of everyone who
+// booked the event.
+type Cancellation struct {
+	EventId uint64    `json:"event_id"`
+	Name    string    `json:"name"`
+	Date    time.Time `json:"date"`
+	Bookers []string  `json:"bookers"`
+}
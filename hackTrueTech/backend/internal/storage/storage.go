@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"cmp"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
@@ -11,6 +13,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -20,22 +23,75 @@ const (
 	ImageFolder = "/data"
 )
 
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrInvalidInput -- is returned when the data given can't be stored, e.g. a series without occurrences.
+	ErrInvalidInput = errors.New("invalid input")
+)
+
 type Storage struct {
 	db     *sql.DB
 	broker nats.Conn
 }
 
 type Event struct {
-	Id           uint64    `json:"id,omitempty"`
-	Price        uint64    `json:"price"`
-	Restrictions uint64    `json:"restrictions"`
-	Date         time.Time `json:"date"`
-	Feature      []string  `json:"feature"`
-	City         string    `json:"city"`
-	Address      string    `json:"address"`
-	Name         string    `json:"name"`
-	ImgPath      string    `json:"img_path"`
-	Description  string    `json:"description"`
+	Id           uint64             `json:"id,omitempty"`
+	Price        uint64             `json:"price"`
+	Restrictions uint64             `json:"restrictions"`
+	Date         time.Time          `json:"date"`
+	Feature      []string           `json:"feature"`
+	City         string             `json:"city"`
+	Address      string             `json:"address"`
+	Name         string             `json:"name"`
+	ImgPath      string             `json:"img_path"`
+	Description  string             `json:"description"`
+	SeriesId     uint64             `json:"series_id,omitempty"`
+	Status       string             `json:"status"`
+	Owner        string             `json:"owner,omitempty"`
+	Rating       float64            `json:"rating"`
+	Ratings      map[string]float64 `json:"ratings,omitempty"`
+	ReviewsCount uint64             `json:"reviews_count"`
+	// Version -- is incremented on every change of the event, see EventPatch.
+	Version uint64 `json:"version"`
+}
+
+// Features -- are the accessibility features events are tagged and filtered with.
+var Features = []string{"blind", "deaf", "disability", "neuro"}
+
+const (
+	SortByDate   = "date"
+	SortByPrice  = "price"
+	SortByRating = "rating"
+
+	OrderAscending  = "ascending"
+	OrderDescending = "descending"
+)
+
+// Filter -- is the set of parameters events listing is built with. Features narrows the listing by accessibility
+// features, SortBy is one of SortBy* consts and Order is one of Order* consts. Statuses narrows the listing by
+// lifecycle status, only published events are listed if it's empty. Drafts are listed to their owner (Viewer)
+// and admins only.
+type Filter struct {
+	Features      []string `json:"features,omitempty"`
+	SortBy        string   `json:"sort_by,omitempty"`
+	Order         string   `json:"order,omitempty"`
+	Statuses      []string `json:"statuses,omitempty"`
+	Viewer        string   `json:"viewer,omitempty"`
+	ViewerIsAdmin bool     `json:"viewer_is_admin,omitempty"`
+}
+
+// Allows -- reports whether event belongs to the listing built with filter.
+func (f *Filter) Allows(event *Event) bool {
+	return slices.Contains(f.ListedStatuses(), event.Status) && event.VisibleTo(f.Viewer, f.ViewerIsAdmin)
+}
+
+// ListedStatuses -- returns the statuses events are listed with, published only if none are set.
+func (f *Filter) ListedStatuses() []string {
+	if len(f.Statuses) == 0 {
+		return []string{StatusPublished}
+	}
+	return f.Statuses
 }
 
 func EventToJSON(event *Event) ([]byte, error) {
@@ -93,6 +149,9 @@ func ParseFormData(r *http.Request) (*Event, error) {
 	if mForm.Value["description"] != nil {
 		event.Description = mForm.Value["description"][0]
 	}
+	if mForm.Value["status"] != nil {
+		event.Status = mForm.Value["status"][0]
+	}
 
 	files, ok := mForm.File["img_path"]
 	ext := strings.Split(files[0].Filename, ".")
@@ -118,3 +177,25 @@ func ParseFormData(r *http.Request) (*Event, error) {
 
 	return &event, nil
 }
+
+// SortEvents -- orders events in place according to filter's SortBy and Order. Unknown SortBy leaves events as is.
+func SortEvents(events []Event, filter *Filter) {
+	var compare func(a, b *Event) int
+	switch filter.SortBy {
+	case SortByDate:
+		compare = func(a, b *Event) int { return a.Date.Compare(b.Date) }
+	case SortByPrice:
+		compare = func(a, b *Event) int { return cmp.Compare(a.Price, b.Price) }
+	case SortByRating:
+		compare = func(a, b *Event) int { return cmp.Compare(a.Rating, b.Rating) }
+	default:
+		return
+	}
+
+	slices.SortStableFunc(events, func(a, b Event) int {
+		if filter.Order == OrderDescending {
+			return compare(&b, &a)
+		}
+		return compare(&a, &b)
+	})
+}
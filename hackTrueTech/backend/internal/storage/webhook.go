@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Webhook -- is a partner system domain events are delivered to. Deliveries are signed with Secret, Subjects narrows
+// them to the listed domain events, every one is delivered if it's empty.
+type Webhook struct {
+	Id        uint64    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Subjects  []string  `json:"subjects"`
+	Owner     string    `json:"owner,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribed -- reports whether domain events of the subject are delivered to the webhook.
+func (w *Webhook) Subscribed(subject string) bool {
+	return len(w.Subjects) == 0 || slices.Contains(w.Subjects, subject)
+}
+
+// Statuses of a delivery.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery -- is a domain event to be delivered to the webhook with the outcome of the last attempt. Key is
+// the idempotency key of the domain event, it's delivered to every webhook once however many times it's published.
+type Delivery struct {
+	Id            uint64          `json:"id"`
+	WebhookId     uint64          `json:"webhook_id"`
+	Key           string          `json:"key"`
+	Subject       string          `json:"subject"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  int             `json:"response_code,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// Attempt -- is the outcome of an attempt to deliver: the status the delivery moves to, the response code and
+// the error, if any, and the time of the next attempt of a pending delivery.
+type Attempt struct {
+	Status        string
+	ResponseCode  int
+	Error         string
+	NextAttemptAt time.Time
+}
@@ -0,0 +1,245 @@
+// Package webhooks delivers domain events to partner systems. Domain events are scheduled for delivery to every
+// webhook subscribed to them as soon as they are published, and a Dispatcher posts them, retrying failed ones,
+// and records the outcome of every attempt in the delivery log.
+//
+// A delivery is a POST of the domain event payload with HeaderSubject, HeaderKey, HeaderTimestamp and HeaderSignature.
+// Receivers verify it computing Sign with the secret of the webhook and drop duplicates by HeaderKey, as a delivery
+// is retried until it's acknowledged with a 2xx response.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/lib/slogResponse"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of a delivery.
+const (
+	// HeaderSubject -- is the subject of the domain event, e.g. storage.SubjectEventCreated.
+	HeaderSubject = "X-Webhook-Event"
+	// HeaderKey -- is the idempotency key of the domain event, the same for every attempt to deliver it.
+	HeaderKey = "X-Webhook-Id"
+	// HeaderTimestamp -- is the time of the attempt in Unix seconds, receivers should reject stale ones.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature -- is Sign of the delivery.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// maxErrorLength -- is the length the error of an attempt is cut to in the delivery log.
+const maxErrorLength = 512
+
+// Storage -- keeps webhooks and their deliveries.
+type Storage interface {
+	GetWebhooks(ctx context.Context) ([]storage.Webhook, error)
+	EnqueueDeliveries(ctx context.Context, subject, key string, payload []byte) (int, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storage.Delivery, error)
+	RecordAttempt(ctx context.Context, id uint64, attempt *storage.Attempt) error
+}
+
+// Dispatcher -- posts due deliveries to their webhooks. Several dispatchers may run against one storage, each
+// delivery is claimed by one of them at a time.
+type Dispatcher struct {
+	db     Storage
+	client *http.Client
+	cfg    config.Webhooks
+}
+
+func New(db Storage, cfg *config.Webhooks) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    *cfg,
+	}
+}
+
+// Enqueue -- schedules the domain event with the idempotency key for delivery to every webhook subscribed to
+// the subject. Created and updated events hidden from the public, e.g. drafts, aren't delivered.
+func (d *Dispatcher) Enqueue(ctx context.Context, subject, key string, payload []byte) error {
+	const op = "webhooks.Enqueue"
+
+	if subject == storage.SubjectEventCreated || subject == storage.SubjectEventUpdated {
+		var event storage.Event
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if !event.VisibleTo("", false) {
+			return nil
+		}
+	}
+
+	if _, err := d.db.EnqueueDeliveries(ctx, subject, key, payload); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Run -- delivers due deliveries every cfg.Interval until ctx is done. Deliveries being posted once it's done are
+// completed and recorded, no more are claimed.
+func (d *Dispatcher) Run(ctx context.Context) {
+	const op = "webhooks.Run"
+
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			dispatched, err := d.Dispatch(context.WithoutCancel(ctx))
+			if err != nil {
+				slog.Error("couldn't dispatch deliveries", slogResponse.SlogOp(op), slogResponse.SlogErr(err))
+				break
+			}
+			if dispatched < d.cfg.Concurrency {
+				break
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Dispatch -- claims up to cfg.Concurrency due deliveries, posts them in parallel and records the outcome of every
+// attempt. Returns the number of claimed deliveries.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	const op = "webhooks.Dispatch"
+
+	// A claimed delivery stays leased while it's posted, and a while after in case recording the attempt is slow.
+	deliveries, err := d.db.ClaimDeliveries(ctx, d.cfg.Concurrency, 2*d.cfg.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+
+	// Webhooks are read from the primary, so deliveries to a webhook created right before are attempted too.
+	webhooks, err := d.db.GetWebhooks(storage.WithReadYourWrites(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	byId := make(map[uint64]*storage.Webhook, len(webhooks))
+	for i := range webhooks {
+		byId[webhooks[i].Id] = &webhooks[i]
+	}
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		webhook, ok := byId[delivery.WebhookId]
+		if !ok {
+			// The webhook is deleted along with the delivery.
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			attempt := d.deliver(ctx, webhook, delivery)
+			if err := d.db.RecordAttempt(ctx, delivery.Id, &attempt); err != nil {
+				slog.Error("couldn't record delivery attempt", slogResponse.SlogOp(op), slogResponse.SlogErr(err),
+					slog.Uint64("delivery_id", delivery.Id))
+			}
+		}()
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// deliver -- posts the delivery to the webhook and returns the outcome of the attempt.
+func (d *Dispatcher) deliver(ctx context.Context, webhook *storage.Webhook,
+	delivery *storage.Delivery) storage.Attempt {
+	code, err := d.post(ctx, webhook, delivery)
+	if err == nil && code >= http.StatusOK && code < http.StatusMultipleChoices {
+		return storage.Attempt{Status: storage.DeliverySucceeded, ResponseCode: code, NextAttemptAt: time.Now()}
+	}
+
+	var attempt = storage.Attempt{ResponseCode: code}
+	if err != nil {
+		attempt.Error = err.Error()
+	} else {
+		attempt.Error = http.StatusText(code)
+	}
+	if len(attempt.Error) > maxErrorLength {
+		attempt.Error = attempt.Error[:maxErrorLength]
+	}
+
+	attempts := delivery.Attempts + 1
+	if attempts >= d.cfg.MaxAttempts {
+		attempt.Status = storage.DeliveryFailed
+		attempt.NextAttemptAt = time.Now()
+		return attempt
+	}
+	attempt.Status = storage.DeliveryPending
+	attempt.NextAttemptAt = time.Now().Add(d.backoff(attempts))
+	return attempt
+}
+
+// post -- sends the signed delivery to the webhook and returns the response code.
+func (d *Dispatcher) post(ctx context.Context, webhook *storage.Webhook, delivery *storage.Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSubject, delivery.Subject)
+	req.Header.Set(HeaderKey, delivery.Key)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// backoff -- returns the wait before the attempt following the given number of attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	if len(d.cfg.Backoff) == 0 {
+		return d.cfg.Interval
+	}
+	return d.cfg.Backoff[min(attempts, len(d.cfg.Backoff))-1]
+}
+
+// Sign -- returns the signature of the delivery body sent at timestamp with the secret of the webhook: "sha256="
+// followed by hex of HMAC-SHA256 of "<timestamp>.<body>".
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret -- returns a random secret to sign deliveries to a new webhook with.
+func NewSecret() (string, error) {
+	const op = "webhooks.NewSecret"
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,134 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/wlcmtunknwndth/hackBPA/internal/config"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage"
+	"github.com/wlcmtunknwndth/hackBPA/internal/storage/memory"
+	"github.com/wlcmtunknwndth/hackBPA/internal/webhooks"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var cfg = config.Webhooks{Interval: 10 * time.Millisecond, Timeout: time.Second, Concurrency: 4, MaxAttempts: 2,
+	Backoff: []time.Duration{-time.Second}}
+
+// receiver -- is a partner system answering deliveries with code and recording the verified ones.
+type receiver struct {
+	t      *testing.T
+	secret string
+	code   int
+
+	mu       sync.Mutex
+	received []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	timestamp, err := strconv.ParseInt(r.Header.Get(webhooks.HeaderTimestamp), 10, 64)
+	if err != nil || r.Header.Get(webhooks.HeaderSignature) != webhooks.Sign(rc.secret, timestamp, body) {
+		rc.t.Errorf("delivery isn't signed with the secret of the webhook: %v", r.Header)
+	}
+
+	rc.mu.Lock()
+	rc.received = append(rc.received, r)
+	rc.bodies = append(rc.bodies, body)
+	rc.mu.Unlock()
+	w.WriteHeader(rc.code)
+}
+
+func setup(t *testing.T, code int, subjects ...string) (*webhooks.Dispatcher, *memory.Storage, *receiver, uint64) {
+	db := memory.New()
+	rc := &receiver{t: t, secret: "secret", code: code}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	id, err := db.CreateWebhook(context.Background(), &storage.Webhook{URL: srv.URL, Secret: rc.secret,
+		Subjects: subjects})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhooks.New(db, &cfg), db, rc, id
+}
+
+func payload(t *testing.T, event storage.Event) []byte {
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDeliver(t *testing.T) {
+	d, db, rc, id := setup(t, http.StatusNoContent)
+	ctx := context.Background()
+
+	published := payload(t, storage.Event{Id: 1, Name: "concert", Status: storage.StatusPublished})
+	if err := d.Enqueue(ctx, storage.SubjectEventCreated, "outbox-1", published); err != nil {
+		t.Fatal(err)
+	}
+	// The domain event is redelivered by the broker, it's delivered to the webhook once anyway.
+	if err := d.Enqueue(ctx, storage.SubjectEventCreated, "outbox-1", published); err != nil {
+		t.Fatal(err)
+	}
+	draft := payload(t, storage.Event{Id: 2, Name: "draft", Status: storage.StatusDraft})
+	if err := d.Enqueue(ctx, storage.SubjectEventCreated, "outbox-2", draft); err != nil {
+		t.Fatal(err)
+	}
+
+	if dispatched, err := d.Dispatch(ctx); err != nil || dispatched != 1 {
+		t.Fatalf("Dispatch = %d, %v; want the published event only", dispatched, err)
+	}
+
+	if len(rc.received) != 1 {
+		t.Fatalf("received %d deliveries, want 1", len(rc.received))
+	}
+	if r := rc.received[0]; r.Header.Get(webhooks.HeaderSubject) != storage.SubjectEventCreated ||
+		r.Header.Get(webhooks.HeaderKey) != "outbox-1" || string(rc.bodies[0]) != string(published) {
+		t.Errorf("delivery = %v %s", r.Header, rc.bodies[0])
+	}
+
+	log, err := db.GetDeliveries(ctx, id, 10)
+	if err != nil || len(log) != 1 || log[0].Status != storage.DeliverySucceeded || log[0].ResponseCode != 204 ||
+		log[0].Attempts != 1 {
+		t.Errorf("delivery log = %+v, %v", log, err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	d, db, rc, id := setup(t, http.StatusServiceUnavailable, storage.SubjectEventDeleted)
+	ctx := context.Background()
+
+	if err := d.Enqueue(ctx, storage.SubjectEventUpdated, "outbox-1",
+		payload(t, storage.Event{Id: 1, Status: storage.StatusPublished})); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Enqueue(ctx, storage.SubjectEventDeleted, "outbox-2", []byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	for range cfg.MaxAttempts {
+		if dispatched, err := d.Dispatch(ctx); err != nil || dispatched != 1 {
+			t.Fatalf("Dispatch = %d, %v; want the deletion retried", dispatched, err)
+		}
+	}
+	if dispatched, err := d.Dispatch(ctx); err != nil || dispatched != 0 {
+		t.Errorf("Dispatch after the last attempt = %d, %v; want nothing", dispatched, err)
+	}
+	if len(rc.received) != cfg.MaxAttempts {
+		t.Errorf("received %d attempts, want %d", len(rc.received), cfg.MaxAttempts)
+	}
+
+	log, err := db.GetDeliveries(ctx, id, 10)
+	if err != nil || len(log) != 1 || log[0].Status != storage.DeliveryFailed || log[0].ResponseCode != 503 ||
+		log[0].Attempts != cfg.MaxAttempts || log[0].Error == "" {
+		t.Errorf("delivery log = %+v, %v", log, err)
+	}
+}